@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/innoai-tech/infra/pkg/cli"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	blobs := cli.Add(app, &Blobs{})
+
+	// named explicitly, or named as lower-cased type name
+	cli.Add(blobs, &BlobsLs{Name: cli.Name{Name: "ls"}})
+	cli.Add(blobs, &BlobsGet{Name: cli.Name{Name: "get"}})
+	cli.Add(blobs, &BlobsLabel{Name: cli.Name{Name: "label"}})
+	cli.Add(blobs, &BlobsUnLabel{Name: cli.Name{Name: "unlabel"}})
+	cli.Add(blobs, &BlobsRm{Name: cli.Name{Name: "rm"}})
+	cli.Add(blobs, &BlobsPut{Name: cli.Name{Name: "put"}})
+	cli.Add(blobs, &BlobsCheck{Name: cli.Name{Name: "check"}})
+}
+
+type Blobs struct {
+	cli.Name `desc:"manage blobs of storage offline"`
+}
+
+type StoreFlags struct {
+	ConfigFile string `flag:"config,c" desc:"config file of storage, same as serve"`
+	Root       string `flag:"root" env:"MTK_STORAGE_ROOT" default:"" desc:"storage root, root of config used when empty"`
+}
+
+// withStore opens store of config directly, and shutdowns it after do.
+func (f *StoreFlags) withStore(ctx context.Context, do func(ctx context.Context, s storage.Store) error) error {
+	c, err := config.Load(f.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if f.Root != "" {
+		c.Storage.Root = f.Root
+	}
+
+	s, err := storage.New(c)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = s.Shutdown(context.Background())
+	}()
+
+	return do(storage.NewContextWithActor(ctx, cliActor()), s)
+}
+
+// cliActor names actor by os user, like cli:root
+func cliActor() storage.Actor {
+	name := os.Getenv("USER")
+	if name == "" {
+		name = "unknown"
+	}
+	return storage.Actor{Name: "cli:" + name}
+}
+
+func parseRef(s string) (blob.Ref, error) {
+	ref := blob.RefString{}
+	if err := ref.UnmarshalText([]byte(s)); err != nil {
+		return blob.Ref{}, err
+	}
+	return ref.Ref(), nil
+}
+
+// parseLabels parses labels in form of k=v
+func parseLabels(kvs []string) (blob.Labels, error) {
+	labels := blob.Labels{}
+	for _, kv := range kvs {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, errors.Errorf("invalid label %q, should be k=v", kv)
+		}
+		labels[k] = append(labels[k], v)
+	}
+	return labels, nil
+}
+
+type BlobsLs struct {
+	cli.Name `desc:"list blobs"`
+	StoreFlags
+	Time   string `flag:"time" default:"" desc:"date time range like 2022-01-01T00:00:00Z..2022-01-02T00:00:00Z, last 24 hours when empty"`
+	Filter string `flag:"filter" default:"" desc:"label filter like {_device_id=\"x\"}"`
+}
+
+func (c *BlobsLs) Run(ctx context.Context) error {
+	timeRange := blob.Last(24 * time.Hour)
+
+	if c.Time != "" {
+		tr := types.DateTimeRange{}
+		if err := tr.UnmarshalText([]byte(c.Time)); err != nil {
+			return err
+		}
+		timeRange = blob.TimeRange{From: tr.From, Through: tr.To}
+	}
+
+	filter := types.Filter{}
+	if err := filter.UnmarshalText([]byte(c.Filter)); err != nil {
+		return err
+	}
+
+	return c.withStore(ctx, func(ctx context.Context, s storage.Store) error {
+		list, err := s.Query(ctx, timeRange, blob.DefaultUser, filter.Matchers...)
+		if err != nil {
+			return err
+		}
+
+		w := bufio.NewWriter(os.Stdout)
+		for i := range list {
+			_, _ = fmt.Fprintln(w, list[i].String())
+		}
+		return w.Flush()
+	})
+}
+
+type BlobsGet struct {
+	cli.Name `args:"REF" desc:"get content of blob"`
+	StoreFlags
+	Output string `flag:"output,o" default:"-" desc:"output file, stdout when -"`
+}
+
+func (c *BlobsGet) Run(ctx context.Context) error {
+	ref, err := parseRef(c.Args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.withStore(ctx, func(ctx context.Context, s storage.Store) error {
+		info, err := s.Info(ctx, ref)
+		if err != nil {
+			return err
+		}
+
+		r, err := s.ReaderAt(ctx, info.Ref)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		var w io.Writer = os.Stdout
+
+		if c.Output != "-" {
+			f, err := os.Create(c.Output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		_, err = io.Copy(w, io.NewSectionReader(r, 0, r.Size()))
+		return err
+	})
+}
+
+type BlobsLabel struct {
+	cli.Name `args:"REF LABEL..." desc:"put labels k=v to blob"`
+	StoreFlags
+}
+
+func (c *BlobsLabel) Run(ctx context.Context) error {
+	ref, err := parseRef(c.Args[0])
+	if err != nil {
+		return err
+	}
+
+	labels, err := parseLabels(c.Args[1:])
+	if err != nil {
+		return err
+	}
+
+	return c.withStore(ctx, func(ctx context.Context, s storage.Store) error {
+		for name, values := range labels {
+			for _, value := range values {
+				if err := s.PutLabel(ctx, ref, name, value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+type BlobsUnLabel struct {
+	cli.Name `args:"REF LABEL..." desc:"delete labels k=v of blob"`
+	StoreFlags
+}
+
+func (c *BlobsUnLabel) Run(ctx context.Context) error {
+	ref, err := parseRef(c.Args[0])
+	if err != nil {
+		return err
+	}
+
+	labels, err := parseLabels(c.Args[1:])
+	if err != nil {
+		return err
+	}
+
+	return c.withStore(ctx, func(ctx context.Context, s storage.Store) error {
+		for name, values := range labels {
+			for _, value := range values {
+				if err := s.DeleteLabel(ctx, ref, name, value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+type BlobsRm struct {
+	cli.Name `args:"REF..." desc:"delete blobs"`
+	StoreFlags
+}
+
+func (c *BlobsRm) Run(ctx context.Context) error {
+	refs := make([]blob.Ref, len(c.Args))
+	for i := range c.Args {
+		ref, err := parseRef(c.Args[i])
+		if err != nil {
+			return err
+		}
+		refs[i] = ref
+	}
+
+	return c.withStore(ctx, func(ctx context.Context, s storage.Store) error {
+		for _, ref := range refs {
+			if err := s.Delete(ctx, ref); err != nil {
+				return errors.Wrap(err, ref.ExternalKey(""))
+			}
+		}
+		return nil
+	})
+}
+
+type BlobsPut struct {
+	cli.Name `args:"FILE" desc:"put file as blob, and print its ref"`
+	StoreFlags
+	Labels    []string `flag:"label,l" default:"" desc:"labels k=v of blob"`
+	MediaType string   `flag:"media-type" default:"" desc:"media type of blob, detected by file ext when empty"`
+}
+
+func (c *BlobsPut) Run(ctx context.Context) error {
+	labels, err := parseLabels(c.Labels)
+	if err != nil {
+		return err
+	}
+
+	for name := range labels {
+		if strings.HasPrefix(name, "_") {
+			return errors.Wrap(storage.ErrLabelImmutable, name)
+		}
+	}
+
+	f, err := os.Open(c.Args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	mediaType := c.MediaType
+	if mediaType == "" {
+		mediaType = mime.TypeByExtension(filepath.Ext(fi.Name()))
+	}
+	if mediaType != "" {
+		labels["_media_type"] = []string{mediaType}
+	}
+	labels["_size"] = []string{strconv.FormatInt(fi.Size(), 10)}
+
+	return c.withStore(ctx, func(ctx context.Context, s storage.Store) error {
+		w, err := s.Writer(ctx)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+
+		if err := w.Commit(ctx, fi.Size(), "", blob.WithLabels(labels)); err != nil {
+			return err
+		}
+
+		info := w.Info()
+		info.Labels = labels
+
+		_, err = fmt.Fprintln(os.Stdout, info.String())
+		return err
+	})
+}
+
+type BlobsCheck struct {
+	cli.Name `desc:"check consistency between content and label index, and print the report"`
+	StoreFlags
+	Time   string `flag:"time" default:"" desc:"date time range like 2022-01-01T00:00:00Z..2022-01-02T00:00:00Z, all when empty"`
+	Repair bool   `flag:"repair" default:"false" desc:"move orphan content into lost+found and mark dangling refs deleted"`
+}
+
+func (c *BlobsCheck) Run(ctx context.Context) error {
+	timeRange := blob.TimeRange{}
+
+	if c.Time != "" {
+		tr := types.DateTimeRange{}
+		if err := tr.UnmarshalText([]byte(c.Time)); err != nil {
+			return err
+		}
+		timeRange = blob.TimeRange{From: tr.From, Through: tr.To}
+	}
+
+	return c.withStore(ctx, func(ctx context.Context, s storage.Store) error {
+		report, err := s.Check(ctx, timeRange, c.Repair)
+		if err != nil {
+			return err
+		}
+
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(report)
+	})
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/innoai-tech/infra/pkg/cli"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	dataset := cli.Add(app, &Dataset{})
+
+	cli.Add(dataset, &DatasetImport{Name: cli.Name{Name: "import"}})
+	cli.Add(dataset, &DatasetVerify{Name: cli.Name{Name: "verify"}})
+	cli.Add(dataset, &DatasetKeygen{Name: cli.Name{Name: "keygen"}})
+}
+
+type Dataset struct {
+	cli.Name `desc:"manage datasets"`
+}
+
+type DatasetImport struct {
+	cli.Name `args:"ARCHIVE" desc:"import dataset archive exported by mtk, and print the report"`
+	StoreFlags
+	Conflict string `flag:"conflict" default:"skip" desc:"when blob existed, skip or merge labels"`
+}
+
+func (c *DatasetImport) Run(ctx context.Context) error {
+	f, err := os.Open(c.Args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.withStore(ctx, func(ctx context.Context, s storage.Store) error {
+		report, err := storage.ImportDataset(ctx, s, f, storage.ImportConflict(c.Conflict))
+		if err != nil {
+			return err
+		}
+
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(report)
+	})
+}
+
+type DatasetVerify struct {
+	cli.Name `args:"ARCHIVE" desc:"verify signed manifest and files of dataset archive offline"`
+	Key      string `flag:"key,k" desc:"ed25519 public key PEM file of signer"`
+}
+
+func (c *DatasetVerify) Run(ctx context.Context) error {
+	publicKey, err := storage.LoadVerifyKey(c.Key)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(c.Args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	report, err := storage.VerifyDataset(f, publicKey)
+	if err != nil {
+		return err
+	}
+
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	if err := e.Encode(report); err != nil {
+		return err
+	}
+
+	if !report.Valid {
+		return storage.ErrDatasetInvalid
+	}
+	return nil
+}
+
+type DatasetKeygen struct {
+	cli.Name `desc:"generate ed25519 key pair to sign datasets, as <output>.pem and <output>.pub.pem"`
+	Output   string `flag:"output,o" default:"dataset-signing-key" desc:"output filename prefix"`
+}
+
+func (c *DatasetKeygen) Run(ctx context.Context) error {
+	privateKeyPEM, publicKeyPEM, err := storage.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.Output+".pem", privateKeyPEM, 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(c.Output+".pub.pem", publicKeyPEM, 0644)
+}
@@ -3,9 +3,12 @@ package cmd
 import (
 	"context"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream/core"
+	"time"
 
 	"github.com/innoai-tech/infra/pkg/cli"
 	"github.com/innoai-tech/media-toolkit/internal/liveplayer"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
 )
 
 func init() {
@@ -13,8 +16,13 @@ func init() {
 }
 
 type ServeFlags struct {
-	Addr       string `flag:"addr" default:":777" desc:"serve address"`
-	ConfigFile string `flag:"config,c" desc:"config file"`
+	Addr           string `flag:"addr" default:":777" desc:"serve address"`
+	RTSPAddr       string `flag:"rtsp-addr" env:"MTK_RTSP_ADDR" default:"" desc:"rtsp server address to re-serve streams without auth, like :8554, disabled when empty"`
+	ConfigFile     string `flag:"config,c" desc:"config file of streams and storage"`
+	AdminToken     string `flag:"admin-token" env:"MTK_ADMIN_TOKEN" default:"" desc:"token of admin apis, admin apis disabled when empty"`
+	SigningKey     string `flag:"dataset-signing-key" env:"MTK_DATASET_SIGNING_KEY" default:"" desc:"ed25519 private key PEM file to sign exported datasets"`
+	ChunkLimit     int    `flag:"blob-chunk-limit" env:"MTK_BLOB_CHUNK_LIMIT" default:"0" desc:"max bytes of each range of blob downloads, no limit when 0"`
+	SnapshotMaxAge int    `flag:"snapshot-max-age" env:"MTK_SNAPSHOT_MAX_AGE" default:"1000" desc:"max age in milliseconds of cached snapshot of each stream"`
 }
 
 type Serve struct {
@@ -27,9 +35,25 @@ func (p *Serve) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	c, err := config.Load(p.ConfigFile)
+	if err != nil {
+		return err
+	}
 	player := &liveplayer.StreamPlayer{
-		Addr:    p.Addr,
-		Streams: streams,
+		Addr:           p.Addr,
+		RTSPAddr:       p.RTSPAddr,
+		AdminToken:     p.AdminToken,
+		BlobChunkLimit: int64(p.ChunkLimit),
+		SnapshotMaxAge: time.Duration(p.SnapshotMaxAge) * time.Millisecond,
+		Streams:        streams,
+		Config:         c,
+	}
+	if p.SigningKey != "" {
+		key, err := storage.LoadSigningKey(p.SigningKey)
+		if err != nil {
+			return err
+		}
+		player.DatasetSigningKey = key
 	}
 	return player.Serve(ctx)
 }
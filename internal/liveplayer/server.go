@@ -2,6 +2,7 @@ package liveplayer
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream/core"
 	"net/http"
@@ -17,11 +18,18 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/innoai-tech/media-toolkit/pkg/httputil"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream/server"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
 )
 
 type StreamPlayer struct {
-	Addr    string
-	Streams []core.Stream
+	Addr              string
+	RTSPAddr          string
+	AdminToken        string
+	DatasetSigningKey ed25519.PrivateKey
+	BlobChunkLimit    int64
+	SnapshotMaxAge    time.Duration
+	Streams           []core.Stream
+	Config            config.Config
 }
 
 func (p *StreamPlayer) Serve(ctx context.Context) error {
@@ -29,7 +37,14 @@ func (p *StreamPlayer) Serve(ctx context.Context) error {
 
 	router := mux.NewRouter()
 
-	lvs := server.NewLiveStreamServer(ctx, p.Streams)
+	lvs, err := server.NewLiveStreamServer(ctx, p.Streams, p.Config)
+	if err != nil {
+		return err
+	}
+	lvs.AdminToken = p.AdminToken
+	lvs.DatasetSigningKey = p.DatasetSigningKey
+	lvs.BlobChunkLimit = p.BlobChunkLimit
+	lvs.SnapshotMaxAge = p.SnapshotMaxAge
 
 	router.PathPrefix("/api").Handler(lvs.Handler())
 	router.PathPrefix("/").Handler(WebUI)
@@ -41,6 +56,8 @@ func (p *StreamPlayer) Serve(ctx context.Context) error {
 	s.Addr = p.Addr
 	s.Handler = router
 
+	rtspServer := lvs.RTSPServer()
+
 	stopCh := make(chan os.Signal, 1)
 	signal.Notify(stopCh, os.Interrupt, syscall.SIGTERM)
 
@@ -56,6 +73,16 @@ func (p *StreamPlayer) Serve(ctx context.Context) error {
 		}
 	}()
 
+	if p.RTSPAddr != "" {
+		go func() {
+			l.Info(fmt.Sprintf("rtsp server serve on rtsp://%s", p.RTSPAddr))
+
+			if e := rtspServer.ListenAndServe(ctx, p.RTSPAddr); e != nil {
+				l.Error(e, "")
+			}
+		}()
+	}
+
 	<-stopCh
 
 	timeout := 10 * time.Second
@@ -65,7 +92,7 @@ func (p *StreamPlayer) Serve(ctx context.Context) error {
 
 	wg := sync.WaitGroup{}
 
-	for _, canShutdown := range []CanShutdown{lvs, s} {
+	for _, canShutdown := range []CanShutdown{lvs, s, rtspServer} {
 		wg.Add(1)
 		go func(canShutdown CanShutdown) {
 			defer wg.Done()
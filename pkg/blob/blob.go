@@ -171,6 +171,8 @@ type Info struct {
 	Ref
 	Labels Labels `json:"labels"`
 	RefKey string `json:"ref"`
+	// Tier which holds the content
+	Tier string `json:"tier,omitempty"`
 }
 
 func (info Info) String() string {
@@ -186,6 +188,29 @@ func (info Info) String() string {
 	return b.String()
 }
 
+// ParseInfo parses info formatted by Info.String
+func ParseInfo(s string) (Info, error) {
+	i := strings.IndexByte(s, '{')
+	if i == -1 {
+		return Info{}, errors.Errorf("invalid info `%s`", s)
+	}
+
+	info, err := ParseExternalKey(s[:i], "")
+	if err != nil {
+		return Info{}, err
+	}
+
+	labels, err := ParseLabels(s[i:])
+	if err != nil {
+		return Info{}, err
+	}
+	if len(labels) > 0 {
+		info.Labels = labels
+	}
+
+	return info, nil
+}
+
 type Infos []Info
 
 func (infos Infos) Len() int {
@@ -14,3 +14,17 @@ func TestBlob(t *testing.T) {
 	Expect(t, err, Be[error](nil))
 	Expect(t, parsed, Equal(b))
 }
+
+func TestParseInfo(t *testing.T) {
+	b := FromString("x", WithLabels(Labels{
+		"_media_type": {"text/plain"},
+		"tag":         {"a", `b,"c"`},
+	}))
+
+	parsed, err := ParseInfo(b.String())
+	Expect(t, err, Be[error](nil))
+	Expect(t, parsed, Equal(b))
+
+	_, err = ParseInfo(b.ExternalKey("") + `{tag=a}`)
+	Expect(t, err, Not(Be[error](nil)))
+}
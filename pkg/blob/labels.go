@@ -4,6 +4,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 type Labels map[string][]string
@@ -33,4 +35,55 @@ func (labels Labels) String() string {
 	return b.String()
 }
 
+// ParseLabels parses labels formatted by Labels.String, like {a="1",b="2"}
+func ParseLabels(s string) (Labels, error) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, errors.Errorf("invalid labels `%s`", s)
+	}
+
+	labels := Labels{}
+
+	for rest := s[1 : len(s)-1]; rest != ""; {
+		i := strings.IndexByte(rest, '=')
+		if i <= 0 {
+			return nil, errors.Errorf("invalid labels `%s`", s)
+		}
+		name := rest[:i]
+
+		quoted, err := strconv.QuotedPrefix(rest[i+1:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value of label %s", name)
+		}
+		value, _ := strconv.Unquote(quoted)
+		labels[name] = append(labels[name], value)
+
+		rest = rest[i+1+len(quoted):]
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, errors.Errorf("invalid labels `%s`", s)
+			}
+			rest = rest[1:]
+		}
+	}
+
+	return labels, nil
+}
+
 const LabelDeleted = "__deleted__"
+
+// LabelHold marks blob under legal hold, which could not be deleted
+const LabelHold = "_hold"
+
+// LabelTier marks the tier which holds the content, hot when missing
+const LabelTier = "_tier"
+
+// LabelDerivedFrom marks blob derived from another blob, value is ref of source
+const LabelDerivedFrom = "_derived_from"
+
+// LabelRendition marks kind and params of rendition which derived blob is, like thumbnail?h=0&w=320
+const LabelRendition = "_rendition"
+
+const (
+	TierHot  = "hot"
+	TierCold = "cold"
+)
@@ -0,0 +1,104 @@
+package format
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/deepch/vdk/av"
+	"github.com/deepch/vdk/format/mp4"
+	"github.com/pkg/errors"
+)
+
+var ErrEmptyClip = errors.New("no packets in clip window")
+
+// Clip remuxes packets of mp4 file between the nearest keyframe before start and end into w, without re-encode.
+// Returns the actual window of clip, which starts at the keyframe.
+func Clip(ctx context.Context, filename string, w io.WriteSeeker, start time.Duration, end time.Duration) (from time.Duration, through time.Duration, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	demuxer := mp4.NewDemuxer(f)
+
+	streams, err := demuxer.Streams()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// packets since last keyframe before start
+	gop := make([]av.Packet, 0)
+
+	var muxer *mp4.Muxer
+
+	writePacket := func(pkt av.Packet) error {
+		if muxer == nil {
+			from = pkt.Time
+			muxer = mp4.NewMuxer(w)
+			if err := muxer.WriteHeader(streams); err != nil {
+				return err
+			}
+		}
+		through = pkt.Time + pkt.Duration
+		pkt.Time -= from
+		return muxer.WritePacket(pkt)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+
+		pkt, err := demuxer.ReadPacket()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, 0, err
+		}
+
+		if pkt.Time > end {
+			break
+		}
+
+		if muxer == nil {
+			if pkt.IsKeyFrame && pkt.Time <= start {
+				gop = gop[0:0]
+			}
+			if len(gop) == 0 && !pkt.IsKeyFrame {
+				// could not decode without keyframe
+				continue
+			}
+			gop = append(gop, pkt)
+
+			if pkt.Time < start {
+				continue
+			}
+
+			for i := range gop {
+				if err := writePacket(gop[i]); err != nil {
+					return 0, 0, err
+				}
+			}
+			gop = nil
+			continue
+		}
+
+		if err := writePacket(pkt); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if muxer == nil {
+		return 0, 0, ErrEmptyClip
+	}
+
+	if err := muxer.WriteTrailer(); err != nil {
+		return 0, 0, err
+	}
+
+	return from, through, nil
+}
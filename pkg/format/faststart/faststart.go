@@ -0,0 +1,357 @@
+package faststart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidMP4     = errors.New("invalid mp4")
+	ErrOffsetOverflow = errors.New("chunk offset overflow")
+)
+
+// BoxTypeMetadata is the box in moov/udta, which payload is Metadata in JSON
+const BoxTypeMetadata = "mtkm"
+
+// Metadata embedded in mp4, which keeps file self-describing after export
+type Metadata struct {
+	DeviceID  string              `json:"deviceID"`
+	StartedAt time.Time           `json:"startedAt"`
+	Labels    map[string][]string `json:"labels,omitempty"`
+}
+
+// Finalize writes mp4 of r into w, with ftyp, moov, then other boxes in order,
+// chunk offsets in moov are shifted as mdat moved, and metadata embedded in moov/udta when not nil.
+func Finalize(r io.ReaderAt, size int64, w io.Writer, metadata *Metadata) error {
+	boxes, err := readBoxes(r, 0, size)
+	if err != nil {
+		return err
+	}
+
+	var ftyp, moov *box
+	rest := make([]box, 0, len(boxes))
+
+	for i := range boxes {
+		switch boxes[i].typ {
+		case "ftyp":
+			ftyp = &boxes[i]
+		case "moov":
+			moov = &boxes[i]
+		default:
+			rest = append(rest, boxes[i])
+		}
+	}
+
+	if moov == nil {
+		return errors.Wrap(ErrInvalidMP4, "moov missing")
+	}
+
+	moovNode, err := readNode(r, moov)
+	if err != nil {
+		return err
+	}
+
+	if metadata != nil {
+		if err := embed(moovNode, metadata); err != nil {
+			return err
+		}
+	}
+
+	// size of moov never changed by shifting offsets
+	offset := moovNode.size()
+	if ftyp != nil {
+		offset += ftyp.size
+	}
+
+	shifted := make([]box, len(rest))
+	for i, b := range rest {
+		shifted[i] = b
+		shifted[i].offset = offset
+		offset += b.size
+	}
+
+	shift := func(o uint64) (uint64, error) {
+		for i, b := range rest {
+			if int64(o) >= b.offset && int64(o) < b.offset+b.size {
+				return uint64(int64(o) + shifted[i].offset - b.offset), nil
+			}
+		}
+		return 0, errors.Wrapf(ErrInvalidMP4, "chunk offset %d out of boxes", o)
+	}
+
+	if err := moovNode.walk(func(n *node) error {
+		switch n.typ {
+		case "stco":
+			return shiftChunkOffsets(n.payload, 4, shift)
+		case "co64":
+			return shiftChunkOffsets(n.payload, 8, shift)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if ftyp != nil {
+		if _, err := io.Copy(w, io.NewSectionReader(r, ftyp.offset, ftyp.size)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.Write(moovNode.bytes()); err != nil {
+		return err
+	}
+
+	for _, b := range rest {
+		if _, err := io.Copy(w, io.NewSectionReader(r, b.offset, b.size)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadMetadata reads Metadata embedded by Finalize
+func ReadMetadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	boxes, err := readBoxes(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range boxes {
+		if boxes[i].typ != "moov" {
+			continue
+		}
+
+		moovNode, err := readNode(r, &boxes[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if udta := moovNode.child("udta"); udta != nil {
+			if n := udta.child(BoxTypeMetadata); n != nil {
+				m := &Metadata{}
+				if err := json.Unmarshal(n.payload, m); err != nil {
+					return nil, errors.Wrap(ErrInvalidMP4, err.Error())
+				}
+				return m, nil
+			}
+		}
+	}
+
+	return nil, errors.Wrap(ErrInvalidMP4, "metadata missing")
+}
+
+func embed(moov *node, metadata *Metadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	udta := moov.child("udta")
+	if udta == nil {
+		udta = &node{typ: "udta", children: make([]*node, 0)}
+		moov.children = append(moov.children, udta)
+	}
+
+	children := make([]*node, 0, len(udta.children)+1)
+	for _, c := range udta.children {
+		// replace existed
+		if c.typ != BoxTypeMetadata {
+			children = append(children, c)
+		}
+	}
+	udta.children = append(children, &node{typ: BoxTypeMetadata, payload: data})
+
+	return nil
+}
+
+func shiftChunkOffsets(payload []byte, width int, shift func(o uint64) (uint64, error)) error {
+	// version & flags, entry count
+	if len(payload) < 8 {
+		return errors.Wrap(ErrInvalidMP4, "chunk offset box too short")
+	}
+
+	count := int(binary.BigEndian.Uint32(payload[4:8]))
+	if len(payload) < 8+count*width {
+		return errors.Wrap(ErrInvalidMP4, "chunk offset box too short")
+	}
+
+	for i := 0; i < count; i++ {
+		p := payload[8+i*width : 8+(i+1)*width]
+
+		if width == 4 {
+			o, err := shift(uint64(binary.BigEndian.Uint32(p)))
+			if err != nil {
+				return err
+			}
+			if o > math.MaxUint32 {
+				return ErrOffsetOverflow
+			}
+			binary.BigEndian.PutUint32(p, uint32(o))
+			continue
+		}
+
+		o, err := shift(binary.BigEndian.Uint64(p))
+		if err != nil {
+			return err
+		}
+		binary.BigEndian.PutUint64(p, o)
+	}
+
+	return nil
+}
+
+type box struct {
+	typ string
+	// offset of header
+	offset int64
+	// size of header
+	hdr int64
+	// size of whole box
+	size int64
+}
+
+func readBoxes(r io.ReaderAt, offset int64, end int64) ([]box, error) {
+	boxes := make([]box, 0)
+
+	buf := make([]byte, 16)
+
+	for offset < end {
+		if end-offset < 8 {
+			return nil, errors.Wrapf(ErrInvalidMP4, "truncated box at %d", offset)
+		}
+		if _, err := r.ReadAt(buf[0:8], offset); err != nil {
+			return nil, err
+		}
+
+		b := box{
+			typ:    string(buf[4:8]),
+			offset: offset,
+			hdr:    8,
+			size:   int64(binary.BigEndian.Uint32(buf[0:4])),
+		}
+
+		switch b.size {
+		case 0:
+			// extends to end of file
+			b.size = end - offset
+		case 1:
+			if _, err := r.ReadAt(buf[8:16], offset+8); err != nil {
+				return nil, err
+			}
+			b.hdr = 16
+			b.size = int64(binary.BigEndian.Uint64(buf[8:16]))
+		}
+
+		if b.size < b.hdr || b.size > end-offset {
+			return nil, errors.Wrapf(ErrInvalidMP4, "invalid size of box %s at %d", b.typ, offset)
+		}
+
+		boxes = append(boxes, b)
+		offset += b.size
+	}
+
+	return boxes, nil
+}
+
+// containers on path to chunk offsets, and user data
+var containers = map[string]bool{
+	"moov": true,
+	"trak": true,
+	"mdia": true,
+	"minf": true,
+	"stbl": true,
+	"udta": true,
+}
+
+// node of box tree in moov
+type node struct {
+	typ      string
+	payload  []byte
+	children []*node
+}
+
+func readNode(r io.ReaderAt, b *box) (*node, error) {
+	data := make([]byte, b.size-b.hdr)
+	if _, err := r.ReadAt(data, b.offset+b.hdr); err != nil {
+		return nil, err
+	}
+	return parseNode(b.typ, data)
+}
+
+func parseNode(typ string, data []byte) (*node, error) {
+	n := &node{typ: typ}
+
+	if !containers[typ] {
+		n.payload = data
+		return n, nil
+	}
+
+	boxes, err := readBoxes(bytes.NewReader(data), 0, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	n.children = make([]*node, 0, len(boxes))
+	for _, b := range boxes {
+		c, err := parseNode(b.typ, data[b.offset+b.hdr:b.offset+b.size])
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, c)
+	}
+
+	return n, nil
+}
+
+func (n *node) child(typ string) *node {
+	for _, c := range n.children {
+		if c.typ == typ {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) walk(fn func(n *node) error) error {
+	if err := fn(n); err != nil {
+		return err
+	}
+	for _, c := range n.children {
+		if err := c.walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *node) size() int64 {
+	size := int64(8 + len(n.payload))
+	for _, c := range n.children {
+		size += c.size()
+	}
+	return size
+}
+
+func (n *node) bytes() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, n.size()))
+	n.writeTo(buf)
+	return buf.Bytes()
+}
+
+func (n *node) writeTo(buf *bytes.Buffer) {
+	hdr := make([]byte, 8)
+	binary.BigEndian.PutUint32(hdr[0:4], uint32(n.size()))
+	copy(hdr[4:8], n.typ)
+	buf.Write(hdr)
+	buf.Write(n.payload)
+	for _, c := range n.children {
+		c.writeTo(buf)
+	}
+}
@@ -0,0 +1,89 @@
+package faststart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	. "github.com/octohelm/x/testing"
+)
+
+func TestFinalize(t *testing.T) {
+	ftyp := rawBox("ftyp", []byte("isom\x00\x00\x02\x00"))
+	mdat := rawBox("mdat", []byte("frame0frame1"))
+
+	// chunks at start of each frame in mdat
+	chunkOffsets := []uint32{uint32(len(ftyp) + 8), uint32(len(ftyp) + 8 + 6)}
+
+	stco := make([]byte, 8+4*len(chunkOffsets))
+	binary.BigEndian.PutUint32(stco[4:8], uint32(len(chunkOffsets)))
+	for i, o := range chunkOffsets {
+		binary.BigEndian.PutUint32(stco[8+i*4:], o)
+	}
+
+	moov := rawBox("moov", rawBox("mvhd", make([]byte, 100)), rawBox("trak",
+		rawBox("mdia", rawBox("minf", rawBox("stbl", rawBox("stco", stco)))),
+	))
+
+	src := bytes.Join([][]byte{ftyp, mdat, moov}, nil)
+
+	metadata := &Metadata{
+		DeviceID:  "1",
+		StartedAt: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC),
+		Labels:    map[string][]string{"tag": {"test"}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := Finalize(bytes.NewReader(src), int64(len(src)), buf, metadata)
+	Expect(t, err, Be[error](nil))
+
+	dst := buf.Bytes()
+
+	t.Run("moov in front of mdat", func(t *testing.T) {
+		boxes, err := readBoxes(bytes.NewReader(dst), 0, int64(len(dst)))
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(boxes), Be(3))
+		Expect(t, boxes[0].typ, Be("ftyp"))
+		Expect(t, boxes[1].typ, Be("moov"))
+		Expect(t, boxes[2].typ, Be("mdat"))
+
+		moovNode, err := readNode(bytes.NewReader(dst), &boxes[1])
+		Expect(t, err, Be[error](nil))
+
+		stcoNode := moovNode.child("trak").child("mdia").child("minf").child("stbl").child("stco")
+		o0 := binary.BigEndian.Uint32(stcoNode.payload[8:12])
+		o1 := binary.BigEndian.Uint32(stcoNode.payload[12:16])
+		Expect(t, string(dst[o0:o0+6]), Be("frame0"))
+		Expect(t, string(dst[o1:o1+6]), Be("frame1"))
+	})
+
+	t.Run("metadata embedded", func(t *testing.T) {
+		m, err := ReadMetadata(bytes.NewReader(dst), int64(len(dst)))
+		Expect(t, err, Be[error](nil))
+		Expect(t, m.DeviceID, Be("1"))
+		Expect(t, m.StartedAt.Equal(metadata.StartedAt), Be(true))
+		Expect(t, m.Labels, Equal(metadata.Labels))
+	})
+
+	t.Run("finalize again", func(t *testing.T) {
+		again := bytes.NewBuffer(nil)
+		err := Finalize(bytes.NewReader(dst), int64(len(dst)), again, metadata)
+		Expect(t, err, Be[error](nil))
+		Expect(t, again.Bytes(), Equal(dst))
+	})
+
+	t.Run("moov missing", func(t *testing.T) {
+		src := bytes.Join([][]byte{ftyp, mdat}, nil)
+		err := Finalize(bytes.NewReader(src), int64(len(src)), bytes.NewBuffer(nil), nil)
+		Expect(t, err, Not(Be[error](nil)))
+	})
+}
+
+func rawBox(typ string, payloads ...[]byte) []byte {
+	payload := bytes.Join(payloads, nil)
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b[0:4], uint32(8+len(payload)))
+	copy(b[4:8], typ)
+	return append(b, payload...)
+}
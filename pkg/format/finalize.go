@@ -0,0 +1,50 @@
+package format
+
+import (
+	"os"
+	"strings"
+
+	"github.com/innoai-tech/media-toolkit/pkg/format/faststart"
+)
+
+// Finalize relocates moov of recorded mp4 to front, and embeds device id, start time and user labels,
+// returns the finalized temp file, which should be removed after committed.
+func Finalize(f *os.File, info Info) (*os.File, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	finalized, err := os.CreateTemp("", "video-faststart-")
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &faststart.Metadata{
+		DeviceID:  info.ID,
+		StartedAt: info.StartedAt,
+		Labels:    userLabels(info.Labels),
+	}
+
+	if err := faststart.Finalize(f, fi.Size(), finalized, metadata); err != nil {
+		_ = finalized.Close()
+		_ = os.Remove(finalized.Name())
+		return nil, err
+	}
+
+	return finalized, nil
+}
+
+// userLabels excludes system labels prefixed with _
+func userLabels(labels map[string][]string) map[string][]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	lbs := map[string][]string{}
+	for k, v := range labels {
+		if !strings.HasPrefix(k, "_") {
+			lbs[k] = v
+		}
+	}
+	return lbs
+}
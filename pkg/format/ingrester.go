@@ -5,39 +5,53 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
 	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
 	"github.com/innoai-tech/media-toolkit/pkg/types"
 	"io"
 	"strconv"
 )
 
-func CommitTo(ctx context.Context, f io.Reader, store storage.Ingester, info Info) error {
+// CommitTo writes f into store, returns info of committed blob.
+func CommitTo(ctx context.Context, f io.Reader, store storage.Ingester, info Info) (*blob.Info, error) {
 	l := logr.FromContextOrDiscard(ctx)
 
 	cw, err := store.Writer(ctx)
 	if err != nil {
 		l.Error(err, "create writer failed")
-		return err
+		return nil, err
 	}
 
 	// mv to start for Read
 	if s, ok := f.(io.Seeker); ok {
 		if _, err := s.Seek(0, 0); err != nil {
 			l.Error(err, "seek failed")
-			return err
+			return nil, err
 		}
 	}
 
 	size, err := io.Copy(cw, f)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return cw.Commit(ctx, size, cw.Info().Digest(),
+	lbs := userLabels(info.Labels)
+	if lbs == nil {
+		lbs = map[string][]string{}
+	}
+	lbs["_media_type"] = []string{info.MediaType}
+	lbs["_device_id"] = []string{info.ID}
+	lbs["_size"] = []string{strconv.Itoa(int(size))}
+
+	opts := []blob.Opt{
 		blob.WithFromThough(types.TimeFromUnixNano(info.StartedAt.UnixNano()), types.TimeFromUnixNano(info.At.UnixNano())),
-		blob.WithLabels(map[string][]string{
-			"_media_type": {info.MediaType},
-			"_device_id":  {info.ID},
-			"_size":       {strconv.Itoa(int(size))},
-		}),
-	)
+		blob.WithLabels(lbs),
+	}
+
+	if err := cw.Commit(ctx, size, cw.Info().Digest(), opts...); err != nil {
+		return nil, err
+	}
+
+	committed := cw.Info()
+	content.CompleteInfo(&committed, opts...)
+	return &committed, nil
 }
@@ -17,6 +17,8 @@ type Info struct {
 	MediaType string
 	At        time.Time
 	StartedAt time.Time
+	// Labels of user
+	Labels map[string][]string
 }
 
 type Recorder interface {
@@ -0,0 +1,242 @@
+package httputil
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Content served with conditional requests per RFC 7232, and range requests per RFC 7233.
+type Content struct {
+	io.ReaderAt
+	Size        int64
+	ContentType string
+	// ETag quoted, strong validator
+	ETag         string
+	LastModified time.Time
+	// Disposition like `inline; filename="x.mp4"`, omitted when empty
+	Disposition string
+	// ChunkLimit caps length of each range, no limit when 0
+	ChunkLimit int64
+	// Header extra, like Cache-Control
+	Header http.Header
+}
+
+// ServeContent serves content as http.ServeContent, but ranges capped by chunk limit.
+func ServeContent(rw http.ResponseWriter, req *http.Request, c *Content) {
+	header := rw.Header()
+
+	for k, values := range c.Header {
+		header[k] = values
+	}
+	if c.ETag != "" {
+		header.Set("ETag", c.ETag)
+	}
+	if !c.LastModified.IsZero() {
+		header.Set("Last-Modified", c.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if c.Disposition != "" {
+		header.Set("Content-Disposition", c.Disposition)
+	}
+	header.Set("Accept-Ranges", "bytes")
+
+	switch checkPreconditions(req, c) {
+	case http.StatusNotModified:
+		// RFC 7232 section 4.1, no representation headers
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		header.Del("Content-Disposition")
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	case http.StatusPreconditionFailed:
+		rw.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	contentType := c.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	rangeHeader := req.Header.Get("Range")
+	if !checkIfRange(req, c) {
+		rangeHeader = ""
+	}
+
+	ranges, err := ParseRange(rangeHeader, c.Size)
+	if err != nil {
+		header.Set("Content-Range", fmt.Sprintf("bytes */%d", c.Size))
+		http.Error(rw, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	if sumRangesSize(ranges) > c.Size {
+		// The total number of bytes in all the ranges is larger than the size of the file,
+		// which could be an attack, send whole content.
+		ranges = nil
+	}
+
+	if c.ChunkLimit > 0 {
+		for i := range ranges {
+			if ranges[i].Length > c.ChunkLimit {
+				ranges[i].Length = c.ChunkLimit
+			}
+		}
+	}
+
+	code := http.StatusOK
+	sendSize := c.Size
+	var body io.Reader = io.NewSectionReader(c, 0, c.Size)
+
+	switch {
+	case len(ranges) == 1:
+		rng := ranges[0]
+		code = http.StatusPartialContent
+		sendSize = rng.Length
+		body = io.NewSectionReader(c, rng.Start, rng.Length)
+		header.Set("Content-Range", rng.ContentRange(c.Size))
+		header.Set("Content-Type", contentType)
+	case len(ranges) > 1:
+		code = http.StatusPartialContent
+		sendSize = multipartSize(ranges, contentType, c.Size)
+
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		header.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		body = pr
+		defer pr.Close()
+
+		go func() {
+			for _, rng := range ranges {
+				part, err := mw.CreatePart(rangeHeaderOf(rng, contentType, c.Size))
+				if err != nil {
+					_ = pw.CloseWithError(err)
+					return
+				}
+				if _, err := io.Copy(part, io.NewSectionReader(c, rng.Start, rng.Length)); err != nil {
+					_ = pw.CloseWithError(err)
+					return
+				}
+			}
+			_ = mw.Close()
+			_ = pw.Close()
+		}()
+	default:
+		header.Set("Content-Type", contentType)
+	}
+
+	header.Set("Content-Length", strconv.FormatInt(sendSize, 10))
+
+	rw.WriteHeader(code)
+
+	if req.Method != http.MethodHead {
+		_, _ = io.CopyN(rw, body, sendSize)
+	}
+}
+
+func checkPreconditions(req *http.Request, c *Content) int {
+	// RFC 7232 section 6, If-Match, then If-Unmodified-Since
+	if im := req.Header.Get("If-Match"); im != "" {
+		if !MatchETag(im, c.ETag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := req.Header.Get("If-Unmodified-Since"); ius != "" && !c.LastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && c.LastModified.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	isGetOrHead := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	// then If-None-Match, or If-Modified-Since
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		if MatchETag(inm, c.ETag, true) {
+			if isGetOrHead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := req.Header.Get("If-Modified-Since"); ims != "" && isGetOrHead && !c.LastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !c.LastModified.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+
+	return http.StatusOK
+}
+
+// checkIfRange returns false when Range should be ignored, RFC 7233 section 3.2
+func checkIfRange(req *http.Request, c *Content) bool {
+	ir := req.Header.Get("If-Range")
+	if ir == "" || req.Header.Get("Range") == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, `W/"`) {
+		return c.ETag != "" && !strings.HasPrefix(ir, "W/") && ir == c.ETag
+	}
+	if c.LastModified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ir)
+	return err == nil && t.Unix() == c.LastModified.Unix()
+}
+
+// MatchETag matches etag in list of If-Match or If-None-Match, weak comparison for If-None-Match
+func MatchETag(list string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, v := range strings.Split(list, ",") {
+		v = textproto.TrimString(v)
+		if v == "*" {
+			return true
+		}
+		if weak {
+			v = strings.TrimPrefix(v, "W/")
+		} else if strings.HasPrefix(v, "W/") {
+			continue
+		}
+		if v == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func sumRangesSize(ranges []Range) (size int64) {
+	for _, ra := range ranges {
+		size += ra.Length
+	}
+	return
+}
+
+func rangeHeaderOf(r Range, contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {r.ContentRange(size)},
+		"Content-Type":  {contentType},
+	}
+}
+
+// multipartSize returns the size of multipart/byteranges body
+func multipartSize(ranges []Range, contentType string, size int64) int64 {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	for _, ra := range ranges {
+		_, _ = mw.CreatePart(rangeHeaderOf(ra, contentType, size))
+		w += countingWriter(ra.Length)
+	}
+	_ = mw.Close()
+	return int64(w)
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
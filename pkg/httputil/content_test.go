@@ -0,0 +1,112 @@
+package httputil
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/octohelm/x/testing"
+)
+
+func TestServeContent(t *testing.T) {
+	data := "0123456789"
+	lastModified := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	serve := func(method string, header http.Header, chunkLimit int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rw := httptest.NewRecorder()
+		ServeContent(rw, req, &Content{
+			ReaderAt:     strings.NewReader(data),
+			Size:         int64(len(data)),
+			ContentType:  "text/plain",
+			ETag:         `"abc"`,
+			LastModified: lastModified,
+			ChunkLimit:   chunkLimit,
+		})
+		return rw
+	}
+
+	t.Run("Full", func(t *testing.T) {
+		rw := serve(http.MethodGet, nil, 0)
+		Expect(t, rw.Code, Be(http.StatusOK))
+		Expect(t, rw.Body.String(), Be(data))
+		Expect(t, rw.Header().Get("Accept-Ranges"), Be("bytes"))
+		Expect(t, rw.Header().Get("ETag"), Be(`"abc"`))
+		Expect(t, rw.Header().Get("Last-Modified"), Be(lastModified.Format(http.TimeFormat)))
+	})
+
+	t.Run("Head", func(t *testing.T) {
+		rw := serve(http.MethodHead, nil, 0)
+		Expect(t, rw.Code, Be(http.StatusOK))
+		Expect(t, rw.Body.Len(), Be(0))
+		Expect(t, rw.Header().Get("Content-Length"), Be("10"))
+	})
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		rw := serve(http.MethodGet, http.Header{"If-None-Match": {`W/"abc", "x"`}}, 0)
+		Expect(t, rw.Code, Be(http.StatusNotModified))
+		Expect(t, rw.Body.Len(), Be(0))
+	})
+
+	t.Run("If-Modified-Since", func(t *testing.T) {
+		rw := serve(http.MethodGet, http.Header{"If-Modified-Since": {lastModified.Format(http.TimeFormat)}}, 0)
+		Expect(t, rw.Code, Be(http.StatusNotModified))
+	})
+
+	t.Run("Single range", func(t *testing.T) {
+		rw := serve(http.MethodGet, http.Header{"Range": {"bytes=2-5"}}, 0)
+		Expect(t, rw.Code, Be(http.StatusPartialContent))
+		Expect(t, rw.Body.String(), Be("2345"))
+		Expect(t, rw.Header().Get("Content-Range"), Be("bytes 2-5/10"))
+	})
+
+	t.Run("Range capped by chunk limit", func(t *testing.T) {
+		rw := serve(http.MethodGet, http.Header{"Range": {"bytes=2-"}}, 3)
+		Expect(t, rw.Code, Be(http.StatusPartialContent))
+		Expect(t, rw.Body.String(), Be("234"))
+		Expect(t, rw.Header().Get("Content-Range"), Be("bytes 2-4/10"))
+	})
+
+	t.Run("If-Range not matched", func(t *testing.T) {
+		rw := serve(http.MethodGet, http.Header{"Range": {"bytes=2-5"}, "If-Range": {`"old"`}}, 0)
+		Expect(t, rw.Code, Be(http.StatusOK))
+		Expect(t, rw.Body.String(), Be(data))
+	})
+
+	t.Run("Range not satisfiable", func(t *testing.T) {
+		rw := serve(http.MethodGet, http.Header{"Range": {"bytes=20-"}}, 0)
+		Expect(t, rw.Code, Be(http.StatusRequestedRangeNotSatisfiable))
+		Expect(t, rw.Header().Get("Content-Range"), Be("bytes */10"))
+	})
+
+	t.Run("Multiple ranges", func(t *testing.T) {
+		rw := serve(http.MethodGet, http.Header{"Range": {"bytes=0-1,-2"}}, 0)
+		Expect(t, rw.Code, Be(http.StatusPartialContent))
+		Expect(t, int64(rw.Body.Len()), Be(rw.Result().ContentLength))
+
+		mediaType, params, err := mime.ParseMediaType(rw.Header().Get("Content-Type"))
+		Expect(t, err, Be[error](nil))
+		Expect(t, mediaType, Be("multipart/byteranges"))
+
+		parts := make([]string, 0)
+		mr := multipart.NewReader(rw.Body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			Expect(t, err, Be[error](nil))
+			b, _ := io.ReadAll(p)
+			parts = append(parts, p.Header.Get("Content-Range")+" "+string(b))
+		}
+		Expect(t, parts, Equal([]string{"bytes 0-1/10 01", "bytes 8-9/10 89"}))
+	})
+}
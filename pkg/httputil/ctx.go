@@ -0,0 +1,17 @@
+package httputil
+
+import "context"
+
+type chunkLimitContextKey struct{}
+
+// ChunkLimitFromContext returns limit of length of each range of downloads, no limit when 0
+func ChunkLimitFromContext(ctx context.Context) int64 {
+	if v, ok := ctx.Value(chunkLimitContextKey{}).(int64); ok {
+		return v
+	}
+	return 0
+}
+
+func NewContextWithChunkLimit(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, chunkLimitContextKey{}, limit)
+}
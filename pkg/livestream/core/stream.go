@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
 )
@@ -11,12 +12,22 @@ type Stream struct {
 	Rtsp string `json:"rtsp"`
 }
 
+// LoadStreams loads streams from config file, which is a list of streams, or object with streams in field `streams`
 func LoadStreams(configFile string) ([]Stream, error) {
 	jsonRaw, err := os.ReadFile(configFile)
 	if err != nil {
 		return nil, err
 	}
 	streams := make([]Stream, 0)
+	if jsonRaw = bytes.TrimSpace(jsonRaw); len(jsonRaw) > 0 && jsonRaw[0] == '{' {
+		c := struct {
+			Streams []Stream `json:"streams"`
+		}{Streams: streams}
+		if err = json.Unmarshal(jsonRaw, &c); err != nil {
+			return nil, err
+		}
+		return c.Streams, nil
+	}
 	if err = json.Unmarshal(jsonRaw, &streams); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,104 @@
+package livestream
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/codec"
+)
+
+// maxGOPFrames bounds memory of gop cache, gop dropped until next keyframe when exceeded.
+const maxGOPFrames = 300
+
+// encodedFrame is encoded buffer with sequence in stream
+type encodedFrame struct {
+	mediadevices.EncodedBuffer
+	Seq        uint64
+	IsKeyFrame bool
+}
+
+// gopCache keeps frames since the last keyframe, for late joiners to get first frame instantly.
+type gopCache struct {
+	mu     sync.Mutex
+	seq    uint64
+	frames []encodedFrame
+}
+
+func (c *gopCache) push(buf mediadevices.EncodedBuffer) encodedFrame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+
+	frame := encodedFrame{
+		EncodedBuffer: buf,
+		Seq:           c.seq,
+		IsKeyFrame:    isH264KeyFrame(buf.Data),
+	}
+
+	if frame.IsKeyFrame {
+		c.frames = c.frames[0:0]
+	}
+
+	if (len(c.frames) > 0 || frame.IsKeyFrame) && len(c.frames) < maxGOPFrames {
+		c.frames = append(c.frames, frame)
+	} else {
+		c.frames = c.frames[0:0]
+	}
+
+	return frame
+}
+
+// snapshot returns copy of cached frames
+func (c *gopCache) snapshot() []encodedFrame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append(make([]encodedFrame, 0, len(c.frames)), c.frames...)
+}
+
+// keyFrameRequester forces encoder to produce keyframe, at most once in interval shared by all subscribers.
+type keyFrameRequester struct {
+	controller codec.EncoderController
+	interval   time.Duration
+	last       int64
+}
+
+var _ codec.KeyFrameController = &keyFrameRequester{}
+
+func (r *keyFrameRequester) ForceKeyFrame() error {
+	kfc, ok := r.controller.(codec.KeyFrameController)
+	if !ok {
+		return nil
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&r.last)
+
+	if now-last < int64(r.interval) || !atomic.CompareAndSwapInt64(&r.last, last, now) {
+		return nil
+	}
+
+	return kfc.ForceKeyFrame()
+}
+
+// isH264KeyFrame checks IDR slice in H264 Annex B stream
+func isH264KeyFrame(data []byte) bool {
+	zeros := 0
+	for i := 0; i < len(data); i++ {
+		switch {
+		case data[i] == 0:
+			zeros++
+		case data[i] == 1 && zeros >= 2:
+			if i+1 < len(data) && data[i+1]&0x1f == 5 {
+				return true
+			}
+			zeros = 0
+		default:
+			zeros = 0
+		}
+	}
+	return false
+}
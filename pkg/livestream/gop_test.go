@@ -0,0 +1,31 @@
+package livestream
+
+import (
+	"testing"
+
+	. "github.com/octohelm/x/testing"
+	"github.com/pion/mediadevices"
+)
+
+func TestGOPCache(t *testing.T) {
+	idr := []byte{0, 0, 0, 1, 0x67, 0x42, 0, 0, 0, 1, 0x68, 0xce, 0, 0, 1, 0x65, 0x88}
+	nonIDR := []byte{0, 0, 0, 1, 0x41, 0x9a}
+
+	Expect(t, isH264KeyFrame(idr), Be(true))
+	Expect(t, isH264KeyFrame(nonIDR), Be(false))
+
+	c := &gopCache{}
+
+	c.push(mediadevices.EncodedBuffer{Data: nonIDR})
+	Expect(t, len(c.snapshot()), Be(0))
+
+	c.push(mediadevices.EncodedBuffer{Data: idr})
+	c.push(mediadevices.EncodedBuffer{Data: nonIDR})
+	Expect(t, len(c.snapshot()), Be(2))
+
+	last := c.push(mediadevices.EncodedBuffer{Data: idr})
+	frames := c.snapshot()
+	Expect(t, len(frames), Be(1))
+	Expect(t, frames[0].Seq, Be(last.Seq))
+	Expect(t, frames[0].IsKeyFrame, Be(true))
+}
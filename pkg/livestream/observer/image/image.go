@@ -3,8 +3,10 @@ package image
 import (
 	"context"
 	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
 	"github.com/innoai-tech/media-toolkit/pkg/format"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream"
+	"github.com/innoai-tech/media-toolkit/pkg/rendition"
 	"github.com/innoai-tech/media-toolkit/pkg/storage"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/mime"
 	"github.com/innoai-tech/media-toolkit/pkg/util/ioutil"
@@ -14,55 +16,140 @@ import (
 	"time"
 )
 
-func New(s storage.Ingester) livestream.StreamObserver {
-	return &imageWriter{
+type Options struct {
+	// Quality of jpeg, 1-100
+	Quality int
+	// Scale of frame size, (0,1]
+	Scale float64
+	// Burst frames to take
+	Burst int
+	// Interval between frames of burst
+	Interval time.Duration
+}
+
+type OptFunc func(o *Options)
+
+func (o *Options) Apply(opts ...OptFunc) {
+	for i := range opts {
+		opts[i](o)
+	}
+}
+
+func New(s storage.Ingester, opts ...OptFunc) *Observer {
+	options := &Options{
+		Quality: jpeg.DefaultQuality,
+		Scale:   1,
+		Burst:   1,
+	}
+
+	options.Apply(opts...)
+
+	return &Observer{
 		s:             s,
+		options:       *options,
+		taken:         make(chan struct{}),
 		CloseNotifier: syncutil.NewCloseNotifier(),
 	}
 }
 
-type imageWriter struct {
+// Observer takes pictures from stream, and commits them to storage.
+type Observer struct {
 	syncutil.CloseNotifier
-	s storage.Ingester
+	s       storage.Ingester
+	options Options
+
+	taken chan struct{}
+	infos []blob.Info
+	err   error
 }
 
-func (w *imageWriter) Name() string {
+var _ livestream.StreamObserver = &Observer{}
+
+func (w *Observer) Name() string {
 	return "Image"
 }
 
-func (w *imageWriter) OnVideoSource(ctx context.Context, videoSource livestream.VideoSource) {
-	if err := w.takePic(videoSource); err != nil {
-		logr.FromContextOrDiscard(ctx).Error(err, "take pic failed")
+// Wait returns infos of committed pictures, once all taken.
+func (w *Observer) Wait(ctx context.Context) ([]blob.Info, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-w.taken:
+		return w.infos, w.err
 	}
 }
 
-func (w *imageWriter) takePic(videoSource livestream.VideoSource) error {
+func (w *Observer) OnVideoSource(ctx context.Context, videoSource livestream.VideoSource) {
 	defer w.Shutdown(nil)
+	defer close(w.taken)
+
+	w.infos, w.err = w.takePics(ctx, videoSource)
+	if w.err != nil {
+		logr.FromContextOrDiscard(ctx).Error(w.err, "take pic failed")
+	}
+}
+
+func (w *Observer) takePics(ctx context.Context, videoSource livestream.VideoSource) ([]blob.Info, error) {
+	infos := make([]blob.Info, 0, w.options.Burst)
+
+	for i := 0; i < w.options.Burst; i++ {
+		if i > 0 && w.options.Interval > 0 {
+			select {
+			case <-ctx.Done():
+				return infos, ctx.Err()
+			case <-time.After(w.options.Interval):
+			}
+		}
+
+		info, err := w.takePic(videoSource)
+		if err != nil {
+			return infos, err
+		}
+		infos = append(infos, *info)
+	}
+
+	return infos, nil
+}
 
+func (w *Observer) takePic(videoSource livestream.VideoSource) (*blob.Info, error) {
+	// new reader for each pic, to start from next frame
 	r, err := videoSource.NewReader()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	img, release, err := r.Read()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer release()
 
+	if w.options.Scale > 0 && w.options.Scale < 1 {
+		b := img.Bounds()
+		img = rendition.Scale(img, int(float64(b.Dx())*w.options.Scale), int(float64(b.Dy())*w.options.Scale))
+	}
+
 	now := time.Now()
 
-	return ioutil.Pipe(
-		func(w io.Writer) error {
-			return jpeg.Encode(w, img, nil)
+	var info *blob.Info
+
+	err = ioutil.Pipe(
+		func(wr io.Writer) error {
+			return jpeg.Encode(wr, img, &jpeg.Options{Quality: w.options.Quality})
 		},
-		func(r io.Reader) error {
-			return format.CommitTo(context.Background(), r, w.s, format.Info{
+		func(r io.Reader) (err error) {
+			info, err = format.CommitTo(context.Background(), r, w.s, format.Info{
 				ID:        videoSource.ID(),
 				MediaType: mime.MediaTypeImageJPEG,
 				StartedAt: now,
 				At:        now,
 			})
+			return err
 		},
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
 }
@@ -0,0 +1,226 @@
+package mjpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/livestream"
+	"github.com/innoai-tech/media-toolkit/pkg/rendition"
+	"github.com/innoai-tech/media-toolkit/pkg/util/syncutil"
+	"github.com/pkg/errors"
+)
+
+var ErrObserverClosed = errors.New("mjpeg observer closed")
+
+type Options struct {
+	// FPS of jpeg frames
+	FPS int
+	// Width and Height of box to fit in, size of source when 0
+	Width  int
+	Height int
+	// Quality of jpeg, 1-100
+	Quality int
+}
+
+type OptFunc func(o *Options)
+
+func (o *Options) Apply(opts ...OptFunc) {
+	for i := range opts {
+		opts[i](o)
+	}
+}
+
+// New creates observer encodes frames as jpeg once, for all viewers with same options.
+func New(opts ...OptFunc) *Observer {
+	options := &Options{
+		FPS:     5,
+		Quality: jpeg.DefaultQuality,
+	}
+
+	options.Apply(opts...)
+
+	return &Observer{
+		CloseNotifier: syncutil.NewCloseNotifier(),
+		options:       *options,
+		viewers:       map[*Viewer]bool{},
+		stop:          make(chan struct{}),
+	}
+}
+
+// Frame of jpeg
+type Frame struct {
+	At   time.Time
+	Data []byte
+}
+
+type Observer struct {
+	syncutil.CloseNotifier
+	options Options
+
+	mu      sync.Mutex
+	viewers map[*Viewer]bool
+	last    *Frame
+	closed  bool
+	stop    chan struct{}
+}
+
+var _ livestream.CanUniqueKey = &Observer{}
+
+func (o *Observer) Name() string {
+	return "MJPEG"
+}
+
+// UniqueKey makes stream subject share one observer for same options
+func (o *Observer) UniqueKey() any {
+	return fmt.Sprintf("%dfps:%dx%d:q%d", o.options.FPS, o.options.Width, o.options.Height, o.options.Quality)
+}
+
+// Watch adds viewer, which receives the last frame first.
+// Returns ErrObserverClosed when all viewers left, should subscribe new one.
+func (o *Observer) Watch() (*Viewer, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil, ErrObserverClosed
+	}
+
+	v := &Viewer{
+		o:      o,
+		frames: make(chan Frame, 1),
+	}
+
+	if o.last != nil {
+		v.frames <- *o.last
+	}
+
+	o.viewers[v] = true
+
+	return v, nil
+}
+
+func (o *Observer) leave(v *Viewer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.viewers[v]; !ok {
+		return
+	}
+
+	delete(o.viewers, v)
+
+	if len(o.viewers) == 0 && !o.closed {
+		o.closed = true
+		close(o.stop)
+	}
+}
+
+func (o *Observer) OnVideoSource(ctx context.Context, videoSource livestream.VideoSource) {
+	go func() {
+		err := o.encode(videoSource)
+		if err != nil {
+			logr.FromContextOrDiscard(ctx).Error(err, "encode mjpeg failed")
+		}
+
+		o.mu.Lock()
+		o.closed = true
+		for v := range o.viewers {
+			close(v.frames)
+			delete(o.viewers, v)
+		}
+		o.mu.Unlock()
+
+		o.Shutdown(nil)
+	}()
+}
+
+func (o *Observer) encode(videoSource livestream.VideoSource) error {
+	r, err := videoSource.NewReader()
+	if err != nil {
+		return err
+	}
+
+	interval := time.Second / time.Duration(o.options.FPS)
+	var lastAt time.Time
+
+	for {
+		select {
+		case <-o.stop:
+			return nil
+		default:
+		}
+
+		img, release, err := r.Read()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if now.Sub(lastAt) < interval {
+			release()
+			continue
+		}
+		lastAt = now
+
+		b := bytes.NewBuffer(nil)
+		err = jpeg.Encode(b, rendition.Scale(img, o.options.Width, o.options.Height), &jpeg.Options{Quality: o.options.Quality})
+		release()
+		if err != nil {
+			return err
+		}
+
+		o.broadcast(Frame{At: now, Data: b.Bytes()})
+	}
+}
+
+func (o *Observer) broadcast(frame Frame) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.last = &frame
+
+	for v := range o.viewers {
+		v.send(frame)
+	}
+}
+
+// Viewer receives latest frames, older frame dropped when viewer too slow
+type Viewer struct {
+	o      *Observer
+	frames chan Frame
+}
+
+// Frames closed when stream broken
+func (v *Viewer) Frames() <-chan Frame {
+	return v.frames
+}
+
+func (v *Viewer) Close() error {
+	v.o.leave(v)
+	return nil
+}
+
+// send called with lock of observer
+func (v *Viewer) send(frame Frame) {
+	select {
+	case v.frames <- frame:
+		return
+	default:
+	}
+
+	// replace the stale frame
+	select {
+	case <-v.frames:
+	default:
+	}
+
+	select {
+	case v.frames <- frame:
+	default:
+	}
+}
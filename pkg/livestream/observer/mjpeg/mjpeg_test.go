@@ -0,0 +1,161 @@
+package mjpeg
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/livestream"
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/io/video"
+
+	. "github.com/octohelm/x/testing"
+)
+
+// fakeVideoSource reads 64x48 frames every millisecond
+type fakeVideoSource struct {
+	reads int64
+}
+
+func (s *fakeVideoSource) ID() string {
+	return "fake"
+}
+
+func (s *fakeVideoSource) NewReader() (video.Reader, error) {
+	return video.ReaderFunc(func() (image.Image, func(), error) {
+		time.Sleep(time.Millisecond)
+		atomic.AddInt64(&s.reads, 1)
+		return image.NewRGBA(image.Rect(0, 0, 64, 48)), func() {}, nil
+	}), nil
+}
+
+func (s *fakeVideoSource) NewEncodedReader(preset livestream.EncodingPreset, opts ...livestream.SubscribeOptFunc) (mediadevices.EncodedReadCloser, error) {
+	return nil, io.EOF
+}
+
+func (s *fakeVideoSource) Status() livestream.Status {
+	return livestream.Status{Active: true}
+}
+
+func nextFrame(t *testing.T, v *Viewer) Frame {
+	select {
+	case frame, ok := <-v.Frames():
+		Expect(t, ok, Be(true))
+		return frame
+	case <-time.After(time.Second):
+		t.Fatal("no frame received")
+	}
+	return Frame{}
+}
+
+func TestObserver(t *testing.T) {
+	t.Run("Shared by options", func(t *testing.T) {
+		withFPS := func(fps int) OptFunc {
+			return func(o *Options) {
+				o.FPS = fps
+			}
+		}
+
+		Expect(t, New(withFPS(10)).UniqueKey(), Equal(New(withFPS(10)).UniqueKey()))
+		Expect(t, New(withFPS(10)).UniqueKey() == New(withFPS(5)).UniqueKey(), Be(false))
+
+		o := New(withFPS(100), func(o *Options) {
+			o.Width = 32
+		})
+
+		v1, err := o.Watch()
+		Expect(t, err, Be[error](nil))
+		v2, err := o.Watch()
+		Expect(t, err, Be[error](nil))
+
+		o.OnVideoSource(context.Background(), &fakeVideoSource{})
+
+		f1 := nextFrame(t, v1)
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(f1.Data))
+		Expect(t, err, Be[error](nil))
+		Expect(t, cfg.Width, Be(32))
+
+		// frames encoded once, received by all viewers
+		_, err = jpeg.DecodeConfig(bytes.NewReader(nextFrame(t, v2).Data))
+		Expect(t, err, Be[error](nil))
+
+		_ = v1.Close()
+		_ = v2.Close()
+	})
+
+	t.Run("Drop frames for slow viewer", func(t *testing.T) {
+		o := New()
+
+		slow, err := o.Watch()
+		Expect(t, err, Be[error](nil))
+		fast, err := o.Watch()
+		Expect(t, err, Be[error](nil))
+
+		for i := byte(0); i < 5; i++ {
+			o.broadcast(Frame{Data: []byte{i}})
+			Expect(t, nextFrame(t, fast).Data, Equal([]byte{i}))
+		}
+
+		// only latest kept
+		Expect(t, nextFrame(t, slow).Data, Equal([]byte{4}))
+		select {
+		case <-slow.Frames():
+			t.Fatal("stale frame should be dropped")
+		default:
+		}
+
+		t.Run("Latest frame first for new viewer", func(t *testing.T) {
+			v, err := o.Watch()
+			Expect(t, err, Be[error](nil))
+			Expect(t, nextFrame(t, v).Data, Equal([]byte{4}))
+			_ = v.Close()
+		})
+
+		_ = slow.Close()
+		_ = fast.Close()
+	})
+
+	t.Run("Closed after last viewer left", func(t *testing.T) {
+		o := New(func(o *Options) {
+			o.FPS = 100
+		})
+
+		v1, err := o.Watch()
+		Expect(t, err, Be[error](nil))
+		v2, err := o.Watch()
+		Expect(t, err, Be[error](nil))
+
+		source := &fakeVideoSource{}
+		o.OnVideoSource(context.Background(), source)
+
+		nextFrame(t, v1)
+
+		_ = v1.Close()
+		// closed twice is ok
+		_ = v1.Close()
+
+		nextFrame(t, v2)
+
+		_ = v2.Close()
+
+		select {
+		case err := <-o.Done():
+			Expect(t, err, Be[error](nil))
+		case <-time.After(time.Second):
+			t.Fatal("observer should be closed")
+		}
+
+		_, err = o.Watch()
+		Expect(t, err, Be[error](ErrObserverClosed))
+
+		// source not read anymore
+		reads := atomic.LoadInt64(&source.reads)
+		time.Sleep(20 * time.Millisecond)
+		Expect(t, atomic.LoadInt64(&source.reads), Be(reads))
+	})
+}
@@ -0,0 +1,75 @@
+package rtsp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/innoai-tech/media-toolkit/pkg/livestream"
+	"github.com/innoai-tech/media-toolkit/pkg/rtspserver"
+	"github.com/innoai-tech/media-toolkit/pkg/util/syncutil"
+	"github.com/pion/mediadevices"
+)
+
+// New creates observer for one rtsp session, reads frames encoded in preset.
+func New(preset livestream.EncodingPreset, opts ...livestream.SubscribeOptFunc) *Observer {
+	return &Observer{
+		CloseNotifier: syncutil.NewCloseNotifier(),
+		preset:        preset,
+		opts:          opts,
+		ready:         make(chan struct{}),
+	}
+}
+
+type Observer struct {
+	syncutil.CloseNotifier
+	preset livestream.EncodingPreset
+	opts   []livestream.SubscribeOptFunc
+
+	ready     chan struct{}
+	reader    mediadevices.EncodedReadCloser
+	err       error
+	closeOnce sync.Once
+}
+
+var _ livestream.StreamObserver = &Observer{}
+var _ rtspserver.Stream = &Observer{}
+
+func (o *Observer) Name() string {
+	return "RTSP"
+}
+
+func (o *Observer) OnVideoSource(ctx context.Context, videoSource livestream.VideoSource) {
+	o.reader, o.err = videoSource.NewEncodedReader(o.preset, o.opts...)
+	close(o.ready)
+}
+
+func (o *Observer) Read() (rtspserver.Frame, error) {
+	<-o.ready
+
+	if o.err != nil {
+		return rtspserver.Frame{}, o.err
+	}
+
+	buf, release, err := o.reader.Read()
+	if err != nil {
+		return rtspserver.Frame{}, err
+	}
+	defer release()
+
+	return rtspserver.Frame{
+		Data:    append([]byte(nil), buf.Data...),
+		Samples: buf.Samples,
+	}, nil
+}
+
+// Close called when session end, or removed from stream
+func (o *Observer) Close() error {
+	o.closeOnce.Do(func() {
+		<-o.ready
+
+		if o.reader != nil {
+			_ = o.reader.Close()
+		}
+	})
+	return o.CloseNotifier.Close()
+}
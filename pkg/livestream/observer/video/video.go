@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/innoai-tech/media-toolkit/pkg/format"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/mime"
+	"io"
 	"os"
 	"time"
 
@@ -15,6 +16,8 @@ import (
 
 type Options struct {
 	MaxDuration time.Duration
+	// Labels of user, committed with video and embedded in it
+	Labels map[string][]string
 }
 
 type OptFunc func(o *Options)
@@ -66,7 +69,12 @@ func (o *videoObserver) OnVideoSource(ctx context.Context, videoSource livestrea
 		return
 	}
 
-	encodedReader, err := videoSource.NewEncodedReader(livestream.Preset1080P)
+	encodedReader, err := videoSource.NewEncodedReader(
+		livestream.Preset1080P,
+		livestream.WithSubscriberName(o.Name()),
+		// larger queue, recording should not drop frames on disk stall
+		livestream.WithQueueSize(300),
+	)
 	if err != nil {
 		return
 	}
@@ -89,12 +97,29 @@ func (o *videoObserver) OnVideoSource(ctx context.Context, videoSource livestrea
 				return
 			}
 
-			err := format.CommitTo(logr.NewContext(context.Background(), l), f, o.ingester, format.Info{
+			finalInfo := format.Info{
 				MediaType: mime.MediaTypeVideoMP4,
 				ID:        info.ID,
 				StartedAt: info.StartedAt,
 				At:        info.At,
-			})
+				Labels:    o.options.Labels,
+			}
+
+			var content io.Reader = f
+
+			// commit as recorded when finalize failed
+			finalized, err := format.Finalize(f, finalInfo)
+			if err != nil {
+				l.Error(err, "finalize video failed")
+			} else {
+				defer func() {
+					_ = finalized.Close()
+					_ = os.Remove(finalized.Name())
+				}()
+				content = finalized
+			}
+
+			_, err = format.CommitTo(logr.NewContext(context.Background(), l), content, o.ingester, finalInfo)
 
 			if err != nil {
 				l.Error(err, "commit video failed")
@@ -14,15 +14,18 @@ import (
 	"github.com/innoai-tech/media-toolkit/pkg/util/syncutil"
 )
 
-func New(w io.Writer) livestream.StreamObserver {
+// New creates observer writes fragmented mp4 into w, w closed when stream broken or subscriber too slow.
+func New(w io.Writer, opts ...livestream.SubscribeOptFunc) livestream.StreamObserver {
 	return &wsmp4f{
 		w:             w,
+		opts:          opts,
 		CloseNotifier: syncutil.NewCloseNotifier(),
 	}
 }
 
 type wsmp4f struct {
-	w io.Writer
+	w    io.Writer
+	opts []livestream.SubscribeOptFunc
 	syncutil.CloseNotifier
 	once syncutil.Once
 }
@@ -40,13 +43,19 @@ func (w *wsmp4f) Close() error {
 
 func (w *wsmp4f) OnVideoSource(ctx context.Context, videoSource livestream.VideoSource) {
 	_ = w.once.Do(func() error {
-		encodedReader, err := videoSource.NewEncodedReader(livestream.Preset1080P)
+		encodedReader, err := videoSource.NewEncodedReader(livestream.Preset1080P, w.opts...)
 		if err != nil {
 			return err
 		}
 
 		go func() {
 			defer encodedReader.Close()
+			// close writer, to disconnect client when reader broken
+			defer func() {
+				if c, ok := w.w.(io.Closer); ok {
+					_ = c.Close()
+				}
+			}()
 
 			p := format.Packetizer{}
 			muxer := mp4f.NewMuxer(nil)
@@ -62,6 +71,7 @@ func (w *wsmp4f) OnVideoSource(ctx context.Context, videoSource livestream.Video
 				default:
 					buf, _, err := encodedReader.Read()
 					if err != nil {
+						l.Error(err, "read frame failed")
 						return
 					}
 
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/go-courier/httptransport"
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	"github.com/julienschmidt/httprouter"
+)
+
+// json body larger than this will not be recorded
+const maxAuditBodySize = 16 * 1024
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// operationID of route is the type name of last operator, like DeleteBlob
+func operationID(route *httptransport.HttpRouteMeta) string {
+	ops := route.OperatorFactoryWithRouteMetas
+	return ops[len(ops)-1].Type.Name()
+}
+
+// auditedByStore is implemented by operators, which operations audited by store already, like HoldBlob
+type auditedByStore interface {
+	AuditedByStore()
+}
+
+func isAuditedByStore(route *httptransport.HttpRouteMeta) bool {
+	ops := route.OperatorFactoryWithRouteMetas
+	return reflect.PtrTo(ops[len(ops)-1].Type).Implements(reflect.TypeOf((*auditedByStore)(nil)).Elem())
+}
+
+// audited records each call of handler to audit log of store, with actor, path params, query and small json body.
+func (ls *LiveStreamServer) audited(action string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		e := storage.AuditEntry{
+			Time:   types.Now(),
+			Actor:  storage.ActorFromContext(req.Context()),
+			Action: action,
+			Params: map[string]string{},
+		}
+
+		for _, p := range httprouter.ParamsFromContext(req.Context()) {
+			if p.Key == "ref" {
+				ref := blob.RefString{}
+				if err := ref.UnmarshalText([]byte(p.Value)); err == nil {
+					e.Ref = &ref
+					continue
+				}
+			}
+			e.Params[p.Key] = p.Value
+		}
+
+		for k, values := range req.URL.Query() {
+			if len(values) > 0 {
+				e.Params[k] = values[0]
+			}
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "application/json" && req.ContentLength > 0 && req.ContentLength <= maxAuditBodySize {
+			data, err := io.ReadAll(req.Body)
+			_ = req.Body.Close()
+			if err == nil {
+				e.Params["body"] = string(data)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(data))
+		}
+
+		srw := &statusResponseWriter{ResponseWriter: rw, statusCode: http.StatusOK}
+
+		handler(srw, req)
+
+		e.Params["status"] = strconv.Itoa(srw.statusCode)
+
+		if err := ls.store.Audit(req.Context(), e); err != nil {
+			logr.FromContextOrDiscard(req.Context()).Error(err, "audit failed", "action", action)
+		}
+	}
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (w *statusResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush keeps streaming responses working
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
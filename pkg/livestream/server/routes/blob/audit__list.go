@@ -0,0 +1,27 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&ListAudit{}))
+}
+
+// ListAudit lists audit entries of mutating operations, ordered by time
+type ListAudit struct {
+	httpx.MethodGet `path:"/audit"`
+	TimeRange       types.DateTimeRange `name:"time" in:"query"`
+}
+
+func (req *ListAudit) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+
+	return s.QueryAudit(ctx, blob.TimeRange{From: req.TimeRange.From, Through: req.TimeRange.To})
+}
@@ -2,6 +2,18 @@ package blob
 
 import (
 	"github.com/go-courier/courier"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/prometheus/prometheus/model/labels"
 )
 
 var BlobRouter = courier.NewRouter()
+
+// withoutDerived excludes derived blobs like thumbnails, unless matchers select them explicitly
+func withoutDerived(matchers []*labels.Matcher) []*labels.Matcher {
+	for _, m := range matchers {
+		if m.Name == blob.LabelDerivedFrom || m.Name == blob.LabelRendition {
+			return matchers
+		}
+	}
+	return append(matchers, labels.MustNewMatcher(labels.MatchEqual, blob.LabelDerivedFrom, ""))
+}
@@ -0,0 +1,90 @@
+package blob
+
+import (
+	"context"
+	"mime"
+	"os"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/httputil"
+	"github.com/innoai-tech/media-toolkit/pkg/rendition"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&GetBlobClip{}))
+}
+
+// GetBlobClip returns clip of video between start and end, remuxed without re-encode,
+// which starts at the nearest keyframe before start.
+type GetBlobClip struct {
+	httpx.MethodGet `path:"/blobs/:ref/clip"`
+	Ref             blob.RefString `name:"ref" in:"path"`
+	// offset from start of video, in seconds like 12.5, or duration like 1m30s
+	Start string `name:"start" in:"query"`
+	End   string `name:"end" in:"query"`
+}
+
+func (req *GetBlobClip) Output(ctx context.Context) (any, error) {
+	c, err := clipOf(req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	s := storage.StoreFromContext(ctx)
+
+	source, err := s.Info(ctx, req.Ref.Ref())
+	if err != nil {
+		return nil, err
+	}
+
+	// clip streamed from temp file, which could be large
+	f, mediaType, err := c.RenderFile(ctx, s, source)
+	if err != nil {
+		return nil, err
+	}
+
+	clip := &tempFile{File: f}
+
+	st, err := f.Stat()
+	if err != nil {
+		_ = clip.Close()
+		return nil, err
+	}
+
+	return &blobContentServer{
+		closer: clip,
+		content: &httputil.Content{
+			ReaderAt:    f,
+			Size:        st.Size(),
+			ContentType: mediaType,
+			Disposition: mime.FormatMediaType("inline", map[string]string{"filename": filenameOf(source, mediaType)}),
+			ChunkLimit:  httputil.ChunkLimitFromContext(ctx),
+		},
+	}, nil
+}
+
+// tempFile removed when closed
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	_ = os.Remove(f.Name())
+	return err
+}
+
+func clipOf(start string, end string) (*rendition.Clip, error) {
+	s, err := rendition.ParseOffset(start)
+	if err != nil {
+		return nil, err
+	}
+	e, err := rendition.ParseOffset(end)
+	if err != nil {
+		return nil, err
+	}
+	return &rendition.Clip{Start: s, End: e}, nil
+}
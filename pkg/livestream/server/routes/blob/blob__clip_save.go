@@ -0,0 +1,33 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/rendition"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&SaveBlobClip{}))
+}
+
+// SaveBlobClip saves clip of video as new blob labeled with _derived_from,
+// which From and Through are the actual window of clip.
+type SaveBlobClip struct {
+	httpx.MethodPost `path:"/blobs/:ref/clip"`
+	Ref              blob.RefString `name:"ref" in:"path"`
+	// offset from start of video, in seconds like 12.5, or duration like 1m30s
+	Start string `name:"start" in:"query"`
+	End   string `name:"end" in:"query"`
+}
+
+func (req *SaveBlobClip) Output(ctx context.Context) (any, error) {
+	c, err := clipOf(req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+	return rendition.Derive(ctx, storage.StoreFromContext(ctx), req.Ref.Ref(), c)
+}
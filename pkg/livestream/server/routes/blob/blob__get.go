@@ -2,40 +2,58 @@ package blob
 
 import (
 	"context"
-	"io"
+	"mime"
 	"net/http"
-	"strconv"
+	"net/url"
+	"strings"
 
 	"github.com/go-courier/courier"
 	"github.com/go-courier/httptransport/httpx"
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
-	"github.com/innoai-tech/media-toolkit/pkg/filesize"
 	"github.com/innoai-tech/media-toolkit/pkg/httputil"
 	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	mtkmime "github.com/innoai-tech/media-toolkit/pkg/storage/mime"
 )
 
 func init() {
 	BlobRouter.Register(courier.NewRouter(&GetBlob{}))
 }
 
+// GetBlob downloads content of blob,
+// with conditional requests by ETag and Last-Modified, and range requests, multiple ranges as multipart/byteranges.
 type GetBlob struct {
 	httpx.MethodGet `path:"/blobs/:ref"`
 	Ref             blob.RefString `name:"ref" in:"path"`
-	Range           string         `name:"Range,omitempty" in:"header"`
 }
 
 func (req *GetBlob) Output(ctx context.Context) (any, error) {
+	return blobContent(ctx, req.Ref.Ref())
+}
+
+func blobContent(ctx context.Context, ref blob.Ref) (any, error) {
 	s := storage.StoreFromContext(ctx)
-	info, err := s.Info(ctx, req.Ref.Ref())
+	info, err := s.Info(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
-	r, err := s.ReaderAt(ctx, info.Ref)
+
+	presigned, err := s.PresignedURL(ctx, info.Ref)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO should redirect when object enabled
+	if presigned != "" {
+		u, err := url.Parse(presigned)
+		if err != nil {
+			return nil, err
+		}
+		return httpx.RedirectWithStatusFound(u), nil
+	}
+
+	r, err := s.ReaderAt(ctx, info.Ref)
+	if err != nil {
+		return nil, err
+	}
 
 	mediaType := "application/octet-stream"
 	if info.Labels != nil {
@@ -44,34 +62,39 @@ func (req *GetBlob) Output(ctx context.Context) (any, error) {
 		}
 	}
 
-	if req.Range != "" {
-		ranges, err := httputil.ParseRange(req.Range, r.Size())
-		if err != nil {
-			return nil, err
-		}
-
-		rng := ranges[0]
-
-		if rng.Length > int64(50*filesize.KiB) {
-			rng.Length = int64(50 * filesize.KiB)
-		}
-
-		return httpx.Compose(
-			httpx.WithStatusCode(http.StatusPartialContent),
-			httpx.WithContentType(mediaType),
-			httpx.WithMetadata(courier.Metadata{
+	return &blobContentServer{
+		closer: r,
+		content: &httputil.Content{
+			ReaderAt:     r,
+			Size:         r.Size(),
+			ContentType:  mediaType,
+			ETag:         `"` + info.Hex + `"`,
+			LastModified: info.Through.Time(),
+			Disposition:  mime.FormatMediaType("inline", map[string]string{"filename": filenameOf(info, mediaType)}),
+			ChunkLimit:   httputil.ChunkLimitFromContext(ctx),
+			Header: http.Header{
 				"Cache-Control": {"max-age=31536000"},
-				"Content-Range": {rng.ContentRange(r.Size())},
-				"Accept-Ranges": {strconv.FormatInt(r.Size(), 10)},
-			}),
-		)(io.NewSectionReader(r, rng.Start, rng.Length)), nil
+			},
+		},
+	}, nil
+}
+
+// filenameOf returns filename like <device_id>-<from><ext>
+func filenameOf(info *blob.Info, mediaType string) string {
+	name := info.Hex[0:12]
+	if deviceIDs := info.Labels["_device_id"]; len(deviceIDs) > 0 && deviceIDs[0] != "" {
+		name = strings.NewReplacer("/", "_", "\\", "_", "\"", "_").Replace(deviceIDs[0])
 	}
+	return name + "-" + info.From.Time().UTC().Format("20060102T150405Z") + mtkmime.ExtensionOf(mediaType)
+}
+
+type blobContentServer struct {
+	closer  interface{ Close() error }
+	content *httputil.Content
+}
 
-	return httpx.Compose(
-		httpx.WithContentType(mediaType),
-		httpx.WithMetadata(courier.Metadata{
-			"Cache-Control": {"max-age=31536000"},
-			"Accept-Ranges": {strconv.FormatInt(r.Size(), 10)},
-		}),
-	)(io.NewSectionReader(r, 0, r.Size())), nil
+func (s *blobContentServer) Upgrade(rw http.ResponseWriter, req *http.Request) error {
+	defer s.closer.Close()
+	httputil.ServeContent(rw, req, s.content)
+	return nil
 }
@@ -0,0 +1,23 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&HeadBlob{}))
+}
+
+// HeadBlob returns headers of GetBlob only
+type HeadBlob struct {
+	httpx.MethodHead `path:"/blobs/:ref"`
+	Ref              blob.RefString `name:"ref" in:"path"`
+}
+
+func (req *HeadBlob) Output(ctx context.Context) (any, error) {
+	return blobContent(ctx, req.Ref.Ref())
+}
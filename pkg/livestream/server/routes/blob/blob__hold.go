@@ -0,0 +1,48 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&HoldBlob{}))
+	BlobRouter.Register(courier.NewRouter(&ReleaseBlob{}))
+}
+
+// HoldBlob puts legal hold on blob, which could not be deleted until released, admin only
+type HoldBlob struct {
+	httpx.MethodPut `path:"/blobs/:ref/hold"`
+	Ref             blob.RefString `name:"ref" in:"path"`
+	Data            HoldBlobData   `in:"body"`
+}
+
+type HoldBlobData struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuditedByStore as hold audited by store
+func (req *HoldBlob) AuditedByStore() {}
+
+func (req *HoldBlob) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+	return nil, s.Hold(ctx, req.Ref.Ref(), req.Data.Reason)
+}
+
+// ReleaseBlob releases legal hold of blob, admin only
+type ReleaseBlob struct {
+	httpx.MethodDelete `path:"/blobs/:ref/hold"`
+	Ref                blob.RefString `name:"ref" in:"path"`
+}
+
+// AuditedByStore as release audited by store
+func (req *ReleaseBlob) AuditedByStore() {}
+
+func (req *ReleaseBlob) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+	return nil, s.Release(ctx, req.Ref.Ref())
+}
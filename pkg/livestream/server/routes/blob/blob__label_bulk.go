@@ -0,0 +1,71 @@
+package blob
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrBulkTargetRequired = errors.New("refs or time range is required")
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&BulkLabelBlob{}))
+}
+
+// BulkLabelBlob put and delete labels of blobs, which are picked by refs or by filter in time range
+type BulkLabelBlob struct {
+	httpx.MethodPatch `path:"/blobs"`
+	Data              BulkLabelBlobData `in:"body"`
+}
+
+type BulkLabelBlobData struct {
+	Refs      []blob.RefString     `json:"refs,omitempty"`
+	TimeRange *types.DateTimeRange `json:"time,omitempty"`
+	Filter    *types.Filter        `json:"filter,omitempty"`
+	storage.LabelChange
+}
+
+func (req *BulkLabelBlob) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+
+	refs := make([]blob.Ref, 0, len(req.Data.Refs))
+	for _, ref := range req.Data.Refs {
+		refs = append(refs, ref.Ref())
+	}
+
+	if len(refs) == 0 {
+		if req.Data.TimeRange == nil || req.Data.TimeRange.IsZero() {
+			return nil, statuserr.Wrap(http.StatusBadRequest, ErrBulkTargetRequired, "")
+		}
+
+		filter := types.Filter{}
+		if req.Data.Filter != nil {
+			filter = *req.Data.Filter
+		}
+
+		blobs, err := s.Query(
+			ctx,
+			blob.TimeRange{From: req.Data.TimeRange.From, Through: req.Data.TimeRange.To},
+			blob.DefaultUser,
+			filter.Matchers...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range blobs {
+			refs = append(refs, b.Ref)
+		}
+	}
+
+	return s.BulkLabel(ctx, refs, req.Data.LabelChange)
+}
@@ -27,6 +27,6 @@ func (req *ListBlob) Output(ctx context.Context) (any, error) {
 		ctx,
 		blob.TimeRange{From: req.TimeRange.From, Through: req.TimeRange.To},
 		blob.DefaultUser,
-		req.Filter.Matchers...,
+		withoutDerived(req.Filter.Matchers)...,
 	)
 }
@@ -0,0 +1,52 @@
+package blob
+
+import (
+	"context"
+	"io"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/rendition"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&GetBlobThumbnail{}))
+}
+
+// GetBlobThumbnail returns JPEG thumbnail of image, or poster frame of video,
+// which fits in w x h, and cached as derived blob.
+type GetBlobThumbnail struct {
+	httpx.MethodGet `path:"/blobs/:ref/thumbnail"`
+	Ref             blob.RefString `name:"ref" in:"path"`
+	Width           int            `name:"w,omitempty" in:"query"`
+	Height          int            `name:"h,omitempty" in:"query"`
+}
+
+func (req *GetBlobThumbnail) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+
+	derived, err := rendition.Derive(ctx, s, req.Ref.Ref(), &rendition.Thumbnail{Width: req.Width, Height: req.Height})
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.ReaderAt(ctx, derived.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpx.Compose(
+		httpx.WithContentType(derived.Labels["_media_type"][0]),
+		httpx.WithMetadata(courier.Metadata{
+			"Cache-Control": {"max-age=31536000"},
+		}),
+	)(&readCloser{Reader: io.NewSectionReader(r, 0, r.Size()), Closer: r}), nil
+}
+
+// readCloser closes content reader after response written
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,88 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/httputil"
+	"github.com/innoai-tech/media-toolkit/pkg/rendition"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/opencontainers/go-digest"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&GetBlobTransformed{}))
+}
+
+// GetBlobTransformed returns image transformed on the fly,
+// cropped, rotated, resized, then encoded in format.
+// Not stored, but cacheable by ETag of source digest and params.
+type GetBlobTransformed struct {
+	httpx.MethodGet `path:"/blobs/:ref/transform"`
+	Ref             blob.RefString `name:"ref" in:"path"`
+	// crop rectangle x,y,w,h in pixels of source
+	Crop string `name:"crop,omitempty" in:"query"`
+	// rotate clockwise, 0, 90, 180 or 270
+	Rotate int `name:"rotate,omitempty" in:"query"`
+	Width  int `name:"w,omitempty" in:"query"`
+	Height int `name:"h,omitempty" in:"query"`
+	// jpeg (default) or png
+	Format string `name:"format,omitempty" in:"query"`
+	// quality of jpeg, 1-100
+	Quality     int    `name:"quality,omitempty" in:"query"`
+	IfNoneMatch string `name:"If-None-Match,omitempty" in:"header"`
+}
+
+func (req *GetBlobTransformed) Output(ctx context.Context) (any, error) {
+	t, err := rendition.NewTransform(req.Crop, req.Rotate, req.Width, req.Height, req.Format, req.Quality)
+	if err != nil {
+		return nil, err
+	}
+
+	s := storage.StoreFromContext(ctx)
+
+	info, err := s.Info(ctx, req.Ref.Ref())
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.Transformable(*info) {
+		return nil, statuserr.Wrap(http.StatusUnsupportedMediaType, rendition.ErrUnsupportedMediaType, "only image could be transformed")
+	}
+
+	etag := `"` + digest.FromString(info.Digest().String()+"?"+t.String()).Encoded() + `"`
+
+	meta := courier.Metadata{
+		"ETag":          {etag},
+		"Cache-Control": {"max-age=31536000"},
+	}
+
+	if httputil.MatchETag(req.IfNoneMatch, etag, true) {
+		return httpx.Compose(
+			httpx.WithStatusCode(http.StatusNotModified),
+			httpx.WithMetadata(meta),
+		)(bytes.NewReader(nil)), nil
+	}
+
+	r, err := s.ReaderAt(ctx, info.Ref)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	buf := bytes.NewBuffer(nil)
+	if err := t.Transform(ctx, io.NewSectionReader(r, 0, r.Size()), buf); err != nil {
+		return nil, err
+	}
+
+	return httpx.Compose(
+		httpx.WithContentType(t.MediaType()),
+		httpx.WithMetadata(meta),
+	)(buf), nil
+}
@@ -0,0 +1,35 @@
+package blob
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&RunCheck{}))
+}
+
+// RunCheck checks consistency between content and label index, repairs when required, admin only
+type RunCheck struct {
+	httpx.MethodPost `path:"/check"`
+	// TimeRange of blobs to check, all when empty
+	TimeRange types.DateTimeRange `name:"time,omitempty" in:"query"`
+	Repair    bool                `name:"repair,omitempty" in:"query"`
+}
+
+func (req *RunCheck) Output(ctx context.Context) (any, error) {
+	if !storage.ActorFromContext(ctx).Admin {
+		return nil, statuserr.Wrap(http.StatusForbidden, storage.ErrAdminRequired, "only admin could check store")
+	}
+
+	s := storage.StoreFromContext(ctx)
+
+	return s.Check(ctx, blob.TimeRange{From: req.TimeRange.From, Through: req.TimeRange.To}, req.Repair)
+}
@@ -2,9 +2,14 @@ package blob
 
 import (
 	"context"
+	"net/http"
+
 	"github.com/go-courier/courier"
 	"github.com/go-courier/httptransport/httpx"
+	"github.com/go-logr/logr"
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/rendition"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
 	"github.com/innoai-tech/media-toolkit/pkg/storage"
 	"github.com/innoai-tech/media-toolkit/pkg/types"
 )
@@ -13,39 +18,86 @@ func init() {
 	BlobRouter.Register(courier.NewRouter(&ExportDataset{}))
 }
 
+// HeaderDatasetDigest is the trailer of digest of whole archive
+const HeaderDatasetDigest = "X-Dataset-Digest"
+
+// ExportDataset streams blobs as tar.gz, digest of archive sent in trailer X-Dataset-Digest
 type ExportDataset struct {
 	httpx.MethodGet `path:"/datasets"`
 	TimeRange       types.DateTimeRange `name:"time" in:"query"`
 	Filter          types.Filter        `name:"filter,omitempty" in:"query"`
+	// blobs (default), imagefolder or coco
+	Layout storage.ExportLayout `name:"layout,omitempty" in:"query"`
+	// label to group images by, required when layout is imagefolder or coco
+	GroupBy string `name:"groupBy,omitempty" in:"query"`
+	// transform images in query string, like w=320&format=png, see GetBlobTransformed
+	Transform string `name:"transform,omitempty" in:"query"`
 }
 
 func (req *ExportDataset) Output(ctx context.Context) (any, error) {
+	if err := storage.CheckExportLayout(req.Layout, req.GroupBy); err != nil {
+		return nil, statuserr.Wrap(http.StatusBadRequest, err, "")
+	}
+
+	timeRange := blob.TimeRange{From: req.TimeRange.From, Through: req.TimeRange.To}
+
+	opts := []storage.ExportOpt{
+		storage.WithExportLayout(req.Layout, req.GroupBy),
+		storage.WithExportQuery(timeRange, req.Filter),
+		storage.WithSigningKey(storage.SigningKeyFromContext(ctx)),
+	}
+
+	if req.Transform != "" {
+		t, err := rendition.ParseTransform(req.Transform)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, storage.WithContentTransformer(t))
+	}
+
 	s := storage.StoreFromContext(ctx)
+
 	blobs, err := s.Query(
 		ctx,
-		blob.TimeRange{From: req.TimeRange.From, Through: req.TimeRange.To},
+		timeRange,
 		blob.DefaultUser,
-		req.Filter.Matchers...,
+		withoutDerived(req.Filter.Matchers)...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	f, err := s.TempFile(ctx)
-	if err != nil {
-		return nil, err
-	}
+	return &datasetExporter{
+		store: s,
+		blobs: blobs,
+		opts:  opts,
+	}, nil
+}
 
-	if _, err := storage.ExportDataset(ctx, s, f, blobs); err != nil {
-		return nil, err
+type datasetExporter struct {
+	store storage.Store
+	blobs []blob.Info
+	opts  []storage.ExportOpt
+}
+
+func (e *datasetExporter) Upgrade(rw http.ResponseWriter, req *http.Request) error {
+	ctx := req.Context()
+
+	header := rw.Header()
+	header.Set("Content-Type", "application/tar+gzip")
+	header.Set("Content-Disposition", `attachment; filename="dataset.tar.gz"`)
+	header.Set("Trailer", HeaderDatasetDigest)
+
+	rw.WriteHeader(http.StatusOK)
+
+	dgst, err := storage.ExportDataset(ctx, e.store, rw, e.blobs, e.opts...)
+	if err != nil {
+		// status already sent, abort to make client know archive broken.
+		logr.FromContextOrDiscard(ctx).Error(err, "export dataset failed")
+		panic(http.ErrAbortHandler)
 	}
 
-	_, _ = f.Seek(0, 0)
+	header.Set(HeaderDatasetDigest, dgst.String())
 
-	return httpx.Compose(
-		httpx.WithContentType("application/tar+gzip"),
-		httpx.WithMetadata(courier.Metadata{
-			"Content-Disposition": {`attachment; filename="dataset.tar.gz"`},
-		}),
-	)(f), nil
+	return nil
 }
@@ -0,0 +1,30 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&ListLabel{}))
+}
+
+type ListLabel struct {
+	httpx.MethodGet `path:"/labels"`
+	TimeRange       types.DateTimeRange `name:"time" in:"query"`
+}
+
+func (req *ListLabel) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+
+	return s.LabelNames(
+		ctx,
+		blob.TimeRange{From: req.TimeRange.From, Through: req.TimeRange.To},
+		blob.DefaultUser,
+	)
+}
@@ -0,0 +1,34 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&ListLabelValue{}))
+}
+
+type ListLabelValue struct {
+	httpx.MethodGet `path:"/labels/:name/values"`
+	Name            string              `name:"name" in:"path"`
+	TimeRange       types.DateTimeRange `name:"time" in:"query"`
+	Filter          types.Filter        `name:"filter,omitempty" in:"query"`
+}
+
+func (req *ListLabelValue) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+
+	return s.LabelValues(
+		ctx,
+		blob.TimeRange{From: req.TimeRange.From, Through: req.TimeRange.To},
+		blob.DefaultUser,
+		req.Name,
+		req.Filter.Matchers...,
+	)
+}
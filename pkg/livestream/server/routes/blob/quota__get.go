@@ -0,0 +1,23 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&GetQuota{}))
+}
+
+// GetQuota returns quota and used bytes of store and devices
+type GetQuota struct {
+	httpx.MethodGet `path:"/quota"`
+}
+
+func (req *GetQuota) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+	return s.Quota(ctx)
+}
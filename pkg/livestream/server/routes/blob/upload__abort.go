@@ -0,0 +1,23 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&AbortUpload{}))
+}
+
+type AbortUpload struct {
+	httpx.MethodDelete `path:"/uploads/:id"`
+	ID                 string `name:"id" in:"path"`
+}
+
+func (req *AbortUpload) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+	return nil, s.AbortUpload(ctx, req.ID)
+}
@@ -0,0 +1,74 @@
+package blob
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	"github.com/opencontainers/go-digest"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&CommitUpload{}))
+}
+
+// CommitUpload verifies digest of uploaded content and commits it as blob
+type CommitUpload struct {
+	httpx.MethodPut `path:"/uploads/:id/commit"`
+	ID              string           `name:"id" in:"path"`
+	Data            CommitUploadData `in:"body"`
+}
+
+type CommitUploadData struct {
+	Digest    digest.Digest `json:"digest"`
+	MediaType string        `json:"mediaType,omitempty"`
+	DeviceID  string        `json:"deviceID,omitempty"`
+	From      *types.Time   `json:"from,omitempty"`
+	Through   *types.Time   `json:"through,omitempty"`
+	Labels    blob.Labels   `json:"labels,omitempty"`
+}
+
+func (req *CommitUpload) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+
+	u, err := s.GetUpload(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := blob.Labels{}
+
+	for name, values := range req.Data.Labels {
+		if strings.HasPrefix(name, "_") {
+			return nil, statuserr.Wrap(http.StatusForbidden, storage.ErrLabelImmutable, name)
+		}
+		labels[name] = values
+	}
+
+	if req.Data.MediaType != "" {
+		labels["_media_type"] = []string{req.Data.MediaType}
+	}
+	if req.Data.DeviceID != "" {
+		labels["_device_id"] = []string{req.Data.DeviceID}
+	}
+	labels["_size"] = []string{strconv.FormatInt(u.Offset, 10)}
+
+	opts := []blob.Opt{blob.WithLabels(labels)}
+
+	if req.Data.From != nil {
+		if req.Data.Through != nil {
+			opts = append(opts, blob.WithFromThough(*req.Data.From, *req.Data.Through))
+		} else {
+			opts = append(opts, blob.WithFromThough(*req.Data.From))
+		}
+	}
+
+	return s.CommitUpload(ctx, req.ID, req.Data.Digest, opts...)
+}
@@ -0,0 +1,35 @@
+package blob
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&CreateUpload{}))
+}
+
+// CreateUpload starts a resumable upload, total is optional
+type CreateUpload struct {
+	httpx.MethodPost `path:"/uploads"`
+	Data             CreateUploadData `in:"body"`
+}
+
+type CreateUploadData struct {
+	Total int64 `json:"total,omitempty"`
+}
+
+func (req *CreateUpload) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+
+	u, err := s.CreateUpload(ctx, req.Data.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpx.WithStatusCode(http.StatusCreated)(u), nil
+}
@@ -0,0 +1,30 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&WriteUpload{}))
+}
+
+// WriteUpload appends chunk in request body to upload, from Upload-Offset
+type WriteUpload struct {
+	httpx.MethodPatch `path:"/uploads/:id"`
+	ID                string `name:"id" in:"path"`
+	Offset            int64  `name:"Upload-Offset" in:"header"`
+}
+
+func (req *WriteUpload) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+
+	r := httptransport.HttpRequestFromContext(ctx)
+	defer r.Body.Close()
+
+	return s.WriteUpload(ctx, req.ID, req.Offset, r.Body)
+}
@@ -0,0 +1,24 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+)
+
+func init() {
+	BlobRouter.Register(courier.NewRouter(&GetUpload{}))
+}
+
+// GetUpload returns status of upload, offset is where to resume from
+type GetUpload struct {
+	httpx.MethodGet `path:"/uploads/:id"`
+	ID              string `name:"id" in:"path"`
+}
+
+func (req *GetUpload) Output(ctx context.Context) (any, error) {
+	s := storage.StoreFromContext(ctx)
+	return s.GetUpload(ctx, req.ID)
+}
@@ -0,0 +1,144 @@
+package livestream
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/livestream"
+	"github.com/innoai-tech/media-toolkit/pkg/livestream/observer/mjpeg"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/mime"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	LiveStreamRouter.Register(courier.NewRouter(&LiveStreamMJPEG{}))
+}
+
+// maxFPS of mjpeg stream
+const maxFPS = 30
+
+// LiveStreamMJPEG streams frames as multipart/x-mixed-replace jpeg,
+// frames encoded once for all viewers with same params.
+type LiveStreamMJPEG struct {
+	httpx.MethodGet `path:"/live-streams/:id/mjpeg"`
+	ID              string `name:"id" in:"path"`
+	// frames per second, 5 by default
+	FPS int `name:"fps,omitempty" in:"query"`
+	// box to fit in, size of source when 0
+	Width  int `name:"w,omitempty" in:"query"`
+	Height int `name:"h,omitempty" in:"query"`
+	// quality of jpeg, 1-100
+	Quality int `name:"quality,omitempty" in:"query"`
+}
+
+func (req *LiveStreamMJPEG) Output(ctx context.Context) (any, error) {
+	if req.FPS < 0 || req.FPS > maxFPS {
+		return nil, statuserr.Wrap(http.StatusBadRequest, errors.Errorf("invalid fps %d", req.FPS), fmt.Sprintf("fps should be 1-%d", maxFPS))
+	}
+	if req.Width < 0 || req.Height < 0 {
+		return nil, statuserr.Wrap(http.StatusBadRequest, errors.Errorf("invalid size %dx%d", req.Width, req.Height), "")
+	}
+	if req.Quality < 0 || req.Quality > 100 {
+		return nil, statuserr.Wrap(http.StatusBadRequest, errors.Errorf("invalid quality %d", req.Quality), "quality should be 1-100")
+	}
+
+	return &mjpegServer{
+		hub: livestream.StreamHubFromContext(ctx),
+		id:  req.ID,
+		opts: []mjpeg.OptFunc{
+			func(o *mjpeg.Options) {
+				if req.FPS > 0 {
+					o.FPS = req.FPS
+				}
+				if req.Quality > 0 {
+					o.Quality = req.Quality
+				}
+				o.Width = req.Width
+				o.Height = req.Height
+			},
+		},
+	}, nil
+}
+
+type mjpegServer struct {
+	hub  *livestream.StreamHub
+	id   string
+	opts []mjpeg.OptFunc
+}
+
+func (s *mjpegServer) Upgrade(rw http.ResponseWriter, req *http.Request) error {
+	ctx := req.Context()
+
+	v, err := s.watch(ctx)
+	if err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "watch failed")
+		return err
+	}
+	defer v.Close()
+
+	mw := multipart.NewWriter(rw)
+
+	rw.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mw.Boundary())
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(http.StatusOK)
+
+	flusher, _ := rw.(http.Flusher)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case frame, ok := <-v.Frames():
+			if !ok {
+				return nil
+			}
+
+			part, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":   {mime.MediaTypeImageJPEG},
+				"Content-Length": {strconv.Itoa(len(frame.Data))},
+			})
+			if err != nil {
+				return nil
+			}
+			if _, err := part.Write(frame.Data); err != nil {
+				return nil
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// watch subscribes shared observer, retries when the shared one closing
+func (s *mjpegServer) watch(ctx context.Context) (*mjpeg.Viewer, error) {
+	for {
+		sub, err := s.hub.Subscribe(ctx, s.id, mjpeg.New(s.opts...))
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := sub.(*mjpeg.Observer).Watch()
+		if err == nil {
+			return v, nil
+		}
+		if !errors.Is(err, mjpeg.ErrObserverClosed) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package livestream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/httputil"
+	"github.com/innoai-tech/media-toolkit/pkg/livestream"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/mime"
+)
+
+func init() {
+	LiveStreamRouter.Register(courier.NewRouter(&LiveStreamSnapshot{}))
+}
+
+// LiveStreamSnapshot returns the most recent frame of stream as jpeg, not stored.
+// Frame cached per stream, until older than max age configured.
+type LiveStreamSnapshot struct {
+	httpx.MethodGet `path:"/live-streams/:id/snapshot.jpg"`
+	ID              string `name:"id" in:"path"`
+}
+
+func (req *LiveStreamSnapshot) Output(ctx context.Context) (any, error) {
+	hub := livestream.StreamHubFromContext(ctx)
+	maxAge := livestream.SnapshotMaxAgeFromContext(ctx)
+
+	s, err := hub.Snapshot(ctx, req.ID, maxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshotServer{
+		content: &httputil.Content{
+			ReaderAt:     bytes.NewReader(s.Data),
+			Size:         int64(len(s.Data)),
+			ContentType:  mime.MediaTypeImageJPEG,
+			ETag:         fmt.Sprintf(`"%s-%d"`, req.ID, s.At.UnixNano()),
+			LastModified: s.At,
+			Header: http.Header{
+				"Cache-Control": {fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))},
+			},
+		},
+	}, nil
+}
+
+type snapshotServer struct {
+	content *httputil.Content
+}
+
+func (s *snapshotServer) Upgrade(rw http.ResponseWriter, req *http.Request) error {
+	httputil.ServeContent(rw, req, s.content)
+	return nil
+}
@@ -2,31 +2,109 @@ package livestream
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/go-courier/courier"
 	"github.com/go-courier/httptransport/httpx"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream/observer/image"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
 	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/pkg/errors"
 )
 
+// maxBurst of pictures taken in one request
+const maxBurst = 30
+
 func init() {
 	LiveStreamRouter.Register(courier.NewRouter(&LiveStreamTakePic{}))
 }
 
+// LiveStreamTakePic takes pictures of stream, returns infos of committed blobs once all taken,
+// or with status 207 when failed after some taken.
 type LiveStreamTakePic struct {
 	httpx.MethodPut `path:"/live-streams/:id/takepic"`
 	ID              string `name:"id" in:"path"`
+	// quality of jpeg, 1-100
+	Quality int `name:"quality,omitempty" in:"query"`
+	// scale of frame size, (0,1]
+	Scale float64 `name:"scale,omitempty" in:"query"`
+	// burst frames to take, 1 by default
+	Burst int `name:"burst,omitempty" in:"query"`
+	// interval between frames of burst, in milliseconds
+	Interval int `name:"interval,omitempty" in:"query"`
 }
 
 func (req *LiveStreamTakePic) Output(ctx context.Context) (any, error) {
+	if req.Quality < 0 || req.Quality > 100 {
+		return nil, statuserr.Wrap(http.StatusBadRequest, errors.Errorf("invalid quality %d", req.Quality), "quality should be 1-100")
+	}
+	if req.Scale < 0 || req.Scale > 1 {
+		return nil, statuserr.Wrap(http.StatusBadRequest, errors.Errorf("invalid scale %v", req.Scale), "scale should be in (0,1]")
+	}
+	if req.Burst < 0 || req.Burst > maxBurst {
+		return nil, statuserr.Wrap(http.StatusBadRequest, errors.Errorf("invalid burst %d", req.Burst), fmt.Sprintf("burst should be 1-%d", maxBurst))
+	}
+	if req.Interval < 0 || time.Duration(req.Interval)*time.Millisecond*time.Duration(req.Burst) > time.Minute {
+		return nil, statuserr.Wrap(http.StatusBadRequest, errors.Errorf("invalid interval %d", req.Interval), "burst should be taken in 1 minute")
+	}
+
 	hub := livestream.StreamHubFromContext(ctx)
 	store := storage.StoreFromContext(ctx)
 
-	_, err := hub.Subscribe(ctx, req.ID, image.New(store))
+	o := image.New(store, func(o *image.Options) {
+		if req.Quality > 0 {
+			o.Quality = req.Quality
+		}
+		if req.Scale > 0 {
+			o.Scale = req.Scale
+		}
+		if req.Burst > 0 {
+			o.Burst = req.Burst
+		}
+		o.Interval = time.Duration(req.Interval) * time.Millisecond
+	})
+
+	closer, err := hub.Subscribe(ctx, req.ID, o)
 	if err != nil {
 		return nil, err
 	}
+	defer closer.Close()
+
+	taken, err := o.Wait(ctx)
+	if err != nil && len(taken) == 0 {
+		return nil, err
+	}
+
+	result := &TakePicResult{
+		Blobs: make([]*blob.Info, 0, len(taken)),
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	for i := range taken {
+		info, err := store.Info(ctx, taken[i].Ref)
+		if err != nil {
+			// committed already, info as taken
+			info = &taken[i]
+		}
+		result.Blobs = append(result.Blobs, info)
+	}
+
+	if result.Error != "" {
+		return httpx.WithStatusCode(http.StatusMultiStatus)(result), nil
+	}
+
+	return result, nil
+}
 
-	return nil, nil
+// TakePicResult of burst, pictures taken before failure returned with error
+type TakePicResult struct {
+	Blobs []*blob.Info `json:"blobs"`
+	Error string       `json:"error,omitempty"`
 }
@@ -2,13 +2,17 @@ package livestream
 
 import (
 	"context"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-courier/courier"
 	"github.com/go-courier/httptransport/httpx"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream/observer/video"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
 	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/pkg/errors"
 )
 
 func init() {
@@ -19,14 +23,26 @@ type LiveStreamTakeVideo struct {
 	httpx.MethodPut `path:"/live-streams/:id/takevideo"`
 	ID              string `name:"id" in:"path"`
 	Stop            bool   `name:"stop,omitempty" in:"query"`
+	// labels k=v of video, embedded in video too
+	Labels []string `name:"label,omitempty" in:"query"`
 }
 
 func (req *LiveStreamTakeVideo) Output(ctx context.Context) (any, error) {
+	labels := map[string][]string{}
+	for _, kv := range req.Labels {
+		k, v, _ := strings.Cut(kv, "=")
+		if k == "" || strings.HasPrefix(k, "_") {
+			return nil, statuserr.Wrap(http.StatusBadRequest, errors.Errorf("invalid label %q", kv), "")
+		}
+		labels[k] = append(labels[k], v)
+	}
+
 	hub := livestream.StreamHubFromContext(ctx)
 	store := storage.StoreFromContext(ctx)
 
 	s, err := hub.Subscribe(ctx, req.ID, livestream.WithUniqueKey(req.ID, video.New(store, func(o *video.Options) {
 		o.MaxDuration = 60 * 10 * time.Second
+		o.Labels = labels
 	})))
 	if err != nil {
 		return nil, err
@@ -23,16 +23,19 @@ func init() {
 type LiveStreamWsmp4f struct {
 	httpx.MethodGet `path:"/live-streams/:id/wsmp4f"`
 	ID              string `name:"id" in:"path"`
+	// when viewer too slow, drop (default) frames until next keyframe, or disconnect
+	Policy livestream.QueuePolicy `name:"policy,omitempty" in:"query"`
 }
 
 func (req *LiveStreamWsmp4f) Output(ctx context.Context) (any, error) {
 	hub := livestream.StreamHubFromContext(ctx)
-	return &upgrader{hub: hub, id: req.ID}, nil
+	return &upgrader{hub: hub, id: req.ID, policy: req.Policy}, nil
 }
 
 type upgrader struct {
-	id  string
-	hub *livestream.StreamHub
+	id     string
+	policy livestream.QueuePolicy
+	hub    *livestream.StreamHub
 }
 
 func (ug *upgrader) Upgrade(rw http.ResponseWriter, req *http.Request) error {
@@ -53,7 +56,11 @@ func (ug *upgrader) Upgrade(rw http.ResponseWriter, req *http.Request) error {
 	}
 	defer c.Close()
 
-	sub, err := ug.hub.Subscribe(ctx, ug.id, wsmp4f.New(&wsWriter{c: c}))
+	sub, err := ug.hub.Subscribe(ctx, ug.id, wsmp4f.New(
+		&wsWriter{c: c},
+		livestream.WithSubscriberName("WsMP4f "+req.RemoteAddr),
+		livestream.WithQueuePolicy(ug.policy),
+	))
 	if err != nil {
 		logr.FromContextOrDiscard(ctx).Error(err, "subscribe failed")
 		return err
@@ -86,3 +93,7 @@ type wsWriter struct {
 func (w *wsWriter) Write(p []byte) (n int, err error) {
 	return len(p), w.c.WriteMessage(websocket.BinaryMessage, p)
 }
+
+func (w *wsWriter) Close() error {
+	return w.c.Close()
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/innoai-tech/media-toolkit/pkg/livestream"
+	rtspobserver "github.com/innoai-tech/media-toolkit/pkg/livestream/observer/rtsp"
+	"github.com/innoai-tech/media-toolkit/pkg/rtspserver"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+// RTSPServer re-serves each stream of hub at rtsp://<addr>/<stream id>,
+// encoded in preset by query like `?preset=720P`, 1080P by default.
+func (ls *LiveStreamServer) RTSPServer() *rtspserver.Server {
+	return &rtspserver.Server{
+		Source: &rtspSource{hub: ls.hub},
+	}
+}
+
+type rtspSource struct {
+	hub *livestream.StreamHub
+}
+
+func (s *rtspSource) Open(ctx context.Context, path string, query url.Values, remoteAddr string) (rtspserver.Stream, error) {
+	preset := livestream.Preset1080P
+	if p := query.Get("preset"); p != "" {
+		preset = livestream.EncodingPreset(strings.ToUpper(p))
+	}
+
+	if !slices.Contains([]livestream.EncodingPreset{livestream.Preset1080P, livestream.Preset720P, livestream.Preset480P}, preset) {
+		return nil, errors.Wrapf(rtspserver.ErrNotFound, "unsupported preset %s", preset)
+	}
+
+	o := rtspobserver.New(preset, livestream.WithSubscriberName("RTSP "+remoteAddr))
+
+	if _, err := s.hub.Subscribe(ctx, path, o); err != nil {
+		if errors.Is(err, livestream.StreamNotFound) {
+			return nil, rtspserver.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return o, nil
+}
@@ -2,15 +2,25 @@ package server
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
 	"github.com/go-logr/logr"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream/core"
+	"net"
 	"net/http"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-courier/httptransport"
+	"github.com/innoai-tech/media-toolkit/pkg/format"
+	"github.com/innoai-tech/media-toolkit/pkg/httputil"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream/server/routes"
+	"github.com/innoai-tech/media-toolkit/pkg/mediadevice/rtsp"
+	"github.com/innoai-tech/media-toolkit/pkg/rendition"
 	"github.com/innoai-tech/media-toolkit/pkg/storage"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/mime"
 	"github.com/innoai-tech/media-toolkit/pkg/version"
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/cors"
@@ -18,23 +28,39 @@ import (
 	"github.com/innoai-tech/media-toolkit/pkg/livestream"
 )
 
-func NewLiveStreamServer(ctx context.Context, streams []core.Stream) *LiveStreamServer {
+func init() {
+	rendition.RegisterFrameDecoder(mime.MediaTypeVideoMP4, rtsp.DecodeFrame)
+	rendition.RegisterClipper(mime.MediaTypeVideoMP4, format.Clip)
+}
+
+func NewLiveStreamServer(ctx context.Context, streams []core.Stream, c config.Config) (*LiveStreamServer, error) {
+	store, err := storage.New(c)
+	if err != nil {
+		return nil, err
+	}
+
 	hub := livestream.NewStreamHub()
 
 	for i := range streams {
 		hub.AddStream(logr.NewContext(ctx, logr.FromContextOrDiscard(ctx)), streams[i])
 	}
 
-	// TODO handle error
-	store, _ := storage.New(config.DefaultConfig)
-
 	return &LiveStreamServer{
 		hub:   hub,
 		store: store,
-	}
+	}, nil
 }
 
 type LiveStreamServer struct {
+	// AdminToken grants admin to requests with header `Authorization: Bearer <AdminToken>`
+	AdminToken string
+	// DatasetSigningKey signs manifest of exported datasets
+	DatasetSigningKey ed25519.PrivateKey
+	// BlobChunkLimit caps bytes of each range of blob downloads, no limit when 0
+	BlobChunkLimit int64
+	// SnapshotMaxAge of cached snapshot of each stream, livestream.DefaultSnapshotMaxAge when 0
+	SnapshotMaxAge time.Duration
+
 	hub   *livestream.StreamHub
 	store storage.Store
 }
@@ -73,11 +99,42 @@ func (ls *LiveStreamServer) injectContext(handler http.Handler) http.Handler {
 
 		ctx = livestream.NewContextWithStreamHub(ctx, ls.hub)
 		ctx = storage.NewContextWithStore(ctx, ls.store)
+		ctx = storage.NewContextWithActor(ctx, ls.actorOf(req))
+		if ls.DatasetSigningKey != nil {
+			ctx = storage.NewContextWithSigningKey(ctx, ls.DatasetSigningKey)
+		}
+		if ls.BlobChunkLimit > 0 {
+			ctx = httputil.NewContextWithChunkLimit(ctx, ls.BlobChunkLimit)
+		}
+		if ls.SnapshotMaxAge > 0 {
+			ctx = livestream.NewContextWithSnapshotMaxAge(ctx, ls.SnapshotMaxAge)
+		}
 
 		handler.ServeHTTP(rw, req.WithContext(ctx))
 	})
 }
 
+// actorOf returns admin verified by admin token, or anonymous.
+// name in header X-Actor is recorded as claimed only, which could not be verified.
+func (ls *LiveStreamServer) actorOf(req *http.Request) storage.Actor {
+	host, _, _ := net.SplitHostPort(req.RemoteAddr)
+
+	a := storage.Actor{
+		Name:    "anonymous",
+		Addr:    host,
+		Claimed: req.Header.Get("X-Actor"),
+	}
+
+	if ls.AdminToken != "" {
+		if token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "); subtle.ConstantTimeCompare([]byte(token), []byte(ls.AdminToken)) == 1 {
+			a.Name = "admin"
+			a.Admin = true
+		}
+	}
+
+	return a
+}
+
 func (ls *LiveStreamServer) apis() http.Handler {
 	allRoutes := routes.RootRouter.Routes()
 
@@ -96,18 +153,20 @@ func (ls *LiveStreamServer) apis() http.Handler {
 		httpRoute := routeMetas[i]
 		httpRoute.Log()
 
-		httpRouter.HandlerFunc(
-			httpRoute.Method(),
-			httpRoute.Path(),
-			httptransport.NewHttpRouteHandler(
-				&httptransport.ServiceMeta{
-					Name:    "livestream",
-					Version: version.FullVersion(),
-				},
-				httpRoute,
-				httptransport.NewRequestTransformerMgr(nil, nil),
-			).ServeHTTP,
-		)
+		handler := httptransport.NewHttpRouteHandler(
+			&httptransport.ServiceMeta{
+				Name:    "livestream",
+				Version: version.FullVersion(),
+			},
+			httpRoute,
+			httptransport.NewRequestTransformerMgr(nil, nil),
+		).ServeHTTP
+
+		if isMutating(httpRoute.Method()) && !isAuditedByStore(httpRoute) {
+			handler = ls.audited(operationID(httpRoute), handler)
+		}
+
+		httpRouter.HandlerFunc(httpRoute.Method(), httpRoute.Path(), handler)
 	}
 
 	return httpRouter
@@ -0,0 +1,96 @@
+package livestream
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"sync"
+	"time"
+)
+
+// DefaultSnapshotMaxAge of cached snapshot
+const DefaultSnapshotMaxAge = time.Second
+
+// Snapshot is the most recent decoded frame of stream, encoded as jpeg
+type Snapshot struct {
+	At   time.Time
+	Data []byte
+}
+
+// snapshotCache caches snapshot of stream, concurrent requests share one decoding.
+type snapshotCache struct {
+	mu       sync.Mutex
+	snapshot *Snapshot
+}
+
+func (c *snapshotCache) get(ctx context.Context, videoSource VideoSource, maxAge time.Duration) (*Snapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.snapshot != nil && time.Since(c.snapshot.At) <= maxAge {
+		return c.snapshot, nil
+	}
+
+	s, err := takeSnapshot(ctx, videoSource)
+	if err != nil {
+		return nil, err
+	}
+
+	c.snapshot = s
+
+	return s, nil
+}
+
+func takeSnapshot(ctx context.Context, videoSource VideoSource) (*Snapshot, error) {
+	r, err := videoSource.NewReader()
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(chan struct{})
+
+	var s *Snapshot
+
+	go func() {
+		defer close(taken)
+
+		img, release, e := r.Read()
+		if e != nil {
+			err = e
+			return
+		}
+		defer release()
+
+		at := time.Now()
+
+		b := bytes.NewBuffer(nil)
+		if err = jpeg.Encode(b, img, nil); err != nil {
+			return
+		}
+
+		s = &Snapshot{At: at, Data: b.Bytes()}
+	}()
+
+	// reading not cancelable, left to next frame
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-taken:
+		return s, err
+	}
+}
+
+type snapshotMaxAgeContextKey struct {
+}
+
+// SnapshotMaxAgeFromContext returns max age of cached snapshot, DefaultSnapshotMaxAge when not set
+func SnapshotMaxAgeFromContext(ctx context.Context) time.Duration {
+	if maxAge, ok := ctx.Value(snapshotMaxAgeContextKey{}).(time.Duration); ok {
+		return maxAge
+	}
+	return DefaultSnapshotMaxAge
+}
+
+func NewContextWithSnapshotMaxAge(ctx context.Context, maxAge time.Duration) context.Context {
+	return context.WithValue(ctx, snapshotMaxAgeContextKey{}, maxAge)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/innoai-tech/media-toolkit/pkg/util/syncutil"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
 
@@ -48,6 +49,14 @@ func (hub *StreamHub) Status(ctx context.Context, id string) (Status, error) {
 	return s.Status(), nil
 }
 
+func (hub *StreamHub) Snapshot(ctx context.Context, id string, maxAge time.Duration) (*Snapshot, error) {
+	s, ok := hub.streams.Load(id)
+	if !ok {
+		return nil, statuserr.Wrap(http.StatusNotFound, StreamNotFound, fmt.Sprintf("`%s` is not found", id))
+	}
+	return s.Snapshot(ctx, maxAge)
+}
+
 func (hub *StreamHub) Subscribe(ctx context.Context, id string, ob StreamObserver) (io.Closer, error) {
 	s, ok := hub.streams.Load(id)
 	if !ok {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/go-logr/logr"
 	"github.com/go-logr/stdr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream"
 	"github.com/innoai-tech/media-toolkit/pkg/livestream/core"
 	imageobserver "github.com/innoai-tech/media-toolkit/pkg/livestream/observer/image"
@@ -49,13 +50,17 @@ func TestStreamHub(t *testing.T) {
 
 			go func(i int) {
 				defer wg.Done()
-				o := imageobserver.New(s)
+				o := imageobserver.New(s, func(o *imageobserver.Options) {
+					o.Burst = 2
+					o.Interval = 100 * time.Millisecond
+				})
 
 				_, err := hub.Subscribe(ctx, "1", o)
 				Expect(t, err, Be[error](nil))
 
-				err = <-o.Done()
+				infos, err := o.Wait(ctx)
 				Expect(t, err, Be[error](nil))
+				Expect(t, infos, HaveLen[[]blob.Info](2))
 				fmt.Println("done", i)
 			}(i)
 		}
@@ -63,6 +68,17 @@ func TestStreamHub(t *testing.T) {
 		wg.Wait()
 	})
 
+	t.Run("Snapshot cached", func(t *testing.T) {
+		ctx := logr.NewContext(context.Background(), l)
+
+		s1, err := hub.Snapshot(ctx, "1", time.Minute)
+		Expect(t, err, Be[error](nil))
+
+		s2, err := hub.Snapshot(ctx, "1", time.Minute)
+		Expect(t, err, Be[error](nil))
+		Expect(t, s2.At, Equal(s1.At))
+	})
+
 	t.Run("Take video", func(t *testing.T) {
 		ctx := logr.NewContext(context.Background(), l)
 
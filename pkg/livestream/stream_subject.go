@@ -15,11 +15,15 @@ type StreamSubject interface {
 	Info() core.Stream
 	Status() Status
 	Subscribe(ctx context.Context, o StreamObserver) (io.Closer, error)
+	// Snapshot returns cached snapshot, or decodes new one when older than maxAge
+	Snapshot(ctx context.Context, maxAge time.Duration) (*Snapshot, error)
 }
 
 type Status struct {
 	Active    bool           `json:"active"`
 	Observers map[string]int `json:"observers"`
+	// Subscribers of encoded video, with lag and dropped frames
+	Subscribers []SubscriberStatus `json:"subscribers"`
 }
 
 type Metadata struct {
@@ -30,11 +34,13 @@ type Metadata struct {
 
 func NewStreamSubject(ctx context.Context, stream core.Stream) StreamSubject {
 	ss := &streamSubject{
-		stream: stream,
+		stream:      stream,
+		subscribers: &subscribers{},
+		snapshots:   &snapshotCache{},
 	}
 
 	ss.videoSource = syncutil.NewPool(func() (VideoSource, error) {
-		return newVideoSource(ctx, stream, ss.Status), nil
+		return newVideoSource(ctx, stream, ss.Status, ss.subscribers), nil
 	})
 
 	return ss
@@ -44,6 +50,8 @@ type streamSubject struct {
 	stream      core.Stream
 	videoSource *syncutil.Pool[VideoSource]
 	observers   syncutil.Map[any, StreamObserver]
+	subscribers *subscribers
+	snapshots   *snapshotCache
 }
 
 func (s *streamSubject) Close() error {
@@ -99,6 +107,14 @@ func (s *streamSubject) Subscribe(ctx context.Context, o StreamObserver) (io.Clo
 	return o, nil
 }
 
+func (s *streamSubject) Snapshot(ctx context.Context, maxAge time.Duration) (*Snapshot, error) {
+	videoSrc, err := s.videoSource.Get()
+	if err != nil {
+		return nil, err
+	}
+	return s.snapshots.get(ctx, videoSrc, maxAge)
+}
+
 func (s *streamSubject) Status() Status {
 	status := Status{
 		Observers: map[string]int{},
@@ -111,6 +127,7 @@ func (s *streamSubject) Status() Status {
 	})
 
 	status.Active = s.videoSource != nil
+	status.Subscribers = s.subscribers.Status()
 
 	return status
 }
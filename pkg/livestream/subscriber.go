@@ -0,0 +1,274 @@
+package livestream
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/util/syncutil"
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrSlowSubscriber   = errors.New("subscriber too slow")
+	ErrSubscriberClosed = errors.New("subscriber closed")
+)
+
+// QueuePolicy of subscriber when its queue full
+type QueuePolicy string
+
+const (
+	// QueuePolicyDrop drops frames until next keyframe
+	QueuePolicyDrop QueuePolicy = "drop"
+	// QueuePolicyDisconnect disconnects subscriber after MaxLag behind
+	QueuePolicyDisconnect QueuePolicy = "disconnect"
+)
+
+type SubscribeOptions struct {
+	// Name of subscriber in status
+	Name      string
+	QueueSize int
+	Policy    QueuePolicy
+	// MaxLag for QueuePolicyDisconnect
+	MaxLag time.Duration
+}
+
+type SubscribeOptFunc func(o *SubscribeOptions)
+
+func (o *SubscribeOptions) Apply(opts ...SubscribeOptFunc) {
+	for i := range opts {
+		opts[i](o)
+	}
+}
+
+func WithSubscriberName(name string) SubscribeOptFunc {
+	return func(o *SubscribeOptions) {
+		o.Name = name
+	}
+}
+
+func WithQueueSize(size int) SubscribeOptFunc {
+	return func(o *SubscribeOptions) {
+		if size > 0 {
+			o.QueueSize = size
+		}
+	}
+}
+
+func WithQueuePolicy(policy QueuePolicy) SubscribeOptFunc {
+	return func(o *SubscribeOptions) {
+		if policy != "" {
+			o.Policy = policy
+		}
+	}
+}
+
+// SubscriberStatus of encoded reader
+type SubscriberStatus struct {
+	Name   string         `json:"name"`
+	Preset EncodingPreset `json:"preset"`
+	Policy QueuePolicy    `json:"policy"`
+	// Queued frames
+	Queued int `json:"queued"`
+	// Lag of last frame read in milliseconds, from received to read
+	Lag int64 `json:"lag"`
+	// Dropped frames
+	Dropped uint64 `json:"dropped"`
+}
+
+type queuedFrame struct {
+	mediadevices.EncodedBuffer
+	release    func()
+	receivedAt time.Time
+}
+
+// subscriberQueue pumps frames of reader into bounded queue,
+// which protects source from slow subscriber, by policy when queue full.
+type subscriberQueue struct {
+	preset  EncodingPreset
+	options SubscribeOptions
+	reader  mediadevices.EncodedReadCloser
+	frames  chan queuedFrame
+
+	lag     int64
+	dropped uint64
+
+	done         chan struct{}
+	err          error
+	shutdownOnce sync.Once
+	closeOnce    sync.Once
+}
+
+func newSubscriberQueue(reader mediadevices.EncodedReadCloser, preset EncodingPreset, opts ...SubscribeOptFunc) *subscriberQueue {
+	options := SubscribeOptions{
+		Name:      string(preset),
+		QueueSize: 60,
+		Policy:    QueuePolicyDrop,
+		MaxLag:    5 * time.Second,
+	}
+	options.Apply(opts...)
+
+	q := &subscriberQueue{
+		preset:  preset,
+		options: options,
+		reader:  reader,
+		frames:  make(chan queuedFrame, options.QueueSize),
+		done:    make(chan struct{}),
+	}
+
+	go q.pump()
+
+	return q
+}
+
+func (q *subscriberQueue) pump() {
+	dropping := false
+
+	for {
+		buf, release, err := q.reader.Read()
+		if err != nil {
+			q.shutdown(err)
+			return
+		}
+
+		if release == nil {
+			release = func() {}
+		}
+
+		frame := queuedFrame{EncodedBuffer: buf, release: release, receivedAt: time.Now()}
+
+		if dropping {
+			if !isH264KeyFrame(buf.Data) {
+				q.drop(frame)
+				continue
+			}
+			dropping = false
+		}
+
+		select {
+		case <-q.done:
+			release()
+			return
+		case q.frames <- frame:
+			continue
+		default:
+		}
+
+		// queue full
+		switch q.options.Policy {
+		case QueuePolicyDisconnect:
+			timer := time.NewTimer(q.options.MaxLag)
+
+			select {
+			case <-q.done:
+				timer.Stop()
+				release()
+				return
+			case q.frames <- frame:
+				timer.Stop()
+			case <-timer.C:
+				release()
+				q.shutdown(ErrSlowSubscriber)
+				return
+			}
+		default:
+			q.drop(frame)
+			dropping = true
+			q.forceKeyFrame()
+		}
+	}
+}
+
+// forceKeyFrame requests keyframe to stop dropping sooner, rate limited by controller of reader
+func (q *subscriberQueue) forceKeyFrame() {
+	if kfc, ok := q.reader.Controller().(codec.KeyFrameController); ok {
+		_ = kfc.ForceKeyFrame()
+	}
+}
+
+func (q *subscriberQueue) drop(frame queuedFrame) {
+	frame.release()
+	atomic.AddUint64(&q.dropped, 1)
+}
+
+func (q *subscriberQueue) Read() (mediadevices.EncodedBuffer, func(), error) {
+	select {
+	case frame := <-q.frames:
+		return q.read(frame)
+	case <-q.done:
+		// frames queued before done
+		select {
+		case frame := <-q.frames:
+			return q.read(frame)
+		default:
+			return mediadevices.EncodedBuffer{}, func() {}, q.err
+		}
+	}
+}
+
+func (q *subscriberQueue) read(frame queuedFrame) (mediadevices.EncodedBuffer, func(), error) {
+	atomic.StoreInt64(&q.lag, time.Since(frame.receivedAt).Milliseconds())
+	return frame.EncodedBuffer, frame.release, nil
+}
+
+func (q *subscriberQueue) shutdown(err error) {
+	q.shutdownOnce.Do(func() {
+		q.err = err
+		close(q.done)
+	})
+}
+
+func (q *subscriberQueue) Close() (err error) {
+	q.shutdown(ErrSubscriberClosed)
+	q.closeOnce.Do(func() {
+		err = q.reader.Close()
+	})
+	return
+}
+
+func (q *subscriberQueue) Controller() codec.EncoderController {
+	return q.reader.Controller()
+}
+
+func (q *subscriberQueue) Status() SubscriberStatus {
+	return SubscriberStatus{
+		Name:    q.options.Name,
+		Preset:  q.preset,
+		Policy:  q.options.Policy,
+		Queued:  len(q.frames),
+		Lag:     atomic.LoadInt64(&q.lag),
+		Dropped: atomic.LoadUint64(&q.dropped),
+	}
+}
+
+// subscribers of stream, for status
+type subscribers struct {
+	queues syncutil.Map[*subscriberQueue, bool]
+}
+
+func (s *subscribers) add(q *subscriberQueue) {
+	s.queues.Store(q, true)
+
+	go func() {
+		<-q.done
+		s.queues.Delete(q)
+	}()
+}
+
+func (s *subscribers) Status() []SubscriberStatus {
+	list := make([]SubscriberStatus, 0)
+
+	s.queues.Range(func(key, _ any) bool {
+		list = append(list, key.(*subscriberQueue).Status())
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
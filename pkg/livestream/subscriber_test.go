@@ -0,0 +1,83 @@
+package livestream
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/octohelm/x/testing"
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/codec"
+)
+
+func TestSubscriberQueue(t *testing.T) {
+	idr := []byte{0, 0, 0, 1, 0x65, 0x88}
+	nonIDR := []byte{0, 0, 0, 1, 0x41, 0x9a}
+
+	newSource := func(frames ...[]byte) *fakeEncodedReader {
+		r := &fakeEncodedReader{frames: make(chan []byte, len(frames)), keyFrameController: &fakeKeyFrameController{}}
+		for _, f := range frames {
+			r.frames <- f
+		}
+		close(r.frames)
+		return r
+	}
+
+	t.Run("Drop until keyframe", func(t *testing.T) {
+		source := newSource(idr, nonIDR, nonIDR, idr, nonIDR)
+		q := newSubscriberQueue(source, Preset1080P, func(o *SubscribeOptions) {
+			o.QueueSize = 1
+		})
+		defer q.Close()
+
+		// wait source drained
+		<-q.done
+
+		buf, _, err := q.Read()
+		Expect(t, err, Be[error](nil))
+		Expect(t, buf.Data, Equal(idr))
+		Expect(t, q.Status().Dropped, Be(uint64(4)))
+		Expect(t, atomic.LoadInt64(&source.keyFrameController.forced) > 0, Be(true))
+	})
+
+	t.Run("Disconnect when lag", func(t *testing.T) {
+		q := newSubscriberQueue(newSource(idr, nonIDR, nonIDR), Preset1080P, WithQueuePolicy(QueuePolicyDisconnect), func(o *SubscribeOptions) {
+			o.QueueSize = 1
+			o.MaxLag = 10 * time.Millisecond
+		})
+		defer q.Close()
+
+		<-q.done
+		Expect(t, q.err, Be(ErrSlowSubscriber))
+	})
+}
+
+type fakeEncodedReader struct {
+	frames             chan []byte
+	keyFrameController *fakeKeyFrameController
+}
+
+func (r *fakeEncodedReader) Read() (mediadevices.EncodedBuffer, func(), error) {
+	data, ok := <-r.frames
+	if !ok {
+		return mediadevices.EncodedBuffer{}, nil, ErrSubscriberClosed
+	}
+	return mediadevices.EncodedBuffer{Data: data}, func() {}, nil
+}
+
+func (r *fakeEncodedReader) Close() error {
+	return nil
+}
+
+func (r *fakeEncodedReader) Controller() codec.EncoderController {
+	return r.keyFrameController
+}
+
+type fakeKeyFrameController struct {
+	forced int64
+}
+
+func (c *fakeKeyFrameController) ForceKeyFrame() error {
+	atomic.AddInt64(&c.forced, 1)
+	return nil
+}
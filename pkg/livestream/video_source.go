@@ -28,7 +28,7 @@ const (
 type VideoSource interface {
 	ID() string
 	NewReader() (video.Reader, error)
-	NewEncodedReader(EncodingPreset) (mediadevices.EncodedReadCloser, error)
+	NewEncodedReader(preset EncodingPreset, opts ...SubscribeOptFunc) (mediadevices.EncodedReadCloser, error)
 	Status() Status
 }
 
@@ -36,11 +36,12 @@ type VideoTrack interface {
 	NewEncodedReader() (mediadevices.EncodedReadCloser, error)
 }
 
-func newVideoSource(ctx context.Context, stream core.Stream, getStatus func() Status) VideoSource {
+func newVideoSource(ctx context.Context, stream core.Stream, getStatus func() Status, subscribers *subscribers) VideoSource {
 	vs := &videoSource{
 		l:           logr.FromContextOrDiscard(ctx).WithValues("stream_id", stream.ID, "stream_name", stream.Name),
 		stream:      stream,
 		getStatus:   getStatus,
+		subscribers: subscribers,
 		idleTimeout: 30 * time.Second,
 	}
 
@@ -89,8 +90,14 @@ func newVideoSource(ctx context.Context, stream core.Stream, getStatus func() St
 					return nil, err
 				}
 
+				gop := &gopCache{}
+
 				broadcaster := io.NewBroadcaster(io.ReaderFunc(func() (interface{}, func(), error) {
-					return encodedReadCloser.Read()
+					buf, release, err := encodedReadCloser.Read()
+					if err != nil {
+						return nil, release, err
+					}
+					return gop.push(buf), release, nil
 				}), nil)
 
 				return &videoEncodedBroadcaster{
@@ -102,6 +109,11 @@ func newVideoSource(ctx context.Context, stream core.Stream, getStatus func() St
 						return encodedReadCloser.Close()
 					},
 					broadcaster: broadcaster,
+					gop:         gop,
+					keyFrame: &keyFrameRequester{
+						controller: encodedReadCloser.Controller(),
+						interval:   2 * time.Second,
+					},
 				}, nil
 			})
 		}(vs, preset)
@@ -116,7 +128,8 @@ func newVideoSource(ctx context.Context, stream core.Stream, getStatus func() St
 type videoSource struct {
 	l logr.Logger
 
-	getStatus func() Status
+	getStatus   func() Status
+	subscribers *subscribers
 
 	stream core.Stream
 
@@ -153,7 +166,8 @@ func (s *videoSource) NewReader() (video.Reader, error) {
 	return s.broadcaster.NewReader(true), nil
 }
 
-func (s *videoSource) NewEncodedReader(preset EncodingPreset) (mediadevices.EncodedReadCloser, error) {
+// NewEncodedReader returns reader with bounded queue, see SubscribeOptions
+func (s *videoSource) NewEncodedReader(preset EncodingPreset, opts ...SubscribeOptFunc) (mediadevices.EncodedReadCloser, error) {
 	vt, ok := s.videoTracks.Load(preset)
 	if !ok {
 		return nil, fmt.Errorf("unsuportted preset %s", preset)
@@ -164,18 +178,37 @@ func (s *videoSource) NewEncodedReader(preset EncodingPreset) (mediadevices.Enco
 		return nil, err
 	}
 
-	return v.NewEncodedReader()
+	r, err := v.NewEncodedReader()
+	if err != nil {
+		return nil, err
+	}
+
+	q := newSubscriberQueue(r, preset, opts...)
+	s.subscribers.add(q)
+	return q, nil
 }
 
 type videoEncodedBroadcaster struct {
 	broadcaster *io.Broadcaster
+	gop         *gopCache
+	keyFrame    *keyFrameRequester
 	used        int64
 	closeFn     func() error
 }
 
+// NewEncodedReader returns reader starts with cached gop, and requests keyframe for next gop.
 func (b *videoEncodedBroadcaster) NewEncodedReader() (mediadevices.EncodedReadCloser, error) {
 	atomic.AddInt64(&b.used, 1)
 
+	// reader created before snapshot, frames in both skipped by seq
+	broadcasterReader := b.broadcaster.NewReader(func(v interface{}) interface{} {
+		return v
+	})
+
+	cached := b.gop.snapshot()
+
+	_ = b.keyFrame.ForceKeyFrame()
+
 	return &videoEncodedReadCloser{
 		closeFn: func() error {
 			atomic.AddInt64(&b.used, -1)
@@ -184,30 +217,61 @@ func (b *videoEncodedBroadcaster) NewEncodedReader() (mediadevices.EncodedReadCl
 			}
 			return nil
 		},
-		broadcasterReader: b.broadcaster.NewReader(func(v interface{}) interface{} {
-			eb := v.(mediadevices.EncodedBuffer)
-			return mediadevices.EncodedBuffer{
-				Data:    eb.Data[0:],
-				Samples: eb.Samples,
-			}
-		}),
+		cached:            cached,
+		broadcasterReader: broadcasterReader,
+		controller:        b.keyFrame,
 	}, nil
 }
 
 type videoEncodedReadCloser struct {
 	closeFn           func() error
+	cached            []encodedFrame
+	lastSeq           uint64
 	broadcasterReader io.Reader
+	controller        codec.EncoderController
 }
 
 func (v *videoEncodedReadCloser) Read() (mediadevices.EncodedBuffer, func(), error) {
-	buf, release, err := v.broadcasterReader.Read()
-	return buf.(mediadevices.EncodedBuffer), release, err
+	if len(v.cached) > 0 {
+		frame := v.cached[0]
+		v.cached = v.cached[1:]
+		v.lastSeq = frame.Seq
+		return v.copy(frame), func() {}, nil
+	}
+
+	for {
+		data, release, err := v.broadcasterReader.Read()
+		if err != nil {
+			return mediadevices.EncodedBuffer{}, release, err
+		}
+
+		frame := data.(encodedFrame)
+		if frame.Seq <= v.lastSeq {
+			// sent in cached gop
+			continue
+		}
+		v.lastSeq = frame.Seq
+
+		if release == nil {
+			release = func() {}
+		}
+
+		return v.copy(frame), release, nil
+	}
+}
+
+func (v *videoEncodedReadCloser) copy(frame encodedFrame) mediadevices.EncodedBuffer {
+	return mediadevices.EncodedBuffer{
+		Data:    frame.Data[0:],
+		Samples: frame.Samples,
+	}
 }
 
 func (v *videoEncodedReadCloser) Close() error {
 	return v.closeFn()
 }
 
+// Controller returns codec.KeyFrameController, which rate limited across subscribers
 func (v *videoEncodedReadCloser) Controller() codec.EncoderController {
-	return nil
+	return v.controller
 }
@@ -159,3 +159,33 @@ func (c *RTSPConnect) readImage() (bool, *image.YCbCr, error) {
 
 	return c.videoStreamDecoder.ToImage(pkt)
 }
+
+// DecodeFrame decodes first video frame of media file or url, like poster frame of mp4
+func DecodeFrame(ctx context.Context, filename string) (image.Image, error) {
+	conn, err := Open(ctx, filename, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	img, release, err := conn.Read()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// frame data owned by decoder, should copy before close
+	if ycc, ok := img.(*image.YCbCr); ok {
+		return &image.YCbCr{
+			Rect:           ycc.Rect,
+			Y:              append([]uint8(nil), ycc.Y...),
+			Cb:             append([]uint8(nil), ycc.Cb...),
+			Cr:             append([]uint8(nil), ycc.Cr...),
+			YStride:        ycc.YStride,
+			CStride:        ycc.CStride,
+			SubsampleRatio: ycc.SubsampleRatio,
+		}, nil
+	}
+
+	return img, nil
+}
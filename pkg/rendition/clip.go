@@ -0,0 +1,154 @@
+package rendition
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// Clipper remuxes packets between the nearest keyframe before start and end of media file into w,
+// and returns the actual window of clip.
+type Clipper func(ctx context.Context, filename string, w io.WriteSeeker, start time.Duration, end time.Duration) (from time.Duration, through time.Duration, err error)
+
+var clippers = sync.Map{}
+
+// RegisterClipper registers Clipper for media type, like FrameDecoder
+func RegisterClipper(mediaType string, c Clipper) {
+	clippers.Store(mediaType, c)
+}
+
+var ErrInvalidClip = errors.New("invalid clip")
+
+// ParseOffset parses offset from start of video, in seconds like 12.5, or duration like 1m30s
+func ParseOffset(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, statuserr.Wrap(http.StatusBadRequest, ErrInvalidClip, "offset should be seconds or duration")
+	}
+	return d, nil
+}
+
+// Clip of video between Start and End, which offsets from start of source.
+// The clip starts at the nearest keyframe before Start, and its time range is the actual window.
+type Clip struct {
+	Start time.Duration
+	End   time.Duration
+
+	window blob.TimeRange
+}
+
+func (c *Clip) Kind() string {
+	return "clip"
+}
+
+func (c *Clip) Params() url.Values {
+	return url.Values{
+		"start": {strconv.FormatInt(c.Start.Milliseconds(), 10)},
+		"end":   {strconv.FormatInt(c.End.Milliseconds(), 10)},
+	}
+}
+
+// TimeRange of clip, available after rendered
+func (c *Clip) TimeRange(source *blob.Info) blob.TimeRange {
+	return c.window
+}
+
+func (c *Clip) Render(ctx context.Context, s storage.Store, source *blob.Info, w io.Writer) (string, error) {
+	f, mediaType, err := c.RenderFile(ctx, s, source)
+	if err != nil {
+		return "", err
+	}
+	defer remove(f)
+
+	if _, err := io.Copy(w, f); err != nil {
+		return "", err
+	}
+
+	return mediaType, nil
+}
+
+// RenderFile renders clip into temp file seeked to start, which should be closed and removed by caller.
+func (c *Clip) RenderFile(ctx context.Context, s storage.Store, source *blob.Info) (*os.File, string, error) {
+	if c.Start < 0 || c.End <= c.Start {
+		return nil, "", statuserr.Wrap(http.StatusBadRequest, ErrInvalidClip, "end should be after start")
+	}
+
+	mediaType := mediaTypeOf(source)
+
+	clipper, ok := clippers.Load(mediaType)
+	if !ok {
+		return nil, "", statuserr.Wrap(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, mediaType)
+	}
+
+	src, err := spool(ctx, s, source)
+	if err != nil {
+		return nil, "", err
+	}
+	defer remove(src)
+
+	// muxer requires seeker
+	dst, err := s.TempFile(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	from, through, err := clipper.(Clipper)(ctx, src.Name(), dst, c.Start, c.End)
+	if err != nil {
+		remove(dst)
+		return nil, "", statuserr.Wrap(http.StatusUnprocessableEntity, err, "clip failed")
+	}
+
+	c.window = blob.TimeRange{
+		From:    source.From.Add(from),
+		Through: source.From.Add(through),
+	}
+
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		remove(dst)
+		return nil, "", err
+	}
+
+	return dst, mediaType, nil
+}
+
+// spool copies content of blob into temp file, for libav reads file only
+func spool(ctx context.Context, s storage.Store, info *blob.Info) (*os.File, error) {
+	r, err := s.ReaderAt(ctx, info.Ref)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := s.TempFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(f, io.NewSectionReader(r, 0, r.Size())); err != nil {
+		remove(f)
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func remove(f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
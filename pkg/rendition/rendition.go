@@ -0,0 +1,231 @@
+package rendition
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrImageTooLarge        = errors.New("image too large")
+)
+
+// MaxImagePixels of image to decode, which decoded in memory as 4 bytes or more per pixel
+const MaxImagePixels = 8192 * 8192
+
+// decodeImage decodes image, size checked by header before decoding
+func decodeImage(r io.Reader) (image.Image, error) {
+	header := bytes.NewBuffer(nil)
+
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, header))
+	if err != nil {
+		return nil, statuserr.Wrap(http.StatusUnprocessableEntity, err, "decode image failed")
+	}
+
+	if int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return nil, statuserr.Wrap(http.StatusUnprocessableEntity, ErrImageTooLarge, fmt.Sprintf("image %dx%d exceeds %d pixels", cfg.Width, cfg.Height, MaxImagePixels))
+	}
+
+	img, _, err := image.Decode(io.MultiReader(header, r))
+	if err != nil {
+		return nil, statuserr.Wrap(http.StatusUnprocessableEntity, err, "decode image failed")
+	}
+	return img, nil
+}
+
+// FrameDecoder decodes first frame of media file, as poster frame of video
+type FrameDecoder func(ctx context.Context, filename string) (image.Image, error)
+
+var frameDecoders = sync.Map{}
+
+// RegisterFrameDecoder registers FrameDecoder for media type,
+// which decoded by libav bindings should be registered by packages linked with libav.
+func RegisterFrameDecoder(mediaType string, d FrameDecoder) {
+	frameDecoders.Store(mediaType, d)
+}
+
+// DecodeFrame decodes image, or first frame of video of blob
+func DecodeFrame(ctx context.Context, s storage.Store, info *blob.Info) (image.Image, error) {
+	mediaType := mediaTypeOf(info)
+
+	if strings.HasPrefix(mediaType, "image/") {
+		r, err := s.ReaderAt(ctx, info.Ref)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return decodeImage(io.NewSectionReader(r, 0, r.Size()))
+	}
+
+	d, ok := frameDecoders.Load(mediaType)
+	if !ok {
+		return nil, statuserr.Wrap(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, mediaType)
+	}
+
+	f, err := spool(ctx, s, info)
+	if err != nil {
+		return nil, err
+	}
+	defer remove(f)
+
+	img, err := d.(FrameDecoder)(ctx, f.Name())
+	if err != nil {
+		return nil, statuserr.Wrap(http.StatusUnprocessableEntity, err, "decode frame failed")
+	}
+	return img, nil
+}
+
+// Rendition renders source into derived blob
+type Rendition interface {
+	// Kind of rendition, like thumbnail
+	Kind() string
+	// Params identify the rendition of same kind
+	Params() url.Values
+	// Render writes rendition of source, and returns media type of it
+	Render(ctx context.Context, s storage.Store, source *blob.Info, w io.Writer) (string, error)
+}
+
+// TimeRanger is optional for Rendition, which derived blob covers part of source,
+// TimeRange called after Render.
+type TimeRanger interface {
+	TimeRange(source *blob.Info) blob.TimeRange
+}
+
+var group singleflight.Group
+
+// renderTimeout of rendition shared by callers, which not canceled by any of them
+const renderTimeout = 5 * time.Minute
+
+// detachedContext keeps values of parent but never canceled with it,
+// like context.WithoutCancel of go 1.21
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.parent.Value(key)
+}
+
+// Derive returns derived blob of source rendered by rendition,
+// which cached as blob labeled with _derived_from and _rendition, and computed once.
+func Derive(ctx context.Context, s storage.Store, ref blob.Ref, r Rendition) (*blob.Info, error) {
+	source, err := s.Info(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := source.Labels[blob.LabelDerivedFrom]; ok {
+		return nil, statuserr.Wrap(http.StatusBadRequest, errors.New("could not derive from derived blob"), "")
+	}
+
+	sourceKey := source.Ref.ExternalKey("")
+	rendition := r.Kind() + "?" + r.Params().Encode()
+
+	ch := group.DoChan(sourceKey+"#"+rendition, func() (any, error) {
+		// shared by callers, should not be canceled when the first caller gone
+		ctx, cancel := context.WithTimeout(detachedContext{parent: ctx}, renderTimeout)
+		defer cancel()
+
+		derived, err := s.Query(
+			ctx,
+			source.TimeRange,
+			source.UserID,
+			labels.MustNewMatcher(labels.MatchEqual, blob.LabelDerivedFrom, sourceKey),
+			labels.MustNewMatcher(labels.MatchEqual, blob.LabelRendition, rendition),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(derived) > 0 {
+			return &derived[0], nil
+		}
+
+		buf := bytes.NewBuffer(nil)
+
+		mediaType, err := r.Render(ctx, s, source, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		timeRange := source.TimeRange
+		if tr, ok := r.(TimeRanger); ok {
+			timeRange = tr.TimeRange(source)
+		}
+
+		size := int64(buf.Len())
+
+		lbs := blob.Labels{
+			"_media_type":         {mediaType},
+			"_size":               {strconv.FormatInt(size, 10)},
+			blob.LabelDerivedFrom: {sourceKey},
+			blob.LabelRendition:   {rendition},
+		}
+
+		// labeled as derived, so not cached instead of evicting others when quota exceeded
+		w, err := s.Writer(ctx, blob.WithUserId(source.UserID), blob.WithFromThough(timeRange.From, timeRange.Through), blob.WithLabels(lbs))
+		if err != nil {
+			return nil, err
+		}
+		defer w.Close()
+
+		if _, err := io.Copy(w, buf); err != nil {
+			return nil, err
+		}
+
+		if err := w.Commit(ctx, size, ""); err != nil {
+			return nil, err
+		}
+
+		info := w.Info()
+		info.Labels = lbs
+		return &info, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case ret := <-ch:
+		if ret.Err != nil {
+			return nil, ret.Err
+		}
+		return ret.Val.(*blob.Info), nil
+	}
+}
+
+func mediaTypeOf(info *blob.Info) string {
+	if mt, ok := info.Labels["_media_type"]; ok && len(mt) > 0 {
+		return mt[0]
+	}
+	return ""
+}
@@ -0,0 +1,287 @@
+package rendition
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	. "github.com/octohelm/x/testing"
+	"github.com/pkg/errors"
+)
+
+func TestThumbnail(t *testing.T) {
+	c := config.DefaultConfig
+	c.Storage.Root = t.TempDir()
+
+	s, err := storage.New(c)
+	Expect(t, err, Be[error](nil))
+	defer func() {
+		_ = s.Shutdown(context.Background())
+	}()
+
+	img := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for x := 0; x < 640; x++ {
+		img.Set(x, x%480, color.White)
+	}
+	buf := bytes.NewBuffer(nil)
+	_ = jpeg.Encode(buf, img, nil)
+
+	w, err := s.Writer(context.Background())
+	Expect(t, err, Be[error](nil))
+	_, _ = io.Copy(w, bytes.NewReader(buf.Bytes()))
+	err = w.Commit(context.Background(), 0, "", blob.WithLabels(blob.Labels{"_media_type": {"image/jpeg"}}))
+	Expect(t, err, Be[error](nil))
+	source := w.Info()
+
+	t.Run("Derive thumbnail", func(t *testing.T) {
+		derived, err := Derive(context.Background(), s, source.Ref, &Thumbnail{Width: 160})
+		Expect(t, err, Be[error](nil))
+		Expect(t, derived.Labels[blob.LabelDerivedFrom], Equal([]string{source.ExternalKey("")}))
+
+		r, err := s.ReaderAt(context.Background(), derived.Ref)
+		Expect(t, err, Be[error](nil))
+		defer r.Close()
+
+		cfg, err := jpeg.DecodeConfig(io.NewSectionReader(r, 0, r.Size()))
+		Expect(t, err, Be[error](nil))
+		Expect(t, cfg.Width, Be(160))
+		Expect(t, cfg.Height, Be(120))
+
+		t.Run("Cached", func(t *testing.T) {
+			cached, err := Derive(context.Background(), s, source.Ref, &Thumbnail{Width: 160})
+			Expect(t, err, Be[error](nil))
+			Expect(t, cached.Ref, Equal(derived.Ref))
+		})
+
+		t.Run("Could not derive from derived", func(t *testing.T) {
+			_, err := Derive(context.Background(), s, derived.Ref, &Thumbnail{Width: 160})
+			Expect(t, err, Not(Be[error](nil)))
+		})
+	})
+
+	t.Run("Unsupported media type", func(t *testing.T) {
+		w, err := s.Writer(context.Background())
+		Expect(t, err, Be[error](nil))
+		_, _ = io.WriteString(w, "text")
+		err = w.Commit(context.Background(), 0, "", blob.WithLabels(blob.Labels{"_media_type": {"text/plain"}}))
+		Expect(t, err, Be[error](nil))
+
+		_, err = Derive(context.Background(), s, w.Info().Ref, &Thumbnail{})
+		Expect(t, err, Not(Be[error](nil)))
+	})
+
+	t.Run("Not canceled by first caller", func(t *testing.T) {
+		r := &blockingRendition{started: make(chan struct{}), release: make(chan struct{})}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		first := make(chan error, 1)
+		go func() {
+			_, err := Derive(ctx, s, source.Ref, r)
+			first <- err
+		}()
+		<-r.started
+
+		second := make(chan error, 1)
+		go func() {
+			_, err := Derive(context.Background(), s, source.Ref, r)
+			second <- err
+		}()
+
+		cancel()
+		Expect(t, <-first, Be[error](context.Canceled))
+
+		close(r.release)
+		Expect(t, <-second, Be[error](nil))
+	})
+
+	t.Run("Deleted with source", func(t *testing.T) {
+		derived, err := Derive(context.Background(), s, source.Ref, &Thumbnail{Width: 80})
+		Expect(t, err, Be[error](nil))
+
+		Expect(t, s.Delete(context.Background(), source.Ref), Be[error](nil))
+
+		_, err = s.Info(context.Background(), derived.Ref)
+		Expect(t, errors.Is(err, storage.ErrNotFound), Be(true))
+	})
+}
+
+// blockingRendition renders until released, fails when ctx canceled
+type blockingRendition struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRendition) Kind() string {
+	return "blocking"
+}
+
+func (r *blockingRendition) Params() url.Values {
+	return url.Values{}
+}
+
+func (r *blockingRendition) Render(ctx context.Context, s storage.Store, source *blob.Info, w io.Writer) (string, error) {
+	close(r.started)
+	<-r.release
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	_, err := io.WriteString(w, "blocking")
+	return "text/plain", err
+}
+
+func TestDeriveWhenQuotaExceeded(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	_ = jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, 640, 480)), nil)
+
+	c := config.DefaultConfig
+	c.Storage.Root = t.TempDir()
+	c.Storage.Quota = config.QuotaConfig{MaxBytes: int64(buf.Len()) + 10, Evict: true}
+
+	s, err := storage.New(c)
+	Expect(t, err, Be[error](nil))
+	defer func() {
+		_ = s.Shutdown(context.Background())
+	}()
+
+	w, err := s.Writer(context.Background())
+	Expect(t, err, Be[error](nil))
+	_, _ = io.Copy(w, bytes.NewReader(buf.Bytes()))
+	err = w.Commit(context.Background(), 0, "", blob.WithLabels(blob.Labels{"_media_type": {"image/jpeg"}}))
+	Expect(t, err, Be[error](nil))
+	source := w.Info()
+
+	_, err = Derive(context.Background(), s, source.Ref, &Thumbnail{Width: 160})
+	Expect(t, errors.Is(err, storage.ErrQuotaExceeded), Be(true))
+
+	// source never evicted by derived
+	_, err = s.Info(context.Background(), source.Ref)
+	Expect(t, err, Be[error](nil))
+}
+
+func TestScale(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	Expect(t, Scale(img, 100, 100).Bounds(), Equal(image.Rect(0, 0, 100, 50)))
+	Expect(t, Scale(img, 0, 100).Bounds(), Equal(image.Rect(0, 0, 200, 100)))
+	Expect(t, Scale(img, 800, 0).Bounds(), Equal(image.Rect(0, 0, 400, 200)))
+}
+
+func TestTransform(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	img.Set(0, 0, color.White)
+
+	t.Run("Crop, rotate and resize", func(t *testing.T) {
+		tf, err := ParseTransform("crop=0,0,200,100&rotate=90&w=25&format=png")
+		Expect(t, err, Be[error](nil))
+
+		out, err := tf.Apply(img)
+		Expect(t, err, Be[error](nil))
+		Expect(t, out.Bounds(), Equal(image.Rect(0, 0, 25, 50)))
+
+		buf := bytes.NewBuffer(nil)
+		Expect(t, tf.Encode(buf, out), Be[error](nil))
+		_, format, err := image.DecodeConfig(buf)
+		Expect(t, err, Be[error](nil))
+		Expect(t, format, Be("png"))
+	})
+
+	t.Run("Rotate keeps pixels", func(t *testing.T) {
+		out := rotate(img, 90)
+		Expect(t, out.Bounds(), Equal(image.Rect(0, 0, 200, 400)))
+		r, _, _, _ := out.At(199, 0).RGBA()
+		Expect(t, r, Be(uint32(0xffff)))
+	})
+
+	t.Run("Canonical params", func(t *testing.T) {
+		a, _ := ParseTransform("w=100&format=jpg")
+		b, _ := NewTransform("", 0, 100, 0, "jpeg", 75)
+		Expect(t, a.String(), Be(b.String()))
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		_ = png.Encode(buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+
+		// IHDR claims 100000x100000
+		data := buf.Bytes()
+		binary.BigEndian.PutUint32(data[16:], 100000)
+		binary.BigEndian.PutUint32(data[20:], 100000)
+		binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+		tf, _ := ParseTransform("w=100")
+		err := tf.Transform(context.Background(), bytes.NewReader(data), io.Discard)
+		Expect(t, errors.Is(err, ErrImageTooLarge), Be(true))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, s := range []string{"rotate=45", "crop=0,0,0,0", "format=gif", "quality=101", "format=webp"} {
+			_, err := ParseTransform(s)
+			Expect(t, err, Not(Be[error](nil)))
+		}
+	})
+}
+
+func TestClip(t *testing.T) {
+	c := config.DefaultConfig
+	c.Storage.Root = t.TempDir()
+
+	s, err := storage.New(c)
+	Expect(t, err, Be[error](nil))
+	defer func() {
+		_ = s.Shutdown(context.Background())
+	}()
+
+	// keyframe every 2s
+	RegisterClipper("video/x-test", func(ctx context.Context, filename string, w io.WriteSeeker, start time.Duration, end time.Duration) (time.Duration, time.Duration, error) {
+		_, err := io.WriteString(w, "clip")
+		return start / (2 * time.Second) * (2 * time.Second), end, err
+	})
+
+	from := types.Now().Add(-time.Hour)
+
+	w, err := s.Writer(context.Background(), blob.WithFromThough(from, from.Add(10*time.Minute)))
+	Expect(t, err, Be[error](nil))
+	_, _ = io.WriteString(w, "video")
+	err = w.Commit(context.Background(), 0, "", blob.WithLabels(blob.Labels{"_media_type": {"video/x-test"}}))
+	Expect(t, err, Be[error](nil))
+	source := w.Info()
+
+	t.Run("Derive clip", func(t *testing.T) {
+		start, _ := ParseOffset("61")
+		end, _ := ParseOffset("1m15s")
+
+		derived, err := Derive(context.Background(), s, source.Ref, &Clip{Start: start, End: end})
+		Expect(t, err, Be[error](nil))
+		Expect(t, derived.Labels[blob.LabelDerivedFrom], Equal([]string{source.ExternalKey("")}))
+		Expect(t, derived.From.Unix(), Be(from.Add(60*time.Second).Unix()))
+		Expect(t, derived.Through.Unix(), Be(from.Add(75*time.Second).Unix()))
+	})
+
+	t.Run("Render into file", func(t *testing.T) {
+		f, mediaType, err := (&Clip{Start: 0, End: 5 * time.Second}).RenderFile(context.Background(), s, &source)
+		Expect(t, err, Be[error](nil))
+		defer remove(f)
+
+		Expect(t, mediaType, Be("video/x-test"))
+		data, _ := io.ReadAll(f)
+		Expect(t, string(data), Be("clip"))
+	})
+
+	t.Run("Invalid window", func(t *testing.T) {
+		_, err := Derive(context.Background(), s, source.Ref, &Clip{Start: 10 * time.Second, End: 5 * time.Second})
+		Expect(t, err, Not(Be[error](nil)))
+	})
+}
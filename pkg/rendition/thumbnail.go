@@ -0,0 +1,106 @@
+package rendition
+
+import (
+	"context"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/mime"
+	"golang.org/x/image/draw"
+)
+
+const (
+	DefaultThumbnailWidth = 320
+	MaxThumbnailSize      = 1920
+)
+
+// Thumbnail scales image or poster frame of video into JPEG, which fits in Width x Height.
+// When one of Width and Height is 0, it is computed by aspect ratio of source.
+type Thumbnail struct {
+	Width  int
+	Height int
+}
+
+func (t *Thumbnail) Kind() string {
+	return "thumbnail"
+}
+
+func (t *Thumbnail) Params() url.Values {
+	w, h := t.normalize()
+	return url.Values{
+		"w": {strconv.Itoa(w)},
+		"h": {strconv.Itoa(h)},
+	}
+}
+
+func (t *Thumbnail) normalize() (w int, h int) {
+	w, h = clamp(t.Width), clamp(t.Height)
+	if w == 0 && h == 0 {
+		w = DefaultThumbnailWidth
+	}
+	return
+}
+
+func (t *Thumbnail) Render(ctx context.Context, s storage.Store, source *blob.Info, w io.Writer) (string, error) {
+	img, err := DecodeFrame(ctx, s, source)
+	if err != nil {
+		return "", err
+	}
+
+	width, height := t.normalize()
+
+	return mime.MediaTypeImageJPEG, jpeg.Encode(w, Scale(img, width, height), &jpeg.Options{Quality: 80})
+}
+
+// Scale scales img to fit in width x height with aspect ratio kept, never upscales.
+func Scale(img image.Image, width int, height int) image.Image {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw == 0 || sh == 0 {
+		return img
+	}
+
+	if width == 0 || width > sw {
+		width = sw
+	}
+	if height == 0 || height > sh {
+		height = sh
+	}
+
+	// fit in box
+	if sw*height > sh*width {
+		height = max(1, sh*width/sw)
+	} else {
+		width = max(1, sw*height/sh)
+	}
+
+	if width == sw && height == sh {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+func clamp(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > MaxThumbnailSize {
+		return MaxThumbnailSize
+	}
+	return v
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,260 @@
+package rendition
+
+import (
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/mime"
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+var (
+	ErrInvalidTransform = errors.New("invalid transform")
+)
+
+// Transform of image, applied in order of crop, rotate, resize, then encoded in format
+type Transform struct {
+	// Crop rectangle in pixels of source, no crop when empty
+	Crop image.Rectangle
+	// Rotate clockwise in degrees, one of 0, 90, 180, 270
+	Rotate int
+	// Width and Height to fit in, see Scale
+	Width  int
+	Height int
+	// Format jpeg (default) or png
+	Format string
+	// Quality of jpeg, 1-100
+	Quality int
+}
+
+// ParseTransform parses transform from query string like crop=0,0,100,100&rotate=90&w=320&h=0&format=jpeg&quality=80
+func ParseTransform(s string) (*Transform, error) {
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, err.Error())
+	}
+
+	atoi := func(key string) (int, error) {
+		v := values.Get(key)
+		if v == "" {
+			return 0, nil
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, key+" should be integer")
+		}
+		return i, nil
+	}
+
+	ints := make([]int, 4)
+	for i, key := range []string{"rotate", "w", "h", "quality"} {
+		v, err := atoi(key)
+		if err != nil {
+			return nil, err
+		}
+		ints[i] = v
+	}
+
+	return NewTransform(values.Get("crop"), ints[0], ints[1], ints[2], values.Get("format"), ints[3])
+}
+
+// NewTransform creates Transform, crop in form of x,y,w,h
+func NewTransform(crop string, rotate int, width int, height int, format string, quality int) (*Transform, error) {
+	t := &Transform{
+		Rotate:  rotate,
+		Width:   clamp(width),
+		Height:  clamp(height),
+		Format:  strings.ToLower(format),
+		Quality: quality,
+	}
+
+	if crop != "" {
+		parts := strings.Split(crop, ",")
+		if len(parts) != 4 {
+			return nil, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, "crop should be x,y,w,h")
+		}
+		v := make([]int, 4)
+		for i := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(parts[i]))
+			if err != nil || n < 0 {
+				return nil, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, "crop should be x,y,w,h")
+			}
+			v[i] = n
+		}
+		t.Crop = image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3])
+		if t.Crop.Empty() {
+			return nil, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, "crop should not be empty")
+		}
+	}
+
+	switch t.Rotate {
+	case 0, 90, 180, 270:
+	default:
+		return nil, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, "rotate should be 0, 90, 180 or 270")
+	}
+
+	switch t.Format {
+	case "", "jpg":
+		t.Format = FormatJPEG
+	case FormatJPEG, FormatPNG:
+	default:
+		return nil, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, "unsupported format "+t.Format)
+	}
+
+	if t.Format == FormatJPEG {
+		if t.Quality == 0 {
+			t.Quality = jpeg.DefaultQuality
+		}
+		if t.Quality < 1 || t.Quality > 100 {
+			return nil, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, "quality should be in 1-100")
+		}
+	} else {
+		t.Quality = 0
+	}
+
+	return t, nil
+}
+
+// String returns canonical params, same transform same string
+func (t *Transform) String() string {
+	values := url.Values{}
+	if !t.Crop.Empty() {
+		values.Set("crop", strings.Join([]string{
+			strconv.Itoa(t.Crop.Min.X),
+			strconv.Itoa(t.Crop.Min.Y),
+			strconv.Itoa(t.Crop.Dx()),
+			strconv.Itoa(t.Crop.Dy()),
+		}, ","))
+	}
+	if t.Rotate != 0 {
+		values.Set("rotate", strconv.Itoa(t.Rotate))
+	}
+	if t.Width != 0 {
+		values.Set("w", strconv.Itoa(t.Width))
+	}
+	if t.Height != 0 {
+		values.Set("h", strconv.Itoa(t.Height))
+	}
+	values.Set("format", t.Format)
+	if t.Quality != 0 {
+		values.Set("quality", strconv.Itoa(t.Quality))
+	}
+	return values.Encode()
+}
+
+func (t *Transform) MediaType() string {
+	if t.Format == FormatPNG {
+		return "image/png"
+	}
+	return mime.MediaTypeImageJPEG
+}
+
+func (t *Transform) Ext() string {
+	if t.Format == FormatPNG {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// Transformable returns true for images
+func (t *Transform) Transformable(info blob.Info) bool {
+	return strings.HasPrefix(mediaTypeOf(&info), "image/")
+}
+
+// Transform decodes image from r, and writes transformed into w
+func (t *Transform) Transform(ctx context.Context, r io.Reader, w io.Writer) error {
+	img, err := decodeImage(r)
+	if err != nil {
+		return err
+	}
+	img, err = t.Apply(img)
+	if err != nil {
+		return err
+	}
+	return t.Encode(w, img)
+}
+
+func (t *Transform) Apply(img image.Image) (image.Image, error) {
+	if !t.Crop.Empty() {
+		rect := t.Crop.Add(img.Bounds().Min).Intersect(img.Bounds())
+		if rect.Empty() {
+			return nil, statuserr.Wrap(http.StatusBadRequest, ErrInvalidTransform, "crop out of image")
+		}
+		img = cropped(img, rect)
+	}
+
+	if t.Rotate != 0 {
+		img = rotate(img, t.Rotate)
+	}
+
+	if t.Width != 0 || t.Height != 0 {
+		img = Scale(img, t.Width, t.Height)
+	}
+
+	return img, nil
+}
+
+func (t *Transform) Encode(w io.Writer, img image.Image) error {
+	if t.Format == FormatPNG {
+		return png.Encode(w, img)
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: t.Quality})
+}
+
+func cropped(img image.Image, rect image.Rectangle) image.Image {
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			dst.Set(x, y, img.At(rect.Min.X+x, rect.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// rotate clockwise
+func rotate(img image.Image, degrees int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	var dst *image.RGBA
+	if degrees == 180 {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+			switch degrees {
+			case 90:
+				dst.Set(h-1-y, x, c)
+			case 180:
+				dst.Set(w-1-x, h-1-y, c)
+			case 270:
+				dst.Set(y, w-1-x, c)
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,415 @@
+package rtspserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pkg/errors"
+)
+
+const (
+	payloadType = 96
+	clockRate   = 90000
+	mtu         = 1400
+	// control of the only track
+	trackControl = "trackID=0"
+)
+
+type conn struct {
+	s   *Server
+	l   logr.Logger
+	c   net.Conn
+	br  *bufio.Reader
+	wmu sync.Mutex
+
+	session *session
+	playing sync.WaitGroup
+}
+
+// session of conn, one stream per session
+type session struct {
+	id     string
+	path   string
+	stream Stream
+
+	sps     []byte
+	pps     []byte
+	pending []Frame
+
+	channel uint8
+	playing bool
+
+	closeOnce sync.Once
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		_ = s.stream.Close()
+	})
+}
+
+func (c *conn) serve(ctx context.Context) {
+	defer c.close()
+
+	for {
+		req, err := c.readRequest()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.l.V(1).Info(fmt.Sprintf("read request failed: %s", err))
+			}
+			return
+		}
+
+		resp := c.handle(ctx, req)
+
+		if err := c.writeResponse(req, resp); err != nil {
+			return
+		}
+
+		if resp.StatusCode != 200 {
+			continue
+		}
+
+		switch req.Method {
+		case "PLAY":
+			c.play()
+		case "TEARDOWN":
+			return
+		}
+	}
+}
+
+func (c *conn) close() {
+	_ = c.c.Close()
+	if c.session != nil {
+		c.session.close()
+	}
+	c.playing.Wait()
+}
+
+func (c *conn) handle(ctx context.Context, req *request) *response {
+	switch req.Method {
+	case "OPTIONS":
+		return &response{
+			StatusCode: 200,
+			Header: textproto.MIMEHeader{
+				"Public": {"OPTIONS, DESCRIBE, SETUP, PLAY, TEARDOWN, GET_PARAMETER, SET_PARAMETER"},
+			},
+		}
+	case "DESCRIBE":
+		if resp := c.open(ctx, req.URL); resp != nil {
+			return resp
+		}
+		base := *req.URL
+		base.Path = "/" + c.session.path + "/"
+		base.RawQuery = ""
+		return &response{
+			StatusCode: 200,
+			Header: textproto.MIMEHeader{
+				"Content-Type": {"application/sdp"},
+				"Content-Base": {base.String()},
+			},
+			Body: c.session.sdp(),
+		}
+	case "SETUP":
+		transport := req.Header.Get("Transport")
+		if !strings.Contains(transport, "RTP/AVP/TCP") {
+			// clients should retry with tcp
+			return &response{StatusCode: 461}
+		}
+		// session id given by first SETUP
+		if c.session != nil && req.Header.Get("Session") != "" {
+			if resp := c.checkSession(req); resp != nil {
+				return resp
+			}
+		}
+		if resp := c.open(ctx, req.URL); resp != nil {
+			return resp
+		}
+		if c.session.playing {
+			return &response{StatusCode: 455}
+		}
+
+		c.session.channel = interleavedChannel(transport)
+
+		return &response{
+			StatusCode: 200,
+			Header: textproto.MIMEHeader{
+				"Transport": {fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", c.session.channel, c.session.channel+1)},
+				"Session":   {c.sessionHeader()},
+			},
+		}
+	case "PLAY":
+		if resp := c.checkSession(req); resp != nil {
+			return resp
+		}
+		return &response{
+			StatusCode: 200,
+			Header: textproto.MIMEHeader{
+				"Session": {c.sessionHeader()},
+				"Range":   {"npt=0.000-"},
+			},
+		}
+	case "TEARDOWN":
+		if resp := c.checkSession(req); resp != nil {
+			return resp
+		}
+		return &response{StatusCode: 200}
+	case "GET_PARAMETER", "SET_PARAMETER":
+		// keepalive
+		if c.session != nil {
+			return &response{StatusCode: 200, Header: textproto.MIMEHeader{"Session": {c.sessionHeader()}}}
+		}
+		return &response{StatusCode: 200}
+	}
+	return &response{StatusCode: 501}
+}
+
+func (c *conn) checkSession(req *request) *response {
+	if c.session == nil {
+		return &response{StatusCode: 454}
+	}
+	if id, _, _ := strings.Cut(req.Header.Get("Session"), ";"); strings.TrimSpace(id) != c.session.id {
+		return &response{StatusCode: 454}
+	}
+	return nil
+}
+
+func (c *conn) sessionHeader() string {
+	return fmt.Sprintf("%s;timeout=%d", c.session.id, int(c.s.sessionTimeout().Seconds()))
+}
+
+// open opens stream of url for session, returns response when failed
+func (c *conn) open(ctx context.Context, u *url.URL) *response {
+	path := strings.TrimSuffix(strings.Trim(u.Path, "/"), "/"+trackControl)
+
+	if c.session != nil {
+		if c.session.path == path {
+			return nil
+		}
+		// only one stream in one connection
+		return &response{StatusCode: 455}
+	}
+
+	stream, err := c.s.Source.Open(ctx, path, u.Query(), c.c.RemoteAddr().String())
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return &response{StatusCode: 404}
+		}
+		c.l.Error(err, "open stream failed")
+		return &response{StatusCode: 503}
+	}
+
+	s := &session{
+		id:     newSessionID(),
+		path:   path,
+		stream: stream,
+	}
+
+	if err := s.waitParameterSets(c.s.describeTimeout()); err != nil {
+		s.close()
+		c.l.Error(err, "describe stream failed")
+		return &response{StatusCode: 503}
+	}
+
+	c.session = s
+
+	return nil
+}
+
+type parameterSets struct {
+	sps    []byte
+	pps    []byte
+	frames []Frame
+	err    error
+}
+
+// waitParameterSets reads frames until sps and pps received, frames kept to play.
+func (s *session) waitParameterSets(timeout time.Duration) error {
+	result := make(chan parameterSets, 1)
+
+	go func() {
+		ps := parameterSets{}
+
+		for ps.sps == nil || ps.pps == nil {
+			frame, err := s.stream.Read()
+			if err != nil {
+				ps.err = err
+				break
+			}
+
+			for _, nalu := range splitNALUs(frame.Data) {
+				switch nalu[0] & 0x1f {
+				case 7:
+					ps.sps = nalu
+				case 8:
+					ps.pps = nalu
+				}
+			}
+
+			// frames before parameter sets could not be decoded
+			if ps.sps != nil {
+				ps.frames = append(ps.frames, frame)
+			}
+		}
+
+		result <- ps
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ps := <-result:
+		if ps.err != nil {
+			return ps.err
+		}
+		s.sps, s.pps, s.pending = ps.sps, ps.pps, ps.frames
+		return nil
+	case <-timer.C:
+		return ErrNoParameterSet
+	}
+}
+
+// sdp of h264 track, RFC 6184 section 8.2.1
+func (s *session) sdp() []byte {
+	b := &strings.Builder{}
+
+	b.WriteString("v=0\r\n")
+	b.WriteString("o=- 0 0 IN IP4 0.0.0.0\r\n")
+	_, _ = fmt.Fprintf(b, "s=%s\r\n", s.path)
+	b.WriteString("c=IN IP4 0.0.0.0\r\n")
+	b.WriteString("t=0 0\r\n")
+	b.WriteString("a=control:*\r\n")
+	_, _ = fmt.Fprintf(b, "m=video 0 RTP/AVP %d\r\n", payloadType)
+	_, _ = fmt.Fprintf(b, "a=rtpmap:%d H264/%d\r\n", payloadType, clockRate)
+
+	fmtp := fmt.Sprintf("a=fmtp:%d packetization-mode=1", payloadType)
+	if len(s.sps) >= 4 {
+		fmtp += ";profile-level-id=" + strings.ToUpper(hex.EncodeToString(s.sps[1:4]))
+	}
+	fmtp += ";sprop-parameter-sets=" + base64.StdEncoding.EncodeToString(s.sps) + "," + base64.StdEncoding.EncodeToString(s.pps)
+	b.WriteString(fmtp + "\r\n")
+
+	_, _ = fmt.Fprintf(b, "a=control:%s\r\n", trackControl)
+
+	return []byte(b.String())
+}
+
+func (c *conn) play() {
+	s := c.session
+	if s.playing {
+		return
+	}
+	s.playing = true
+
+	c.playing.Add(1)
+
+	go func() {
+		defer c.playing.Done()
+		// disconnect client when stream broken
+		defer c.c.Close()
+
+		p := rtp.NewPacketizer(mtu, payloadType, randUint32(), &codecs.H264Payloader{}, rtp.NewRandomSequencer(), clockRate)
+
+		pending := s.pending
+		s.pending = nil
+
+		for {
+			var frame Frame
+
+			if len(pending) > 0 {
+				frame, pending = pending[0], pending[1:]
+			} else {
+				f, err := s.stream.Read()
+				if err != nil {
+					c.l.V(1).Info(fmt.Sprintf("stream end: %s", err))
+					return
+				}
+				frame = f
+			}
+
+			for _, pkt := range p.Packetize(frame.Data, frame.Samples) {
+				raw, err := pkt.Marshal()
+				if err != nil {
+					return
+				}
+				if err := c.writeInterleaved(s.channel, raw); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// interleavedChannel parses rtp channel of Transport like `RTP/AVP/TCP;unicast;interleaved=0-1`
+func interleavedChannel(transport string) uint8 {
+	for _, part := range strings.Split(transport, ";") {
+		if part = strings.TrimSpace(part); strings.HasPrefix(part, "interleaved=") {
+			rtpChannel, _, _ := strings.Cut(strings.TrimPrefix(part, "interleaved="), "-")
+			if ch, err := strconv.ParseUint(rtpChannel, 10, 8); err == nil {
+				return uint8(ch)
+			}
+		}
+	}
+	return 0
+}
+
+// splitNALUs splits h264 Annex B stream
+func splitNALUs(data []byte) [][]byte {
+	nalus := make([][]byte, 0)
+
+	start := -1
+	zeros := 0
+
+	for i := 0; i < len(data); i++ {
+		switch {
+		case data[i] == 0:
+			zeros++
+			continue
+		case data[i] == 1 && zeros >= 2:
+			if start >= 0 {
+				end := i - zeros
+				if end > start {
+					nalus = append(nalus, data[start:end])
+				}
+			}
+			start = i + 1
+		}
+		zeros = 0
+	}
+
+	if start >= 0 && start < len(data) {
+		nalus = append(nalus, data[start:])
+	}
+
+	return nalus
+}
+
+func newSessionID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func randUint32() uint32 {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return binary.BigEndian.Uint32(b)
+}
@@ -0,0 +1,279 @@
+package rtspserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNotFound       = errors.New("stream not found")
+	ErrServerClosed   = errors.New("rtsp server closed")
+	ErrNoParameterSet = errors.New("no sps and pps of h264 received")
+)
+
+// Frame is access unit of h264 in Annex B
+type Frame struct {
+	Data []byte
+	// Samples of frame duration in 90kHz clock
+	Samples uint32
+}
+
+// Stream of session, closed when session end
+type Stream interface {
+	Read() (Frame, error)
+	Close() error
+}
+
+// Source opens stream for each session
+type Source interface {
+	// Open returns stream of path, like `<stream id>`, ErrNotFound when not exists
+	Open(ctx context.Context, path string, query url.Values, remoteAddr string) (Stream, error)
+}
+
+// Server serves h264 streams of Source over RTSP, RTP interleaved in TCP only.
+type Server struct {
+	Source Source
+	// DescribeTimeout for waiting sps and pps of stream
+	DescribeTimeout time.Duration
+	// SessionTimeout closes session when no request from client, like GET_PARAMETER for keepalive
+	SessionTimeout time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[*conn]bool
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve accepts connections on l, until Shutdown
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.conns = map[*conn]bool{}
+	s.mu.Unlock()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		cc := &conn{
+			s:  s,
+			l:  logr.FromContextOrDiscard(ctx).WithValues("remote", c.RemoteAddr().String()),
+			c:  c,
+			br: bufio.NewReader(c),
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = c.Close()
+			return ErrServerClosed
+		}
+		s.conns[cc] = true
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, cc)
+				s.mu.Unlock()
+			}()
+			cc.serve(ctx)
+		}()
+	}
+}
+
+// Shutdown stops listener and closes all sessions
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	for c := range s.conns {
+		_ = c.c.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
+func (s *Server) describeTimeout() time.Duration {
+	if s.DescribeTimeout > 0 {
+		return s.DescribeTimeout
+	}
+	return 10 * time.Second
+}
+
+func (s *Server) sessionTimeout() time.Duration {
+	if s.SessionTimeout > 0 {
+		return s.SessionTimeout
+	}
+	return 60 * time.Second
+}
+
+type request struct {
+	Method string
+	URL    *url.URL
+	Header textproto.MIMEHeader
+}
+
+type response struct {
+	StatusCode int
+	Header     textproto.MIMEHeader
+	Body       []byte
+}
+
+var statusText = map[int]string{
+	200: "OK",
+	400: "Bad Request",
+	404: "Not Found",
+	454: "Session Not Found",
+	455: "Method Not Valid in This State",
+	461: "Unsupported Transport",
+	500: "Internal Server Error",
+	501: "Not Implemented",
+	503: "Service Unavailable",
+}
+
+// readRequest reads next request, interleaved frames from client like RTCP receiver reports skipped.
+func (c *conn) readRequest() (*request, error) {
+	for {
+		_ = c.c.SetReadDeadline(time.Now().Add(c.s.sessionTimeout()))
+
+		b, err := c.br.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != '$' {
+			break
+		}
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(c.br, header); err != nil {
+			return nil, err
+		}
+		if _, err := c.br.Discard(int(header[2])<<8 | int(header[3])); err != nil {
+			return nil, err
+		}
+	}
+
+	tp := textproto.NewReader(c.br)
+
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "RTSP/") {
+		return nil, errors.Errorf("invalid request line %q", line)
+	}
+
+	u, err := url.Parse(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	// body not used
+	if n, _ := strconv.Atoi(header.Get("Content-Length")); n > 0 {
+		if _, err := c.br.Discard(n); err != nil {
+			return nil, err
+		}
+	}
+
+	return &request{Method: parts[0], URL: u, Header: header}, nil
+}
+
+func (c *conn) writeResponse(req *request, resp *response) error {
+	b := &strings.Builder{}
+
+	_, _ = fmt.Fprintf(b, "RTSP/1.0 %d %s\r\n", resp.StatusCode, statusText[resp.StatusCode])
+	_, _ = fmt.Fprintf(b, "CSeq: %s\r\n", req.Header.Get("CSeq"))
+	_, _ = fmt.Fprintf(b, "Server: mtk\r\n")
+
+	for k, values := range resp.Header {
+		for _, v := range values {
+			_, _ = fmt.Fprintf(b, "%s: %s\r\n", k, v)
+		}
+	}
+
+	if len(resp.Body) > 0 {
+		_, _ = fmt.Fprintf(b, "Content-Length: %d\r\n", len(resp.Body))
+	}
+
+	b.WriteString("\r\n")
+	b.Write(resp.Body)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	_, err := io.WriteString(c.c, b.String())
+	return err
+}
+
+// writeInterleaved writes rtp packet in channel, RFC 2326 section 10.12
+func (c *conn) writeInterleaved(channel uint8, data []byte) error {
+	b := make([]byte, 4+len(data))
+	b[0] = '$'
+	b[1] = channel
+	b[2] = byte(len(data) >> 8)
+	b[3] = byte(len(data))
+	copy(b[4:], data)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	_ = c.c.SetWriteDeadline(time.Now().Add(c.s.sessionTimeout()))
+	_, err := c.c.Write(b)
+	return err
+}
@@ -0,0 +1,208 @@
+package rtspserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+
+	. "github.com/octohelm/x/testing"
+)
+
+var (
+	sps = []byte{0x67, 0x42, 0xc0, 0x1f, 0x8c}
+	pps = []byte{0x68, 0xce, 0x3c, 0x80}
+	idr = []byte{0x65, 0x88, 0x84}
+	p   = []byte{0x41, 0x9a, 0x02}
+)
+
+func annexB(nalus ...[]byte) []byte {
+	b := make([]byte, 0)
+	for _, nalu := range nalus {
+		b = append(b, 0, 0, 0, 1)
+		b = append(b, nalu...)
+	}
+	return b
+}
+
+type fakeStream struct {
+	n       int
+	closed  chan struct{}
+	onClose func()
+}
+
+func (s *fakeStream) Read() (Frame, error) {
+	s.n++
+	select {
+	case <-s.closed:
+		return Frame{}, io.EOF
+	case <-time.After(time.Millisecond):
+	}
+	if s.n == 1 {
+		// could not decode
+		return Frame{Data: annexB(p), Samples: 3000}, nil
+	}
+	if s.n == 2 {
+		return Frame{Data: annexB(sps, pps, idr), Samples: 3000}, nil
+	}
+	return Frame{Data: annexB(p), Samples: 3000}, nil
+}
+
+func (s *fakeStream) Close() error {
+	close(s.closed)
+	s.onClose()
+	return nil
+}
+
+type fakeSource struct {
+	opened int64
+	closed int64
+}
+
+func (f *fakeSource) Open(ctx context.Context, path string, query url.Values, remoteAddr string) (Stream, error) {
+	if path != "1" {
+		return nil, ErrNotFound
+	}
+	atomic.AddInt64(&f.opened, 1)
+	return &fakeStream{
+		closed: make(chan struct{}),
+		onClose: func() {
+			atomic.AddInt64(&f.closed, 1)
+		},
+	}, nil
+}
+
+type client struct {
+	c    net.Conn
+	br   *bufio.Reader
+	cseq int
+}
+
+func (c *client) do(method string, u string, header ...string) (int, textproto.MIMEHeader, string) {
+	c.cseq++
+	_, _ = fmt.Fprintf(c.c, "%s %s RTSP/1.0\r\nCSeq: %d\r\n%s\r\n", method, u, c.cseq, strings.Join(append(header, ""), "\r\n"))
+
+	tp := textproto.NewReader(c.br)
+	line, _ := tp.ReadLine()
+	h, _ := tp.ReadMIMEHeader()
+	code, _ := strconv.Atoi(strings.Split(line, " ")[1])
+
+	body := make([]byte, 0)
+	if n, _ := strconv.Atoi(h.Get("Content-Length")); n > 0 {
+		body = make([]byte, n)
+		_, _ = io.ReadFull(c.br, body)
+	}
+
+	return code, h, string(body)
+}
+
+func (c *client) readPacket() (uint8, *rtp.Packet, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.br, header); err != nil {
+		return 0, nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(header[2:]))
+	if _, err := io.ReadFull(c.br, data); err != nil {
+		return 0, nil, err
+	}
+	pkt := &rtp.Packet{}
+	return header[1], pkt, pkt.Unmarshal(data)
+}
+
+func TestServer(t *testing.T) {
+	source := &fakeSource{}
+	s := &Server{Source: source}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(t, err, Be[error](nil))
+
+	go func() {
+		_ = s.Serve(context.Background(), l)
+	}()
+
+	dial := func(t *testing.T) *client {
+		c, err := net.Dial("tcp", l.Addr().String())
+		Expect(t, err, Be[error](nil))
+		return &client{c: c, br: bufio.NewReader(c)}
+	}
+
+	base := "rtsp://" + l.Addr().String()
+
+	t.Run("Not found", func(t *testing.T) {
+		c := dial(t)
+		defer c.c.Close()
+
+		code, _, _ := c.do("DESCRIBE", base+"/2")
+		Expect(t, code, Be(404))
+	})
+
+	t.Run("Play", func(t *testing.T) {
+		c := dial(t)
+		defer c.c.Close()
+
+		code, h, _ := c.do("OPTIONS", base+"/1")
+		Expect(t, code, Be(200))
+		Expect(t, strings.Contains(h.Get("Public"), "PLAY"), Be(true))
+
+		code, h, sdp := c.do("DESCRIBE", base+"/1?preset=720P")
+		Expect(t, code, Be(200))
+		Expect(t, h.Get("Content-Base"), Be(base+"/1/"))
+		Expect(t, strings.Contains(sdp, "profile-level-id=42C01F"), Be(true))
+		Expect(t, strings.Contains(sdp, "sprop-parameter-sets=Z0LAH4w=,aM48gA=="), Be(true))
+
+		code, _, _ = c.do("SETUP", base+"/1/trackID=0", "Transport: RTP/AVP;unicast;client_port=5000-5001")
+		Expect(t, code, Be(461))
+
+		code, h, _ = c.do("SETUP", base+"/1/trackID=0", "Transport: RTP/AVP/TCP;unicast;interleaved=2-3")
+		Expect(t, code, Be(200))
+		Expect(t, h.Get("Transport"), Be("RTP/AVP/TCP;unicast;interleaved=2-3"))
+
+		session, _, _ := strings.Cut(h.Get("Session"), ";")
+
+		code, _, _ = c.do("PLAY", base+"/1/", "Session: unknown")
+		Expect(t, code, Be(454))
+
+		code, _, _ = c.do("PLAY", base+"/1/", "Session: "+session)
+		Expect(t, code, Be(200))
+
+		channel, pkt, err := c.readPacket()
+		Expect(t, err, Be[error](nil))
+		Expect(t, channel, Be[uint8](2))
+		Expect(t, pkt.PayloadType, Be[uint8](96))
+		// sps and pps aggregated in STAP-A
+		Expect(t, pkt.Payload[0]&0x1f, Be[uint8](24))
+
+		_, keyFrame, err := c.readPacket()
+		Expect(t, err, Be[error](nil))
+		Expect(t, keyFrame.Payload[0]&0x1f, Be[uint8](5))
+		Expect(t, keyFrame.Timestamp, Be(pkt.Timestamp))
+		Expect(t, keyFrame.Marker, Be(true))
+
+		_, next, err := c.readPacket()
+		Expect(t, err, Be[error](nil))
+		Expect(t, next.Payload[0]&0x1f, Be[uint8](1))
+		Expect(t, next.Timestamp-pkt.Timestamp, Be[uint32](3000))
+		Expect(t, next.SequenceNumber-pkt.SequenceNumber, Be[uint16](2))
+	})
+
+	t.Run("Session closed with connection", func(t *testing.T) {
+		Expect(t, s.Shutdown(context.Background()), Be[error](nil))
+		Expect(t, atomic.LoadInt64(&source.closed), Be(atomic.LoadInt64(&source.opened)))
+	})
+}
+
+func TestSplitNALUs(t *testing.T) {
+	nalus := splitNALUs(append([]byte{0, 0, 1}, annexB(sps, pps)...))
+	Expect(t, nalus, Equal([][]byte{sps, pps}))
+}
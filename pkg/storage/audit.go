@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label/index"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+)
+
+const auditTableName = "audit"
+
+type Auditor interface {
+	// Audit appends entry to audit log, actor from ctx when not set.
+	Audit(ctx context.Context, e AuditEntry) error
+	// QueryAudit returns audit entries in time range, ordered by time.
+	QueryAudit(ctx context.Context, timeRange blob.TimeRange) ([]AuditEntry, error)
+}
+
+type AuditEntry struct {
+	Time   types.Time        `json:"time"`
+	Actor  Actor             `json:"actor"`
+	Action string            `json:"action"`
+	Ref    *blob.RefString   `json:"ref,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// auditLog is an append-only log in its own table,
+// entries of same day share one hash value, and ordered by time.
+type auditLog struct {
+	indexClient index.Client
+	// instance makes keys unique, since seq restarts with process
+	instance string
+	seq      uint32
+}
+
+func newAuditLog(indexClient index.Client) (*auditLog, error) {
+	instance := make([]byte, 8)
+	if _, err := rand.Read(instance); err != nil {
+		return nil, err
+	}
+	return &auditLog{indexClient: indexClient, instance: hex.EncodeToString(instance)}, nil
+}
+
+func (l *auditLog) hashValue(day int64) string {
+	return fmt.Sprintf("audit:%d", day)
+}
+
+func (l *auditLog) Append(ctx context.Context, e AuditEntry) error {
+	if e.Time == 0 {
+		e.Time = types.Now()
+	}
+
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// seq keeps entries in same time
+	rangeValue := []byte(fmt.Sprintf("%016x%08x%s", e.Time.Time().UnixNano(), atomic.AddUint32(&l.seq, 1), l.instance))
+
+	batch := l.indexClient.NewWriteBatch()
+	batch.Add(index.Entry{
+		TableName:  auditTableName,
+		HashValue:  l.hashValue(blob.UnixDay(e.Time)),
+		RangeValue: rangeValue,
+		Value:      value,
+	})
+
+	return l.indexClient.BatchWrite(ctx, batch)
+}
+
+// Query returns entries in time range, ordered by time
+func (l *auditLog) Query(ctx context.Context, timeRange blob.TimeRange) ([]AuditEntry, error) {
+	entries := make([]AuditEntry, 0)
+
+	for day := blob.UnixDay(timeRange.From); day <= blob.UnixDay(timeRange.Through); day++ {
+		q := index.Query{
+			TableName: auditTableName,
+			HashValue: l.hashValue(day),
+		}
+
+		err := l.indexClient.QueryPages(ctx, []index.Query{q}, func(result index.ReadBatchResult, query index.Query) error {
+			for iter := result.Iterator(); iter.Next(); {
+				e := AuditEntry{}
+				if err := json.Unmarshal(iter.Entry().Value, &e); err != nil {
+					return err
+				}
+				if e.Time < timeRange.From || e.Time > timeRange.Through {
+					continue
+				}
+				entries = append(entries, e)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *store) Audit(ctx context.Context, e AuditEntry) error {
+	if e.Actor.Name == "" {
+		e.Actor = ActorFromContext(ctx)
+	}
+	return s.auditLog.Append(ctx, e)
+}
+
+func (s *store) QueryAudit(ctx context.Context, timeRange blob.TimeRange) ([]AuditEntry, error) {
+	return s.auditLog.Query(ctx, timeRange)
+}
+
+// audit appends entry of actor in ctx
+func (s *store) audit(ctx context.Context, action string, ref *blob.Ref, params map[string]string) error {
+	e := AuditEntry{
+		Time:   types.Now(),
+		Action: action,
+		Params: params,
+	}
+	if ref != nil {
+		r := blob.RefString(*ref)
+		e.Ref = &r
+	}
+	return s.Audit(ctx, e)
+}
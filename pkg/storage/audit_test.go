@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	. "github.com/octohelm/x/testing"
+)
+
+func TestStoreAudit(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	ctx := NewContextWithActor(context.Background(), Actor{Name: "bob"})
+	ref := blob.FromString("x").Ref
+
+	for _, action := range []string{"LabelBlob", "DeleteBlob"} {
+		r := blob.RefString(ref)
+		err := s.Audit(ctx, AuditEntry{Action: action, Ref: &r, Params: map[string]string{"status": "204"}})
+		Expect(t, err, Be[error](nil))
+	}
+
+	t.Run("Query in time range", func(t *testing.T) {
+		entries, err := s.QueryAudit(context.Background(), blob.Last(time.Hour))
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(entries), Be(2))
+		Expect(t, entries[0].Action, Be("LabelBlob"))
+		Expect(t, entries[0].Actor.Name, Be("bob"))
+		Expect(t, entries[1].Action, Be("DeleteBlob"))
+		Expect(t, entries[1].Ref.Ref().Hex, Be(ref.Hex))
+	})
+
+	t.Run("Query out of time range", func(t *testing.T) {
+		entries, err := s.QueryAudit(context.Background(), blob.LastFrom(types.Now().Add(-24*time.Hour), time.Hour))
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(entries), Be(0))
+	})
+
+	t.Run("Entry without ref", func(t *testing.T) {
+		Expect(t, s.Audit(ctx, AuditEntry{Action: "TakePic"}), Be[error](nil))
+
+		entries, err := s.QueryAudit(context.Background(), blob.Last(time.Hour))
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(entries), Be(3))
+		Expect(t, entries[2].Ref == nil, Be(true))
+	})
+
+	t.Run("Entries in same time kept after reopen", func(t *testing.T) {
+		c := newTestConfig(t)
+		at := types.Now()
+
+		for i := 0; i < 2; i++ {
+			s, err := New(c)
+			Expect(t, err, Be[error](nil))
+			Expect(t, s.Audit(ctx, AuditEntry{Time: at, Action: "TakePic"}), Be[error](nil))
+			_ = s.Shutdown(context.Background())
+		}
+
+		s, err := New(c)
+		Expect(t, err, Be[error](nil))
+		defer func() {
+			_ = s.Shutdown(context.Background())
+		}()
+
+		entries, err := s.QueryAudit(context.Background(), blob.Last(time.Hour))
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(entries), Be(2))
+	})
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	contentlocal "github.com/innoai-tech/media-toolkit/pkg/storage/content/local"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+)
+
+type CheckReport struct {
+	// OrphanContents blob paths of content without any index entry
+	OrphanContents []string `json:"orphanContents"`
+	// DanglingRefs refs indexed without content
+	DanglingRefs []blob.RefString `json:"danglingRefs"`
+	// OrphanObjects shared objects without any ref
+	OrphanObjects []string `json:"orphanObjects"`
+	// DanglingObjectRefs ref entries of shared objects without object
+	DanglingObjectRefs []string `json:"danglingObjectRefs"`
+	// UnlinkedObjectRefs refs of shared objects which blob path missing
+	UnlinkedObjectRefs []string `json:"unlinkedObjectRefs"`
+	Repaired           bool     `json:"repaired"`
+}
+
+// objectsChecker of local content store
+type objectsChecker interface {
+	CheckObjects(ctx context.Context, repair bool) (*contentlocal.ObjectsReport, error)
+	Collect(ctx context.Context, blobPath string) error
+}
+
+// Check checks consistency between local content and label index of blobs of default user, day by day in time range,
+// all time when time range is zero. Shared objects and their refs are always checked.
+// When repair, orphan contents and objects will be moved into lost+found, unlinked refs of objects linked again,
+// and dangling refs will be marked as deleted.
+// Contents of pending intents are not orphan, which labels indexed when intents replayed.
+// Commits wait while checking objects and each day.
+func (s *store) Check(ctx context.Context, timeRange blob.TimeRange, repair bool) (*CheckReport, error) {
+	report := &CheckReport{
+		OrphanContents:     []string{},
+		DanglingRefs:       []blob.RefString{},
+		OrphanObjects:      []string{},
+		DanglingObjectRefs: []string{},
+		UnlinkedObjectRefs: []string{},
+		Repaired:           repair,
+	}
+
+	// objects checked first, blob paths relinked before checking days
+	if err := s.checkObjects(ctx, repair, report); err != nil {
+		return nil, err
+	}
+
+	if timeRange.From == 0 && timeRange.Through == 0 {
+		if len(s.c.Schema.Configs) == 0 {
+			return report, nil
+		}
+		timeRange = blob.TimeRange{From: s.c.Schema.Configs[0].From, Through: types.Now()}
+	}
+
+	for day := blob.UnixDay(timeRange.From); day <= blob.UnixDay(timeRange.Through); day++ {
+		if err := s.checkDay(ctx, day, repair, report); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func (s *store) checkObjects(ctx context.Context, repair bool, report *CheckReport) error {
+	c, ok := s.localContentStore().(objectsChecker)
+	if !ok {
+		return nil
+	}
+
+	s.commitMu.Lock()
+	defer s.commitMu.Unlock()
+
+	r, err := c.CheckObjects(ctx, repair)
+	if err != nil {
+		return err
+	}
+	report.OrphanObjects = r.OrphanObjects
+	report.DanglingObjectRefs = r.DanglingRefs
+	report.UnlinkedObjectRefs = r.UnlinkedRefs
+	return nil
+}
+
+func (s *store) checkDay(ctx context.Context, day int64, repair bool, report *CheckReport) error {
+	l := logr.FromContextOrDiscard(ctx).WithValues("day", day)
+
+	s.commitMu.Lock()
+	defer s.commitMu.Unlock()
+
+	// pending intents of failed commits only, no commit in progress.
+	pending, err := s.intents.Pending()
+	if err != nil {
+		return err
+	}
+
+	committing := map[string]bool{}
+	for _, info := range pending {
+		committing[info.BlobPath("")] = true
+	}
+
+	dayRange := blob.SinceFrom(types.TimeFromUnix(day*int64(24*time.Hour/time.Second)), 24*time.Hour-time.Millisecond)
+
+	contents, err := s.listContentsOfDay(day)
+	if err != nil {
+		return err
+	}
+
+	indexStore, err := s.labelIndexStoreFor(ctx, dayRange)
+	if err != nil {
+		return err
+	}
+
+	refs, err := indexStore.GetBlobRefs(ctx, dayRange, blob.DefaultUser, label.MetricLabel)
+	if err != nil {
+		return err
+	}
+
+	indexed := map[string]bool{}
+	dayRefs := make([]blob.Ref, 0, len(refs))
+
+	for _, ref := range refs {
+		if blob.UnixDay(ref.From) != day {
+			continue
+		}
+		indexed[ref.BlobPath("")] = true
+		dayRefs = append(dayRefs, ref)
+	}
+
+	for _, p := range contents {
+		if indexed[p] || committing[p] {
+			continue
+		}
+
+		report.OrphanContents = append(report.OrphanContents, p)
+
+		if repair {
+			if err := s.collect(ctx, p); err != nil {
+				return err
+			}
+			l.Info("orphan content moved", "path", p)
+		}
+	}
+
+	// deleted blobs are excluded
+	alive, err := indexStore.RefsToBlobs(ctx, dayRefs, label.MetricLabel)
+	if err != nil {
+		return err
+	}
+
+	existed := map[string]bool{}
+	for _, p := range contents {
+		existed[p] = true
+	}
+
+	for _, b := range alive {
+		// content in object storage or cold tier not checked
+		if existed[b.BlobPath("")] || !s.isLocalContent(b.Ref) || b.Labels[blob.LabelTier] != nil {
+			continue
+		}
+
+		report.DanglingRefs = append(report.DanglingRefs, blob.RefString(b.Ref))
+
+		// blob under legal hold never deleted, even content lost.
+		if repair && errIfHeld(&b) == nil {
+			labelWriter, err := s.labelWriterFor(ctx, b.TimeRange)
+			if err != nil {
+				return err
+			}
+			if err := labelWriter.DelOne(ctx, b.TimeRange, label.MetricLabel, b.Ref); err != nil {
+				return err
+			}
+			l.Info("dangling ref deleted", "ref", b.Ref.ExternalKey(""))
+		}
+	}
+
+	return nil
+}
+
+// collect moves orphan content into lost+found, with its refs of shared object
+func (s *store) collect(ctx context.Context, p string) error {
+	if c, ok := s.localContentStore().(objectsChecker); ok {
+		return c.Collect(ctx, p)
+	}
+
+	target := filepath.Join(s.c.Storage.Root, "lost+found", p)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(s.c.Storage.Root, p), target)
+}
+
+func (s *store) isLocalContent(ref blob.Ref) bool {
+	cs := s.Store
+	if ts, ok := cs.(*tieredContentStore); ok {
+		cs = ts.Store
+	}
+	if ps, ok := cs.(*periodContentStore); ok {
+		return ps.isLocal(ref)
+	}
+	return true
+}
+
+// listContentsOfDay returns blob paths (relative to root) of the day
+func (s *store) listContentsOfDay(day int64) ([]string, error) {
+	dayDir := filepath.Join("blobs", strconv.FormatInt(day, 10))
+
+	algs, err := os.ReadDir(filepath.Join(s.c.Storage.Root, dayDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	contents := make([]string, 0)
+
+	for _, alg := range algs {
+		if !alg.IsDir() {
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(s.c.Storage.Root, dayDir, alg.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			contents = append(contents, filepath.Join(dayDir, alg.Name(), f.Name()))
+		}
+	}
+
+	sort.Strings(contents)
+
+	return contents, nil
+}
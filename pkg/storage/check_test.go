@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
+	. "github.com/octohelm/x/testing"
+)
+
+func TestStoreCheck(t *testing.T) {
+	s, c := newTestStore(t)
+
+	orphan := commitContent(t, s, "orphan")
+
+	dangling := commitContent(t, s, "dangling")
+	w, _ := s.(*store).labelWriterFor(context.Background(), dangling.TimeRange)
+	Expect(t, w.Put(context.Background(), label.MetricLabel, []blob.Info{dangling}), Be[error](nil))
+	Expect(t, os.Remove(dangling.BlobPath(c.Storage.Root)), Be[error](nil))
+
+	orphanObject := filepath.Join("objects", "sha256", blob.FromString("orphan object").Hex)
+	Expect(t, os.MkdirAll(filepath.Join(c.Storage.Root, filepath.Dir(orphanObject)), 0755), Be[error](nil))
+	Expect(t, os.WriteFile(filepath.Join(c.Storage.Root, orphanObject), []byte("orphan object"), 0644), Be[error](nil))
+
+	t.Run("Report", func(t *testing.T) {
+		report, err := s.Check(context.Background(), blob.Last(time.Hour), false)
+		Expect(t, err, Be[error](nil))
+		Expect(t, report.OrphanContents, Equal([]string{orphan.BlobPath("")}))
+		Expect(t, report.DanglingRefs, Equal([]blob.RefString{blob.RefString(dangling.Ref)}))
+		Expect(t, report.OrphanObjects, Equal([]string{orphanObject}))
+		Expect(t, report.UnlinkedObjectRefs, Equal([]string{dangling.BlobPath("")}))
+	})
+
+	t.Run("Repair", func(t *testing.T) {
+		_, err := s.Check(context.Background(), blob.Last(time.Hour), true)
+		Expect(t, err, Be[error](nil))
+
+		repaired, err := s.Check(context.Background(), blob.Last(time.Hour), false)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(repaired.OrphanContents), Be(0))
+		Expect(t, len(repaired.DanglingRefs), Be(0))
+		Expect(t, len(repaired.OrphanObjects), Be(0))
+		Expect(t, len(repaired.UnlinkedObjectRefs), Be(0))
+
+		_, err = os.Stat(filepath.Join(c.Storage.Root, "objects", orphan.Alg, orphan.Hex))
+		Expect(t, os.IsNotExist(err), Be(true))
+	})
+
+	t.Run("Content of pending intent kept", func(t *testing.T) {
+		// like labels indexing failed
+		info := commitContent(t, s, "pending")
+		_, err := s.(*store).intents.Begin(info)
+		Expect(t, err, Be[error](nil))
+
+		report, err := s.Check(context.Background(), blob.Last(time.Hour), true)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.OrphanContents), Be(0))
+
+		_, err = os.Stat(info.BlobPath(c.Storage.Root))
+		Expect(t, err, Be[error](nil))
+	})
+}
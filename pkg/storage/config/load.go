@@ -0,0 +1,38 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// Load loads config from json file, in which fields override DefaultConfig, like
+//
+//	{"streams": [...], "storage": {"root": "/data/mediadb", "tiering": {"interval": 3600000000000}}}
+//
+// durations are in nanoseconds.
+// DefaultConfig returned when file empty, or file is a list of streams only.
+func Load(filename string) (Config, error) {
+	c := DefaultConfig
+	// json decodes into backing array of slice, which should not be shared with DefaultConfig
+	c.Schema.Configs = append([]PeriodConfig{}, DefaultConfig.Schema.Configs...)
+
+	if filename == "" {
+		return c, nil
+	}
+
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return c, err
+	}
+
+	if raw = bytes.TrimSpace(raw); len(raw) == 0 || raw[0] == '[' {
+		return c, nil
+	}
+
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/octohelm/x/testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(t *testing.T, content string) string {
+		filename := filepath.Join(dir, t.Name()[len("TestLoad/"):]+".json")
+		Expect(t, os.WriteFile(filename, []byte(content), 0o644), Be[error](nil))
+		return filename
+	}
+
+	t.Run("List of streams", func(t *testing.T) {
+		c, err := Load(write(t, `[{"id":"1","rtsp":"rtsp://127.0.0.1/1"}]`))
+		Expect(t, err, Be[error](nil))
+		Expect(t, c.Storage.Root, Be(DefaultConfig.Storage.Root))
+	})
+
+	t.Run("Storage overridden", func(t *testing.T) {
+		c, err := Load(write(t, `{"streams":[],"storage":{"root":"/data/mediadb","tiering":{"interval":3600000000000}}}`))
+		Expect(t, err, Be[error](nil))
+		Expect(t, c.Storage.Root, Be("/data/mediadb"))
+		Expect(t, c.Storage.Tiering.Interval, Be(time.Hour))
+		Expect(t, c.Storage.Compactor.After, Be(DefaultConfig.Storage.Compactor.After))
+		Expect(t, len(c.Schema.Configs), Be(1))
+	})
+
+	t.Run("Schema overridden", func(t *testing.T) {
+		c, err := Load(write(t, `{"schema":{"configs":[{"from":"2023-01-01T00:00:00Z","schema":"v1","rowShards":8}]}}`))
+		Expect(t, err, Be[error](nil))
+		Expect(t, c.Schema.Configs[0].RowShards, Be[uint32](8))
+		Expect(t, DefaultConfig.Schema.Configs[0].RowShards, Be[uint32](16))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := Load(write(t, `{"storage":`))
+		Expect(t, err == nil, Be(false))
+	})
+}
@@ -33,7 +33,7 @@ type PeriodConfig struct {
 	IndexTables PeriodicTableConfig
 	Schema      string
 	RowShards   uint32
-	ObjectType  string // type of object client to use; if omitted, defaults to store.
+	ObjectType  string // type of object client to use; if omitted, defaults to local.
 }
 
 type PeriodicTableConfig struct {
@@ -1,11 +1,78 @@
 package config
 
+import "time"
+
 type StorageConfig struct {
 	Root      string
 	Compactor CompactorConfig
+	S3        S3Config
+	Tiering   TieringConfig
+	Quota     QuotaConfig
+	// UploadExpires aborts upload sessions not written in the duration, defaults to 24h
+	UploadExpires time.Duration
 }
 
 type CompactorConfig struct {
 	After        uint
 	DiscardAfter uint
 }
+
+const (
+	ObjectTypeLocal = "local"
+	ObjectTypeS3    = "s3"
+)
+
+// S3Config of S3-compatible object storage, used by periods with ObjectType s3
+type S3Config struct {
+	// Endpoint like https://s3.us-east-1.amazonaws.com or http://127.0.0.1:9000
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix of object keys
+	Prefix string
+	// VirtualHostStyle uses <bucket>.<endpoint host> instead of <endpoint>/<bucket>
+	VirtualHostStyle bool
+	// PresignExpires of presigned download url, redirect disabled when zero
+	PresignExpires time.Duration
+}
+
+// TieringConfig of moving blobs from local disk to cold tier
+type TieringConfig struct {
+	// ObjectType of cold tier, local or s3, tiering disabled when empty
+	ObjectType string
+	// Root of cold tier when local, like a mounted NAS path
+	Root string
+	// After days blobs will be moved, 0 means not moved by age
+	After uint
+	// Filter of blobs will be moved, like {_media_type="video/mp4"}
+	Filter string
+	// Interval of tiering, not scheduled when zero
+	Interval time.Duration
+}
+
+// QuotaConfig of blobs in local disk, counted by size of alive blobs
+type QuotaConfig struct {
+	// MaxBytes of the store, unlimited when 0
+	MaxBytes int64
+	// DeviceMaxBytes of each _device_id, unlimited when 0
+	DeviceMaxBytes int64
+	// Devices overrides DeviceMaxBytes by _device_id
+	Devices map[string]int64
+	// Evict oldest blobs when quota exceeded, otherwise writes rejected
+	Evict bool
+	// RetainLabel blobs with which never evicted, defaults to retain
+	RetainLabel string
+}
+
+func (c QuotaConfig) Enabled() bool {
+	return c.MaxBytes > 0 || c.DeviceMaxBytes > 0 || len(c.Devices) > 0
+}
+
+func (c QuotaConfig) MaxBytesOfDevice(deviceID string) int64 {
+	if max, ok := c.Devices[deviceID]; ok {
+		return max
+	}
+	return c.DeviceMaxBytes
+}
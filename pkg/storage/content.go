@@ -19,13 +19,40 @@ type Provider interface {
 
 type Store interface {
 	content.Store
+	content.Presigner
 	Manager
+	Checker
+	Uploader
+	Tiering
+	Migrator
+	QuotaManager
+	Holder
+	Auditor
 	Shutdown(ctx context.Context) error
 }
 
+type Checker interface {
+	Check(ctx context.Context, timeRange blob.TimeRange, repair bool) (*CheckReport, error)
+}
+
 type Manager interface {
 	Query(ctx context.Context, timeRange blob.TimeRange, userID string, matchers ...*labels.Matcher) ([]blob.Info, error)
 	Info(ctx context.Context, ref blob.Ref) (*blob.Info, error)
+	// LabelNames returns label names ever put in time range,
+	// names are never pruned, so names of deleted labels or blobs may be included.
+	LabelNames(ctx context.Context, timeRange blob.TimeRange, userID string) ([]string, error)
+	LabelValues(ctx context.Context, timeRange blob.TimeRange, userID string, labelName string, matchers ...*labels.Matcher) ([]string, error)
 	PutLabel(ctx context.Context, ref blob.Ref, labelName string, labelValue string) error
 	DeleteLabel(ctx context.Context, ref blob.Ref, labelName string, labelValue string) error
+	BulkLabel(ctx context.Context, refs []blob.Ref, change LabelChange) ([]LabelChangeResult, error)
+}
+
+type LabelChange struct {
+	Put    blob.Labels `json:"put,omitempty"`
+	Delete blob.Labels `json:"delete,omitempty"`
+}
+
+type LabelChangeResult struct {
+	Ref   blob.RefString `json:"ref"`
+	Error string         `json:"error,omitempty"`
 }
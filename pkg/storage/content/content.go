@@ -8,12 +8,18 @@ import (
 
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
 	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
 )
 
 type Store interface {
 	Remover
 	Provider
 	Ingester
+	IngestManager
 }
 
 type Remover interface {
@@ -29,6 +35,24 @@ type Ingester interface {
 	TempFile(ctx context.Context) (*os.File, error)
 }
 
+// IngestManager manages ingests which not committed
+type IngestManager interface {
+	// ResumeWriter reopens writer by Status.Ref, and continue writing from Status.Offset
+	ResumeWriter(ctx context.Context, ref string) (Writer, error)
+	Abort(ctx context.Context, ref string) error
+}
+
+// Importer imports content committed in other store
+type Importer interface {
+	Import(ctx context.Context, info blob.Info, r ReaderAt) error
+}
+
+// Presigner creates url to download content directly,
+// empty url returned when not supported.
+type Presigner interface {
+	PresignedURL(ctx context.Context, ref blob.Ref) (string, error)
+}
+
 type ReaderAt interface {
 	io.ReaderAt
 	io.Closer
@@ -43,7 +67,21 @@ type Writer interface {
 	Truncate(size int64) error
 }
 
+// CompleteInfo applies opts to info and fills defaults, which should be done before commit.
+func CompleteInfo(info *blob.Info, opts ...blob.Opt) {
+	for _, opt := range opts {
+		opt(info)
+	}
+	if info.UserID == "" {
+		info.UserID = blob.DefaultUser
+	}
+	if info.Through == 0 {
+		info.Through = info.From
+	}
+}
+
 type Status struct {
+	Ref       string
 	Offset    int64
 	Total     int64
 	Expected  digest.Digest
@@ -11,7 +11,7 @@ import (
 )
 
 var (
-	ErrNotFound      = errors.New("not found")
+	ErrNotFound      = content.ErrNotFound
 	ErrAlreadyExists = errors.New("not found")
 )
 
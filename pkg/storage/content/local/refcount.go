@@ -0,0 +1,410 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/opencontainers/go-digest"
+)
+
+// Content with same digest shares one file in objects/<alg>/<hex>,
+// and linked (hard link) to blobs/<unix_day>/<alg>/<hex> as the ref need.
+//
+// Each ref of the content holds an entry in refs/<alg>/<hex>/,
+// the content will be removed when the last ref deleted.
+//
+// Object moved into place before ref added and linked, an object left by a crash between them
+// will be restored by replaying commit intent, or collected by CheckObjects as orphan.
+
+func (s *contentStore) objectPath(ref blob.Ref) string {
+	return filepath.Join(s.root, "objects", ref.Alg, ref.Hex)
+}
+
+func (s *contentStore) refsDir(ref blob.Ref) string {
+	return filepath.Join(s.root, "refs", ref.Alg, ref.Hex)
+}
+
+func (s *contentStore) refEntry(ref blob.Ref) string {
+	return filepath.Join(s.refsDir(ref), digest.FromString(ref.ExternalKey("")).Hex())
+}
+
+// place moves committed data as content of ref, data dropped when the content existed.
+func (s *contentStore) place(data string, ref blob.Ref) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object := s.objectPath(ref)
+
+	if _, err := os.Stat(object); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(data, object); err != nil {
+			return err
+		}
+	} else {
+		if err := os.Remove(data); err != nil {
+			return err
+		}
+	}
+
+	if err := s.addRef(ref); err != nil {
+		return err
+	}
+
+	return s.link(ref)
+}
+
+// Retain adds ref to the content at blob path, which committed before refcounted,
+// and the content will be shared if the same content existed.
+func (s *contentStore) Retain(ctx context.Context, ref blob.Ref) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bp := ref.BlobPath(s.root)
+
+	fi, err := os.Stat(bp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("blob not found: %w", ErrNotFound)
+		}
+		return err
+	}
+
+	object := s.objectPath(ref)
+
+	objectInfo, err := os.Stat(object)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+			return err
+		}
+		if err := os.Link(bp, object); err != nil {
+			return err
+		}
+	} else if !os.SameFile(fi, objectInfo) {
+		// replace the copy with link to the shared one
+		tmp := bp + ".link"
+		_ = os.Remove(tmp)
+		if err := os.Link(object, tmp); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, bp); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+
+	return s.addRef(ref)
+}
+
+// Restore finishes placing content of ref, which object moved into place but ref not added or linked by a crash.
+func (s *contentStore) Restore(ctx context.Context, ref blob.Ref) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.objectPath(ref)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("object not found: %w", ErrNotFound)
+		}
+		return err
+	}
+
+	if err := s.addRef(ref); err != nil {
+		return err
+	}
+
+	return s.link(ref)
+}
+
+func (s *contentStore) addRef(ref blob.Ref) error {
+	if err := os.MkdirAll(s.refsDir(ref), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.refEntry(ref), []byte(ref.ExternalKey("")), 0644)
+}
+
+func (s *contentStore) link(ref blob.Ref) error {
+	bp := ref.BlobPath(s.root)
+
+	if _, err := os.Stat(bp); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(bp), 0755); err != nil {
+		return err
+	}
+
+	return os.Link(s.objectPath(ref), bp)
+}
+
+// refs returns refs of the content of ref
+func (s *contentStore) refs(ref blob.Ref) ([]blob.Ref, error) {
+	list, err := os.ReadDir(s.refsDir(ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	refs := make([]blob.Ref, 0, len(list))
+
+	for _, f := range list {
+		data, err := os.ReadFile(filepath.Join(s.refsDir(ref), f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		info, err := blob.ParseExternalKey(string(data), "")
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, info.Ref)
+	}
+
+	return refs, nil
+}
+
+// Delete removes ref of the content,
+// blob path unlinked when no other ref of same day, and the content removed when no ref left.
+func (s *contentStore) Delete(ctx context.Context, ref blob.Ref) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refExisted := true
+
+	if err := os.Remove(s.refEntry(ref)); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		refExisted = false
+	}
+
+	remains, err := s.refs(ref)
+	if err != nil {
+		return err
+	}
+
+	sameDay := false
+	for _, r := range remains {
+		if blob.UnixDay(r.From) == blob.UnixDay(ref.From) {
+			sameDay = true
+			break
+		}
+	}
+
+	if !refExisted && sameDay {
+		return fmt.Errorf("content: %w", ErrNotFound)
+	}
+
+	if !sameDay {
+		// content committed before refcounted removed here too.
+		if err := os.Remove(ref.BlobPath(s.root)); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			if !refExisted {
+				return fmt.Errorf("content: %w", ErrNotFound)
+			}
+		}
+	}
+
+	if len(remains) == 0 {
+		if err := os.Remove(s.objectPath(ref)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		_ = os.Remove(s.refsDir(ref))
+	}
+
+	return nil
+}
+
+// ObjectsReport of shared objects and their refs, paths relative to root
+type ObjectsReport struct {
+	// OrphanObjects objects without any ref, like left by a crash before ref added
+	OrphanObjects []string
+	// DanglingRefs ref entries without object
+	DanglingRefs []string
+	// UnlinkedRefs refs of object which blob path missing
+	UnlinkedRefs []string
+}
+
+// CheckObjects checks objects/ and refs/.
+// When repair, orphan objects will be moved into lost+found, dangling refs removed, and unlinked refs linked again.
+func (s *contentStore) CheckObjects(ctx context.Context, repair bool) (*ObjectsReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &ObjectsReport{
+		OrphanObjects: []string{},
+		DanglingRefs:  []string{},
+		UnlinkedRefs:  []string{},
+	}
+
+	objects, err := s.listDigests("objects", false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range objects {
+		refs, err := s.refs(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(refs) == 0 {
+			report.OrphanObjects = append(report.OrphanObjects, s.rel(s.objectPath(ref)))
+
+			if repair {
+				target := filepath.Join(s.root, "lost+found", s.rel(s.objectPath(ref)))
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return nil, err
+				}
+				if err := os.Rename(s.objectPath(ref), target); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		for _, r := range refs {
+			if _, err := os.Stat(r.BlobPath(s.root)); err == nil || !os.IsNotExist(err) {
+				continue
+			}
+
+			report.UnlinkedRefs = append(report.UnlinkedRefs, s.rel(r.BlobPath(s.root)))
+
+			if repair {
+				if err := s.link(r); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	refsDirs, err := s.listDigests("refs", true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range refsDirs {
+		if _, err := os.Stat(s.objectPath(ref)); err == nil || !os.IsNotExist(err) {
+			continue
+		}
+
+		report.DanglingRefs = append(report.DanglingRefs, s.rel(s.refsDir(ref)))
+
+		if repair {
+			if err := os.RemoveAll(s.refsDir(ref)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// Collect moves content at blob path (relative to root) into lost+found,
+// refs of the same day removed, and the shared object removed when no ref left.
+func (s *contentStore) Collect(ctx context.Context, blobPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// blobs/<unix_day>/<alg>/<hex>
+	parts := strings.Split(filepath.ToSlash(blobPath), "/")
+	if len(parts) != 4 || parts[0] != "blobs" {
+		return fmt.Errorf("invalid blob path %s", blobPath)
+	}
+
+	day, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid blob path %s: %w", blobPath, err)
+	}
+
+	target := filepath.Join(s.root, "lost+found", blobPath)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(s.root, blobPath), target); err != nil {
+		return err
+	}
+
+	ref := blob.Ref{Alg: parts[2], Hex: parts[3]}
+
+	refs, err := s.refs(ref)
+	if err != nil {
+		return err
+	}
+
+	remains := 0
+
+	for _, r := range refs {
+		if blob.UnixDay(r.From) != day {
+			remains++
+			continue
+		}
+		if err := os.Remove(s.refEntry(r)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if remains == 0 {
+		if err := os.Remove(s.objectPath(ref)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		_ = os.Remove(s.refsDir(ref))
+	}
+
+	return nil
+}
+
+// listDigests lists digests of <dir>/<alg>/<hex>
+func (s *contentStore) listDigests(dir string, isDir bool) ([]blob.Ref, error) {
+	algs, err := os.ReadDir(filepath.Join(s.root, dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	refs := make([]blob.Ref, 0)
+
+	for _, alg := range algs {
+		if !alg.IsDir() {
+			continue
+		}
+
+		list, err := os.ReadDir(filepath.Join(s.root, dir, alg.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range list {
+			if f.IsDir() != isDir {
+				continue
+			}
+			refs = append(refs, blob.Ref{Alg: alg.Name(), Hex: f.Name()})
+		}
+	}
+
+	return refs, nil
+}
+
+func (s *contentStore) rel(p string) string {
+	r, err := filepath.Rel(s.root, p)
+	if err != nil {
+		return p
+	}
+	return r
+}
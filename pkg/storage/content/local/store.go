@@ -2,6 +2,9 @@ package local
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -34,6 +37,8 @@ func NewStore(root string) (content.Store, error) {
 
 type contentStore struct {
 	root string
+	// mu guards objects and refs
+	mu sync.Mutex
 }
 
 func (s *contentStore) TempFile(ctx context.Context) (*os.File, error) {
@@ -46,18 +51,6 @@ func (s *contentStore) TempFile(ctx context.Context) (*os.File, error) {
 	return os.CreateTemp(temp, "")
 }
 
-func (s *contentStore) Delete(ctx context.Context, ref blob.Ref) error {
-	bp := ref.BlobPath(s.root)
-
-	if err := os.RemoveAll(bp); err != nil {
-		if !os.IsNotExist(err) {
-			return err
-		}
-		return fmt.Errorf("content: %w", ErrNotFound)
-	}
-	return nil
-}
-
 func (s *contentStore) Writer(ctx context.Context, opts ...blob.Opt) (content.Writer, error) {
 	info := &blob.Info{}
 	for i := range opts {
@@ -75,8 +68,13 @@ func (s *contentStore) Writer(ctx context.Context, opts ...blob.Opt) (content.Wr
 	return w, nil // lock is now held by w.
 }
 
-func (s *contentStore) ingestRoot(info *blob.Info) string {
-	return filepath.Join(s.root, "ingest", digest.FromString(info.ExternalKey("")).Hex())
+// ingestRoot of new writer, random session id included, writers of blobs in same time never share one.
+func (s *contentStore) ingestRoot(info *blob.Info) (string, error) {
+	session := make([]byte, 16)
+	if _, err := rand.Read(session); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, "ingest", digest.FromString(info.ExternalKey("")+"#"+hex.EncodeToString(session)).Hex()), nil
 }
 
 func (s *contentStore) writer(ctx context.Context, info *blob.Info) (content.Writer, error) {
@@ -87,14 +85,68 @@ func (s *contentStore) writer(ctx context.Context, info *blob.Info) (content.Wri
 		}
 	}
 
-	root := s.ingestRoot(info)
+	root, err := s.ingestRoot(info)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openWriter(root, info)
+}
+
+// ResumeWriter reopens the writer of ingest ref, which offset and digest restored from written data.
+func (s *contentStore) ResumeWriter(ctx context.Context, ref string) (content.Writer, error) {
+	root := filepath.Join(s.root, "ingest", ref)
+
+	data, err := os.ReadFile(filepath.Join(root, "info"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("ingest %s: %w", ref, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	ii := ingestInfo{}
+	if err := json.Unmarshal(data, &ii); err != nil {
+		return nil, err
+	}
+
+	return s.openWriter(root, &blob.Info{
+		Ref: blob.Ref{
+			UserID:    ii.UserID,
+			TimeRange: blob.TimeRange{From: types.Time(ii.From)},
+		},
+	})
+}
+
+func (s *contentStore) Abort(ctx context.Context, ref string) error {
+	root := filepath.Join(s.root, "ingest", ref)
+
+	if _, err := os.Stat(root); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("ingest %s: %w", ref, ErrNotFound)
+		}
+		return err
+	}
+
+	return os.RemoveAll(root)
+}
+
+// ingestInfo stored in ingest dir for resuming
+type ingestInfo struct {
+	From      int64     `json:"from"`
+	UserID    string    `json:"userID,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+func (s *contentStore) openWriter(root string, info *blob.Info) (content.Writer, error) {
 	dataFile := filepath.Join(root, "data")
+	infoFile := filepath.Join(root, "info")
 
 	var (
 		digester  = digest.Canonical.Digester()
 		offset    int64
-		startedAt time.Time
-		updatedAt time.Time
+		startedAt = time.Now()
+		updatedAt = startedAt
 	)
 
 	if err := os.Mkdir(root, 0755); err != nil {
@@ -103,11 +155,49 @@ func (s *contentStore) writer(ctx context.Context, info *blob.Info) (content.Wri
 		}
 	}
 
-	fp, err := os.OpenFile(dataFile, os.O_WRONLY|os.O_CREATE, 0666)
+	if data, err := os.ReadFile(infoFile); err == nil {
+		ii := ingestInfo{}
+		if err := json.Unmarshal(data, &ii); err == nil {
+			startedAt = ii.StartedAt
+		}
+	} else {
+		data, err := json.Marshal(ingestInfo{
+			From:      int64(info.From),
+			UserID:    info.UserID,
+			StartedAt: startedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(infoFile, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write info file: %w", err)
+		}
+	}
+
+	fp, err := os.OpenFile(dataFile, os.O_RDWR|os.O_CREATE, 0666)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open data file: %w", err)
 	}
 
+	fi, err := fp.Stat()
+	if err != nil {
+		_ = fp.Close()
+		return nil, err
+	}
+
+	// resume from written data
+	if fi.Size() > 0 {
+		buf := bufPool.Get().(*[]byte)
+		defer bufPool.Put(buf)
+
+		offset, err = io.CopyBuffer(digester.Hash(), fp, *buf)
+		if err != nil {
+			_ = fp.Close()
+			return nil, fmt.Errorf("failed to digest written data: %w", err)
+		}
+		updatedAt = fi.ModTime()
+	}
+
 	return &writer{
 		s:         s,
 		file:      fp,
@@ -134,6 +224,7 @@ type writer struct {
 
 func (w *writer) Status() (content.Status, error) {
 	return content.Status{
+		Ref:       filepath.Base(w.path),
 		Offset:    w.offset,
 		Total:     w.total,
 		StartedAt: w.startedAt,
@@ -162,15 +253,7 @@ func (w *writer) Write(p []byte) (n int, err error) {
 }
 
 func (w *writer) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...blob.Opt) error {
-	for _, opt := range opts {
-		opt(w.info)
-	}
-	if w.info.UserID == "" {
-		w.info.UserID = blob.DefaultUser
-	}
-	if w.info.Through == 0 {
-		w.info.Through = w.info.From
-	}
+	content.CompleteInfo(w.info, opts...)
 
 	file := w.file
 	w.file = nil
@@ -202,17 +285,7 @@ func (w *writer) Commit(ctx context.Context, size int64, expected digest.Digest,
 		return fmt.Errorf("unexpected commit digest %s, expected %s: %w", dgst, expected, errdefs.ErrFailedPrecondition)
 	}
 
-	var (
-		from   = filepath.Join(w.path, "data")
-		target = w.Info().BlobPath(w.s.root)
-	)
-
-	// make sure parent directories of blob exist
-	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-		return err
-	}
-
-	if err := os.Rename(from, target); err != nil {
+	if err := w.s.place(filepath.Join(w.path, "data"), w.Info().Ref); err != nil {
 		return err
 	}
 
@@ -0,0 +1,115 @@
+package s3test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/httputil"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content/s3"
+)
+
+// NewServer starts an in-process S3-compatible server for tests.
+// The returned config is ready for s3.NewStore.
+func NewServer() (*httptest.Server, config.S3Config) {
+	c := Config()
+
+	srv := httptest.NewServer(NewHandler(c))
+	c.Endpoint = srv.URL
+
+	return srv, c
+}
+
+// Config of fake credentials, without endpoint
+func Config() config.S3Config {
+	return config.S3Config{
+		Region:          "us-east-1",
+		Bucket:          "media",
+		AccessKeyID:     "fake",
+		SecretAccessKey: "fakefakefake",
+		PresignExpires:  time.Minute,
+	}
+}
+
+// NewHandler serves path-style objects kept in memory, with signatures verified by credentials of c.
+func NewHandler(c config.S3Config) http.Handler {
+	return &fakeServer{
+		c:       c,
+		objects: map[string]fakeObject{},
+	}
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+type fakeServer struct {
+	c       config.S3Config
+	mu      sync.RWMutex
+	objects map[string]fakeObject
+}
+
+func (f *fakeServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if !s3.VerifyRequest(f.c, req) {
+		rw.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.objects[req.URL.Path] = fakeObject{data: data, contentType: req.Header.Get("Content-Type")}
+		f.mu.Unlock()
+		rw.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		f.mu.Lock()
+		delete(f.objects, req.URL.Path)
+		f.mu.Unlock()
+		rw.WriteHeader(http.StatusNoContent)
+	case http.MethodGet, http.MethodHead:
+		f.mu.RLock()
+		o, ok := f.objects[req.URL.Path]
+		f.mu.RUnlock()
+
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		rw.Header().Set("Content-Type", o.contentType)
+
+		data := o.data
+		status := http.StatusOK
+
+		if rng := req.Header.Get("Range"); rng != "" {
+			ranges, err := httputil.ParseRange(rng, int64(len(o.data)))
+			if err != nil || len(ranges) != 1 {
+				rw.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			data = o.data[ranges[0].Start : ranges[0].Start+ranges[0].Length]
+			rw.Header().Set("Content-Range", ranges[0].ContentRange(int64(len(o.data))))
+			status = http.StatusPartialContent
+		}
+
+		rw.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		rw.WriteHeader(status)
+
+		if req.Method == http.MethodGet {
+			_, _ = io.Copy(rw, bytes.NewReader(data))
+		}
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
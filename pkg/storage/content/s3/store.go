@@ -0,0 +1,303 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// NewStore creates content store of S3-compatible object storage.
+// Content ingested in staging store, and put as object when committed.
+func NewStore(c config.S3Config, staging content.Store) (content.Store, error) {
+	if c.Endpoint == "" || c.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid s3 endpoint")
+	}
+
+	region := c.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &objectStore{
+		c:        c,
+		endpoint: endpoint,
+		staging:  staging,
+		client:   http.DefaultClient,
+		signer: &signer{
+			region:          region,
+			accessKeyID:     c.AccessKeyID,
+			secretAccessKey: c.SecretAccessKey,
+		},
+	}, nil
+}
+
+type objectStore struct {
+	c        config.S3Config
+	endpoint *url.URL
+	staging  content.Store
+	client   *http.Client
+	signer   *signer
+}
+
+func (s *objectStore) Writer(ctx context.Context, opts ...blob.Opt) (content.Writer, error) {
+	w, err := s.staging.Writer(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return s.importingWriter(w), nil
+}
+
+func (s *objectStore) ResumeWriter(ctx context.Context, ref string) (content.Writer, error) {
+	w, err := s.staging.ResumeWriter(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.importingWriter(w), nil
+}
+
+func (s *objectStore) importingWriter(w content.Writer) content.Writer {
+	return content.NewImportingWriter(w, s.staging, func(info blob.Info) content.Importer {
+		return s
+	})
+}
+
+func (s *objectStore) Abort(ctx context.Context, ref string) error {
+	return s.staging.Abort(ctx, ref)
+}
+
+func (s *objectStore) TempFile(ctx context.Context) (*os.File, error) {
+	return s.staging.TempFile(ctx)
+}
+
+func (s *objectStore) Import(ctx context.Context, info blob.Info, r content.ReaderAt) error {
+	req, err := s.newRequest(ctx, http.MethodPut, info.Ref, io.NewSectionReader(r, 0, r.Size()))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = r.Size()
+
+	if mediaTypes, ok := info.Labels["_media_type"]; ok && len(mediaTypes) > 0 {
+		req.Header.Set("Content-Type", mediaTypes[0])
+	} else {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	return nil
+}
+
+func (s *objectStore) Delete(ctx context.Context, ref blob.Ref) error {
+	// S3 responses 204 even if object not exists
+	if _, err := s.stat(ctx, ref); err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodDelete, ref, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	return nil
+}
+
+func (s *objectStore) ReaderAt(ctx context.Context, ref blob.Ref) (content.ReaderAt, error) {
+	size, err := s.stat(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &objectReaderAt{
+		ctx:  ctx,
+		s:    s,
+		ref:  ref,
+		size: size,
+	}, nil
+}
+
+func (s *objectStore) PresignedURL(ctx context.Context, ref blob.Ref) (string, error) {
+	if s.c.PresignExpires == 0 {
+		return "", nil
+	}
+	return s.signer.Presign(http.MethodGet, s.objectURL(ref), time.Now(), s.c.PresignExpires).String(), nil
+}
+
+func (s *objectStore) stat(ctx context.Context, ref blob.Ref) (int64, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, ref, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+
+	return resp.ContentLength, nil
+}
+
+// key of object, under blob path of ref like local blobs.
+// Each ref owns its object, refs of same content in same day not share one,
+// otherwise deleting one ref removes the content of others.
+func (s *objectStore) key(ref blob.Ref) string {
+	return path.Join(s.c.Prefix, filepath.ToSlash(ref.BlobPath("")), digest.FromString(ref.ExternalKey("")).Hex())
+}
+
+func (s *objectStore) objectURL(ref blob.Ref) *url.URL {
+	u := *s.endpoint
+
+	if s.c.VirtualHostStyle {
+		u.Host = s.c.Bucket + "." + u.Host
+		u.Path = "/" + s.key(ref)
+	} else {
+		u.Path = "/" + path.Join(s.c.Bucket, s.key(ref))
+	}
+
+	return &u
+}
+
+func (s *objectStore) newRequest(ctx context.Context, method string, ref blob.Ref, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, s.objectURL(ref).String(), body)
+}
+
+func (s *objectStore) do(req *http.Request) (*http.Response, error) {
+	s.signer.Sign(req, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusNoContent:
+		return resp, nil
+	case http.StatusNotFound:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("object %s: %w", req.URL.Path, content.ErrNotFound)
+	default:
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s failed: %d %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+}
+
+// readAhead is the min bytes of each GET, small sequential reads served from buffer.
+const readAhead = 1 << 20
+
+type objectReaderAt struct {
+	ctx  context.Context
+	s    *objectStore
+	ref  blob.Ref
+	size int64
+
+	mu        sync.Mutex
+	buf       []byte
+	bufOffset int64
+}
+
+func (r *objectReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	if offset >= r.size {
+		return 0, io.EOF
+	}
+
+	end := offset + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+
+	if end == offset {
+		return 0, nil
+	}
+
+	var n int
+
+	if end-offset >= readAhead {
+		// large read, no need to buffer
+		if err := r.fetch(p[:end-offset], offset); err != nil {
+			return 0, err
+		}
+		n = int(end - offset)
+	} else {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if offset < r.bufOffset || end > r.bufOffset+int64(len(r.buf)) {
+			fetchEnd := offset + readAhead
+			if fetchEnd > r.size {
+				fetchEnd = r.size
+			}
+
+			buf := make([]byte, fetchEnd-offset)
+			if err := r.fetch(buf, offset); err != nil {
+				return 0, err
+			}
+			r.buf, r.bufOffset = buf, offset
+		}
+
+		n = copy(p, r.buf[offset-r.bufOffset:end-r.bufOffset])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// fetch reads range of object from offset into p
+func (r *objectReaderAt) fetch(p []byte, offset int64) error {
+	req, err := r.s.newRequest(r.ctx, http.MethodGet, r.ref, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-"+strconv.FormatInt(offset+int64(len(p))-1, 10))
+
+	resp, err := r.s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.ReadFull(resp.Body, p)
+	return err
+}
+
+func (r *objectReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *objectReaderAt) Close() error {
+	return nil
+}
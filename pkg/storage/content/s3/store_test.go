@@ -0,0 +1,130 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	contentlocal "github.com/innoai-tech/media-toolkit/pkg/storage/content/local"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content/s3"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content/s3/s3test"
+	. "github.com/octohelm/x/testing"
+	"github.com/pkg/errors"
+)
+
+func TestStore(t *testing.T) {
+	c := s3test.Config()
+	h := s3test.NewHandler(c)
+
+	gets := int64(0)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			atomic.AddInt64(&gets, 1)
+		}
+		h.ServeHTTP(rw, req)
+	}))
+	defer srv.Close()
+
+	c.Endpoint = srv.URL
+
+	staging, err := contentlocal.NewStore(t.TempDir())
+	Expect(t, err, Be[error](nil))
+
+	s, err := s3.NewStore(c, staging)
+	Expect(t, err, Be[error](nil))
+
+	data := "0123456789"
+
+	w, err := s.Writer(context.Background())
+	Expect(t, err, Be[error](nil))
+	_, _ = io.Copy(w, bytes.NewBufferString(data))
+	err = w.Commit(context.Background(), int64(len(data)), blob.FromString(data).Digest(), blob.WithLabels(blob.Labels{
+		"_media_type": {"text/plain"},
+	}))
+	Expect(t, err, Be[error](nil))
+
+	info := w.Info()
+
+	t.Run("staging removed", func(t *testing.T) {
+		_, err := staging.ReaderAt(context.Background(), info.Ref)
+		Expect(t, errors.Is(err, content.ErrNotFound), Be(true))
+	})
+
+	t.Run("ReaderAt", func(t *testing.T) {
+		r, err := s.ReaderAt(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, r.Size(), Be(int64(len(data))))
+
+		buf := make([]byte, 4)
+		n, err := r.ReadAt(buf, 8)
+		Expect(t, n, Be(2))
+		Expect(t, err, Be(io.EOF))
+		Expect(t, string(buf[:n]), Be("89"))
+	})
+
+	t.Run("ReaderAt sequential reads", func(t *testing.T) {
+		r, err := s.ReaderAt(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+
+		atomic.StoreInt64(&gets, 0)
+
+		b := &strings.Builder{}
+		_, err = io.CopyBuffer(b, io.NewSectionReader(r, 0, r.Size()), make([]byte, 3))
+		Expect(t, err, Be[error](nil))
+		Expect(t, b.String(), Be(data))
+		Expect(t, atomic.LoadInt64(&gets), Be(int64(1)))
+	})
+
+	t.Run("PresignedURL", func(t *testing.T) {
+		u, err := s.(content.Presigner).PresignedURL(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+
+		resp, err := http.Get(u)
+		Expect(t, err, Be[error](nil))
+		defer resp.Body.Close()
+		Expect(t, resp.StatusCode, Be(http.StatusOK))
+		Expect(t, resp.Header.Get("Content-Type"), Be("text/plain"))
+
+		got, _ := io.ReadAll(resp.Body)
+		Expect(t, string(got), Be(data))
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		bc := c
+		bc.SecretAccessKey = "bad"
+		bad, _ := s3.NewStore(bc, staging)
+		_, err := bad.ReaderAt(context.Background(), info.Ref)
+		Expect(t, err == nil, Be(false))
+	})
+
+	t.Run("Delete not shared by refs of same content", func(t *testing.T) {
+		w, err := s.Writer(context.Background(), blob.WithFromThough(info.From+1))
+		Expect(t, err, Be[error](nil))
+		_, _ = io.Copy(w, bytes.NewBufferString(data))
+		Expect(t, w.Commit(context.Background(), int64(len(data)), blob.FromString(data).Digest()), Be[error](nil))
+
+		other := w.Info()
+		Expect(t, other.BlobPath(""), Be(info.BlobPath("")))
+
+		Expect(t, s.Delete(context.Background(), other.Ref), Be[error](nil))
+
+		r, err := s.ReaderAt(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, r.Size(), Be(int64(len(data))))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		Expect(t, s.Delete(context.Background(), info.Ref), Be[error](nil))
+		_, err := s.ReaderAt(context.Background(), info.Ref)
+		Expect(t, errors.Is(err, content.ErrNotFound), Be(true))
+		Expect(t, errors.Is(s.Delete(context.Background(), info.Ref), content.ErrNotFound), Be(true))
+	})
+}
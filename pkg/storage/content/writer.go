@@ -0,0 +1,54 @@
+package content
+
+import (
+	"context"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/opencontainers/go-digest"
+)
+
+// NewImportingWriter creates Writer which commits content into staging store first,
+// then imports into the target and removes the staging copy.
+// Content kept in staging when targetFor returns nil.
+func NewImportingWriter(w Writer, staging Store, targetFor func(info blob.Info) Importer) Writer {
+	return &importingWriter{
+		Writer:    w,
+		staging:   staging,
+		targetFor: targetFor,
+	}
+}
+
+type importingWriter struct {
+	Writer
+	staging   Store
+	targetFor func(info blob.Info) Importer
+}
+
+func (w *importingWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...blob.Opt) error {
+	if err := w.Writer.Commit(ctx, size, expected, opts...); err != nil {
+		return err
+	}
+
+	info := w.Writer.Info()
+
+	target := w.targetFor(info)
+	if target == nil {
+		return nil
+	}
+
+	r, err := w.staging.ReaderAt(ctx, info.Ref)
+	if err != nil {
+		return err
+	}
+
+	if err := target.Import(ctx, info, r); err != nil {
+		_ = r.Close()
+		// staging copy removed to avoid orphan content
+		_ = w.staging.Delete(ctx, info.Ref)
+		return err
+	}
+
+	_ = r.Close()
+
+	return w.staging.Delete(ctx, info.Ref)
+}
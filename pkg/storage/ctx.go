@@ -1,6 +1,9 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"crypto/ed25519"
+)
 
 type storeContextKey struct {
 }
@@ -12,3 +15,45 @@ func StoreFromContext(ctx context.Context) Store {
 func NewContextWithStore(ctx context.Context, s Store) context.Context {
 	return context.WithValue(ctx, storeContextKey{}, s)
 }
+
+// Actor who operates the store
+type Actor struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin,omitempty"`
+	// Addr of remote client
+	Addr string `json:"addr,omitempty"`
+	// Claimed name by client, unverified
+	Claimed string `json:"claimed,omitempty"`
+}
+
+// ActorSystem for operations triggered by store itself
+var ActorSystem = Actor{Name: "system"}
+
+type actorContextKey struct {
+}
+
+func ActorFromContext(ctx context.Context) Actor {
+	if a, ok := ctx.Value(actorContextKey{}).(Actor); ok {
+		return a
+	}
+	return ActorSystem
+}
+
+func NewContextWithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, a)
+}
+
+type signingKeyContextKey struct {
+}
+
+// SigningKeyFromContext returns key to sign exported datasets, nil when not configured
+func SigningKeyFromContext(ctx context.Context) ed25519.PrivateKey {
+	if k, ok := ctx.Value(signingKeyContextKey{}).(ed25519.PrivateKey); ok {
+		return k
+	}
+	return nil
+}
+
+func NewContextWithSigningKey(ctx context.Context, key ed25519.PrivateKey) context.Context {
+	return context.WithValue(ctx, signingKeyContextKey{}, key)
+}
@@ -5,57 +5,385 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"fmt"
-	"github.com/innoai-tech/media-toolkit/pkg/blob"
-	"github.com/opencontainers/go-digest"
+	"crypto/ed25519"
+	"encoding/json"
 	"io"
 	"path"
+	"sort"
+	"strings"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	mtkmime "github.com/innoai-tech/media-toolkit/pkg/storage/mime"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	"github.com/innoai-tech/media-toolkit/pkg/version"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// DatasetManifest is the JSON Lines manifest of dataset, written before all contents.
+const DatasetManifest = "manifest.jsonl"
+
+type ExportLayout string
+
+const (
+	// ExportLayoutBlobs puts contents as blobs/<unix_day>/<alg>/<hex>
+	ExportLayoutBlobs ExportLayout = "blobs"
+	// ExportLayoutImageFolder puts images as <group>/<hex><ext>, grouped by value of label
+	ExportLayoutImageFolder ExportLayout = "imagefolder"
+	// ExportLayoutCOCO puts images as images/<hex><ext>, with annotations.json categorized by value of label
+	ExportLayoutCOCO ExportLayout = "coco"
 )
 
-func ExportDataset(ctx context.Context, store Store, w io.Writer, blobs []blob.Info) (dgst digest.Digest, err error) {
+// unlabeled group for blobs without the group label
+const unlabeled = "_unlabeled"
+
+type ExportOpt func(o *exportOptions)
+
+type exportOptions struct {
+	layout     ExportLayout
+	groupBy    string
+	query      DatasetQuery
+	signingKey ed25519.PrivateKey
+	transform  ContentTransformer
+}
+
+// ContentTransformer transforms contents of blobs when exporting, like image transforms.
+type ContentTransformer interface {
+	// Transformable returns true when content of blob could be transformed
+	Transformable(info blob.Info) bool
+	// Transform reads content from r, and writes transformed into w
+	Transform(ctx context.Context, r io.Reader, w io.Writer) error
+	// MediaType of transformed content
+	MediaType() string
+	// Ext of transformed content
+	Ext() string
+	// String returns params of transform, recorded in manifest
+	String() string
+}
+
+// WithExportLayout sets layout of exported contents, groupBy is required when layout not blobs.
+func WithExportLayout(layout ExportLayout, groupBy string) ExportOpt {
+	return func(o *exportOptions) {
+		if layout != "" {
+			o.layout = layout
+		}
+		o.groupBy = groupBy
+	}
+}
+
+// WithExportQuery records the query which selected blobs in signed manifest.
+func WithExportQuery(timeRange blob.TimeRange, filter types.Filter) ExportOpt {
+	return func(o *exportOptions) {
+		o.query.TimeRange = types.DateTimeRange{From: timeRange.From, To: timeRange.Through}
+		if len(filter.Matchers) > 0 {
+			data, _ := filter.MarshalText()
+			o.query.Filter = string(data)
+		}
+	}
+}
+
+// WithSigningKey signs manifest by the ed25519 key, manifest unsigned when nil.
+func WithSigningKey(key ed25519.PrivateKey) ExportOpt {
+	return func(o *exportOptions) {
+		o.signingKey = key
+	}
+}
+
+// WithContentTransformer transforms contents of transformable blobs,
+// contents transformed are recorded with transform in manifest, and could not be imported.
+func WithContentTransformer(t ContentTransformer) ExportOpt {
+	return func(o *exportOptions) {
+		o.transform = t
+	}
+}
+
+// DatasetEntry is one line of manifest
+type DatasetEntry struct {
+	blob.Info
+	// Path of content in archive
+	Path   string        `json:"path"`
+	Digest digest.Digest `json:"digest"`
+	// Transform params when content at Path is transformed from blob
+	Transform string `json:"transform,omitempty"`
+}
+
+// ExportDataset streams blobs as tar.gz into w, and returns the digest of the whole archive.
+func ExportDataset(ctx context.Context, store Store, w io.Writer, blobs []blob.Info, opts ...ExportOpt) (dgst digest.Digest, err error) {
+	o := &exportOptions{layout: ExportLayoutBlobs}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	entries, err := o.entries(blobs)
+	if err != nil {
+		return "", err
+	}
+
 	digester := digest.Canonical.Digester()
 	mw := io.MultiWriter(w, digester.Hash())
 
 	gw := gzip.NewWriter(mw)
-	defer func() {
-		_ = gw.Close()
-		// should calc digest when gz close
-		dgst = digester.Digest()
-	}()
-
 	tw := tar.NewWriter(gw)
-	defer func() {
-		_ = tw.Close()
-	}()
 
-	labels := bytes.NewBuffer(nil)
+	if err := writeDataset(ctx, store, tw, entries, o); err != nil {
+		return "", err
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	// should calc digest when gz close
+	if err := gw.Close(); err != nil {
+		return "", err
+	}
+
+	return digester.Digest(), nil
+}
 
-	for i := range blobs {
-		b := blobs[i]
+func writeDataset(ctx context.Context, store Store, w *tar.Writer, entries []DatasetEntry, o *exportOptions) error {
+	tw := &digestingTarWriter{Writer: w}
 
-		r, err := store.ReaderAt(ctx, b.Ref)
-		if err != nil {
-			return "", err
+	manifest := bytes.NewBuffer(nil)
+	e := json.NewEncoder(manifest)
+	for i := range entries {
+		if err := e.Encode(entries[i]); err != nil {
+			return err
 		}
+	}
+
+	if _, err := tw.copy(manifest, tar.Header{
+		Name: DatasetManifest,
+		Size: int64(manifest.Len()),
+	}); err != nil {
+		return err
+	}
+
+	// blobs share same content share same path
+	written := map[string]bool{}
 
-		_, _ = fmt.Fprintln(labels, b.String())
+	for _, entry := range entries {
+		if written[entry.Path] {
+			continue
+		}
+		written[entry.Path] = true
 
-		if err := copyToTar(tw, io.NewSectionReader(r, 0, r.Size()), tar.Header{
-			Name: path.Join(b.Ref.BlobPath("")),
-			Size: r.Size(),
+		if entry.Transform != "" {
+			if err := copyTransformedBlobToTar(ctx, store, tw, entry, o.transform); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := copyBlobToTar(ctx, store, tw, entry); err != nil {
+			return err
+		}
+	}
+
+	if o.layout == ExportLayoutCOCO {
+		data, err := json.Marshal(newCOCOAnnotations(entries, o.groupBy))
+		if err != nil {
+			return err
+		}
+		if _, err := tw.copy(bytes.NewReader(data), tar.Header{
+			Name: "annotations.json",
+			Size: int64(len(data)),
 		}); err != nil {
-			return "", err
+			return err
 		}
 	}
 
-	if err := copyToTar(tw, labels, tar.Header{
-		Name: "labels",
-		Size: int64(labels.Len()),
-	}); err != nil {
-		return "", err
+	return tw.sign(&SignedManifest{
+		Version:   version.FullVersion(),
+		CreatedAt: types.Now(),
+		Query:     o.query,
+		Layout:    o.layout,
+	}, o.signingKey)
+}
+
+func copyBlobToTar(ctx context.Context, store Store, tw *digestingTarWriter, entry DatasetEntry) error {
+	r, err := store.ReaderAt(ctx, entry.Ref)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	dgst, err := tw.copy(io.NewSectionReader(r, 0, r.Size()), tar.Header{
+		Name: entry.Path,
+		Size: r.Size(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// never sign corrupted content
+	if entry.Digest.Algorithm() == dgst.Algorithm() && entry.Digest != dgst {
+		return errors.Errorf("content of %s corrupted, digest %s", entry.RefKey, dgst)
+	}
+
+	return nil
+}
+
+func copyTransformedBlobToTar(ctx context.Context, store Store, tw *digestingTarWriter, entry DatasetEntry, t ContentTransformer) error {
+	r, err := store.ReaderAt(ctx, entry.Ref)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// size required by tar header before content
+	buf := bytes.NewBuffer(nil)
+	if err := t.Transform(ctx, io.NewSectionReader(r, 0, r.Size()), buf); err != nil {
+		return errors.Wrapf(err, "transform %s failed", entry.RefKey)
+	}
+
+	_, err = tw.copy(buf, tar.Header{
+		Name: entry.Path,
+		Size: int64(buf.Len()),
+	})
+	return err
+}
+
+// CheckExportLayout checks layout before export starts
+func CheckExportLayout(layout ExportLayout, groupBy string) error {
+	switch layout {
+	case "", ExportLayoutBlobs:
+		return nil
+	case ExportLayoutImageFolder, ExportLayoutCOCO:
+		if groupBy == "" {
+			return errors.Errorf("group label is required for layout %s", layout)
+		}
+		return nil
+	}
+	return errors.Errorf("unsupported layout %s", layout)
+}
+
+func (o *exportOptions) entries(blobs []blob.Info) ([]DatasetEntry, error) {
+	if err := CheckExportLayout(o.layout, o.groupBy); err != nil {
+		return nil, err
+	}
+
+	entries := make([]DatasetEntry, 0, len(blobs))
+
+	for _, b := range blobs {
+		entry := DatasetEntry{Info: b, Digest: b.Digest()}
+		if entry.RefKey == "" {
+			entry.RefKey = b.ExternalKey("")
+		}
+
+		transformed := o.transform != nil && o.transform.Transformable(b)
+		if transformed {
+			entry.Transform = o.transform.String()
+		}
+
+		switch o.layout {
+		case ExportLayoutBlobs:
+			entry.Path = b.BlobPath("")
+			if transformed {
+				entry.Path += o.transform.Ext()
+			}
+		case ExportLayoutImageFolder, ExportLayoutCOCO:
+			mediaType := mediaTypeOf(b)
+			if !strings.HasPrefix(mediaType, "image/") {
+				continue
+			}
+			ext := mtkmime.ExtensionOf(mediaType)
+			if transformed {
+				ext = o.transform.Ext()
+			}
+			filename := b.Hex + ext
+			if o.layout == ExportLayoutCOCO {
+				entry.Path = path.Join("images", filename)
+			} else {
+				entry.Path = path.Join(groupOf(b, o.groupBy), filename)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func mediaTypeOf(info blob.Info) string {
+	if mt, ok := info.Labels["_media_type"]; ok && len(mt) > 0 {
+		return mt[0]
+	}
+	return ""
+}
+
+// groupOf returns first value of label as group, which safe as dir name
+func groupOf(info blob.Info, groupBy string) string {
+	if values := info.Labels[groupBy]; len(values) > 0 && values[0] != "" {
+		return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(values[0])
+	}
+	return unlabeled
+}
+
+type cocoAnnotations struct {
+	Images      []cocoImage      `json:"images"`
+	Categories  []cocoCategory   `json:"categories"`
+	Annotations []cocoAnnotation `json:"annotations"`
+}
+
+type cocoImage struct {
+	ID       int    `json:"id"`
+	FileName string `json:"file_name"`
+}
+
+type cocoCategory struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type cocoAnnotation struct {
+	ID         int `json:"id"`
+	ImageID    int `json:"image_id"`
+	CategoryID int `json:"category_id"`
+}
+
+func newCOCOAnnotations(entries []DatasetEntry, groupBy string) *cocoAnnotations {
+	a := &cocoAnnotations{
+		Images:      make([]cocoImage, 0),
+		Categories:  make([]cocoCategory, 0),
+		Annotations: make([]cocoAnnotation, 0),
+	}
+
+	imageIDs := map[string]int{}
+	categoryIDs := map[string]int{}
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		for _, name := range entry.Labels[groupBy] {
+			if _, ok := categoryIDs[name]; !ok {
+				categoryIDs[name] = 0
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		categoryIDs[name] = i + 1
+		a.Categories = append(a.Categories, cocoCategory{ID: i + 1, Name: name})
+	}
+
+	for _, entry := range entries {
+		imageID, ok := imageIDs[entry.Path]
+		if !ok {
+			imageID = len(imageIDs) + 1
+			imageIDs[entry.Path] = imageID
+			a.Images = append(a.Images, cocoImage{ID: imageID, FileName: entry.Path})
+		}
+
+		for _, name := range entry.Labels[groupBy] {
+			a.Annotations = append(a.Annotations, cocoAnnotation{
+				ID:         len(a.Annotations) + 1,
+				ImageID:    imageID,
+				CategoryID: categoryIDs[name],
+			})
+		}
 	}
 
-	return "", nil
+	return a
 }
 
 func copyToTar(tw *tar.Writer, r io.Reader, header tar.Header) error {
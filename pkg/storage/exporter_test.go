@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	. "github.com/octohelm/x/testing"
+	"github.com/opencontainers/go-digest"
+)
+
+func TestStoreExportDatasetLayout(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	for _, x := range []struct {
+		data      string
+		mediaType string
+		class     string
+	}{
+		{"cat", "image/jpeg", "cat"},
+		{"dog", "image/jpeg", "dog"},
+		{"clip", "video/mp4", "dog"},
+	} {
+		commitBlob(t, s, x.data, blob.WithLabels(blob.Labels{
+			"_media_type": {x.mediaType},
+			"class":       {x.class},
+		}))
+	}
+
+	blobs, err := s.Query(context.Background(), blob.Last(time.Hour), blob.DefaultUser)
+	Expect(t, err, Be[error](nil))
+
+	readArchive := func(t *testing.T, data []byte) map[string][]byte {
+		files := map[string][]byte{}
+		err := walkDataset(bytes.NewReader(data), func(hdr *tar.Header, r io.Reader) error {
+			b, err := io.ReadAll(r)
+			files[hdr.Name] = b
+			return err
+		})
+		Expect(t, err, Be[error](nil))
+		return files
+	}
+
+	t.Run("Layout required group label", func(t *testing.T) {
+		_, err := ExportDataset(context.Background(), s, io.Discard, blobs, WithExportLayout(ExportLayoutCOCO, ""))
+		Expect(t, err, Not(Be[error](nil)))
+	})
+
+	t.Run("ImageFolder", func(t *testing.T) {
+		archive := bytes.NewBuffer(nil)
+		dgst, err := ExportDataset(context.Background(), s, archive, blobs, WithExportLayout(ExportLayoutImageFolder, "class"))
+		Expect(t, err, Be[error](nil))
+		Expect(t, dgst, Be(digest.FromBytes(archive.Bytes())))
+
+		files := readArchive(t, archive.Bytes())
+		Expect(t, len(files), Be(4))
+		Expect(t, string(files["cat/"+blob.FromString("cat").Hex+".jpg"]), Be("cat"))
+		Expect(t, string(files["dog/"+blob.FromString("dog").Hex+".jpg"]), Be("dog"))
+		Expect(t, bytes.Count(files[DatasetManifest], []byte("\n")), Be(2))
+	})
+
+	t.Run("COCO", func(t *testing.T) {
+		archive := bytes.NewBuffer(nil)
+		_, err := ExportDataset(context.Background(), s, archive, blobs, WithExportLayout(ExportLayoutCOCO, "class"))
+		Expect(t, err, Be[error](nil))
+
+		files := readArchive(t, archive.Bytes())
+		Expect(t, len(files), Be(5))
+
+		a := cocoAnnotations{}
+		Expect(t, json.Unmarshal(files["annotations.json"], &a), Be[error](nil))
+		Expect(t, len(a.Images), Be(2))
+		Expect(t, a.Categories, Equal([]cocoCategory{{ID: 1, Name: "cat"}, {ID: 2, Name: "dog"}}))
+		Expect(t, len(a.Annotations), Be(2))
+	})
+
+	t.Run("Transformed", func(t *testing.T) {
+		archive := bytes.NewBuffer(nil)
+		_, err := ExportDataset(context.Background(), s, archive, blobs, WithExportLayout(ExportLayoutImageFolder, "class"), WithContentTransformer(&upperTransformer{}))
+		Expect(t, err, Be[error](nil))
+
+		files := readArchive(t, archive.Bytes())
+		Expect(t, string(files["cat/"+blob.FromString("cat").Hex+".txt"]), Be("CAT"))
+		Expect(t, bytes.Contains(files[DatasetManifest], []byte(`"transform":"upper"`)), Be(true))
+
+		report, err := ImportDataset(context.Background(), s, bytes.NewReader(archive.Bytes()), ImportConflictSkip)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.Failed), Be(2))
+	})
+}
+
+type upperTransformer struct{}
+
+func (upperTransformer) Transformable(info blob.Info) bool {
+	return mediaTypeOf(info) == "image/jpeg"
+}
+
+func (upperTransformer) Transform(ctx context.Context, r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes.ToUpper(data))
+	return err
+}
+
+func (upperTransformer) MediaType() string {
+	return "text/plain"
+}
+
+func (upperTransformer) Ext() string {
+	return ".txt"
+}
+
+func (upperTransformer) String() string {
+	return "upper"
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrAdminRequired = errors.New("admin required")
+	ErrBlobHeld      = errors.New("blob is under legal hold")
+)
+
+const (
+	AuditActionHold    = "hold"
+	AuditActionRelease = "release"
+)
+
+type Holder interface {
+	// Hold puts legal hold on blob, which blocks deletion until released, admin only.
+	Hold(ctx context.Context, ref blob.Ref, reason string) error
+	// Release releases legal hold of blob, admin only.
+	Release(ctx context.Context, ref blob.Ref) error
+}
+
+func (s *store) Hold(ctx context.Context, ref blob.Ref, reason string) error {
+	if !ActorFromContext(ctx).Admin {
+		return statuserr.Wrap(http.StatusForbidden, ErrAdminRequired, "only admin could hold blob")
+	}
+
+	if reason == "" {
+		reason = "hold"
+	}
+
+	if _, err := s.Info(ctx, ref); err != nil {
+		return err
+	}
+
+	labelWriter, err := s.labelWriterFor(ctx, ref.TimeRange)
+	if err != nil {
+		return err
+	}
+
+	if err := labelWriter.PutLabels(ctx, ref.TimeRange, label.MetricLabel, ref, blob.Labels{blob.LabelHold: {reason}}); err != nil {
+		return err
+	}
+
+	return s.audit(ctx, AuditActionHold, &ref, map[string]string{"reason": reason})
+}
+
+func (s *store) Release(ctx context.Context, ref blob.Ref) error {
+	if !ActorFromContext(ctx).Admin {
+		return statuserr.Wrap(http.StatusForbidden, ErrAdminRequired, "only admin could release blob")
+	}
+
+	info, err := s.Info(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	reasons, ok := info.Labels[blob.LabelHold]
+	if !ok {
+		return nil
+	}
+
+	labelWriter, err := s.labelWriterFor(ctx, ref.TimeRange)
+	if err != nil {
+		return err
+	}
+
+	if err := labelWriter.DelLabels(ctx, ref.TimeRange, label.MetricLabel, ref, blob.Labels{blob.LabelHold: reasons}); err != nil {
+		return err
+	}
+
+	s.rewindQuota(ref.From)
+
+	return s.audit(ctx, AuditActionRelease, &ref, nil)
+}
+
+// errIfHeld returns error when blob under legal hold
+func errIfHeld(info *blob.Info) error {
+	if reasons, ok := info.Labels[blob.LabelHold]; ok && len(reasons) > 0 {
+		return statuserr.Wrap(http.StatusLocked, ErrBlobHeld, "blob is under legal hold: "+reasons[0])
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	. "github.com/octohelm/x/testing"
+	"github.com/pkg/errors"
+)
+
+func TestStoreHold(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	info := commitBlob(t, s, "evidence", blob.WithLabels(blob.Labels{"_tag": {"test"}}))
+
+	admin := NewContextWithActor(context.Background(), Actor{Name: "alice", Admin: true})
+	user := NewContextWithActor(context.Background(), Actor{Name: "bob"})
+
+	t.Run("Admin only", func(t *testing.T) {
+		err := s.Hold(user, info.Ref, "case-1")
+		Expect(t, errors.Is(err, ErrAdminRequired), Be(true))
+	})
+
+	t.Run("Hold blocks delete", func(t *testing.T) {
+		Expect(t, s.Hold(admin, info.Ref, "case-1"), Be[error](nil))
+
+		held, err := s.Info(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, held.Labels[blob.LabelHold], Equal([]string{"case-1"}))
+
+		err = s.Delete(user, info.Ref)
+		Expect(t, errors.Is(err, ErrBlobHeld), Be(true))
+
+		err = s.DeleteLabel(admin, info.Ref, blob.LabelHold, "case-1")
+		Expect(t, errors.Is(err, ErrLabelImmutable), Be(true))
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		Expect(t, s.Release(user, info.Ref), Not(Be[error](nil)))
+		Expect(t, s.Release(admin, info.Ref), Be[error](nil))
+		Expect(t, s.Delete(user, info.Ref), Be[error](nil))
+	})
+
+	t.Run("Audited", func(t *testing.T) {
+		entries, err := s.(*store).auditLog.Query(context.Background(), blob.Last(time.Hour))
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(entries), Be(2))
+		Expect(t, entries[0].Action, Be(AuditActionHold))
+		Expect(t, entries[0].Actor.Name, Be("alice"))
+		Expect(t, entries[0].Params["reason"], Be("case-1"))
+		Expect(t, entries[1].Action, Be(AuditActionRelease))
+	})
+}
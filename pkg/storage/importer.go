@@ -0,0 +1,340 @@
+package storage
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/pkg/errors"
+)
+
+type ImportConflict string
+
+const (
+	// ImportConflictSkip keeps existed blob untouched
+	ImportConflictSkip ImportConflict = "skip"
+	// ImportConflictMerge adds missing user labels to existed blob
+	ImportConflictMerge ImportConflict = "merge"
+)
+
+const AuditActionImport = "import"
+
+type ImportReport struct {
+	Imported []blob.RefString `json:"imported"`
+	Skipped  []blob.RefString `json:"skipped"`
+	Merged   []blob.RefString `json:"merged"`
+	Failed   []ImportFailure  `json:"failed"`
+}
+
+type ImportFailure struct {
+	Ref   blob.RefString `json:"ref"`
+	Error string         `json:"error"`
+}
+
+func (r *ImportReport) fail(info blob.Info, err error) {
+	r.Failed = append(r.Failed, ImportFailure{Ref: blob.RefString(info.Ref), Error: err.Error()})
+}
+
+// ImportDataset imports dataset archive written by ExportDataset.
+// The archive is read twice, first for the manifest, then for blob contents,
+// each content is verified by its digest when committing.
+func ImportDataset(ctx context.Context, store Store, r io.ReadSeeker, conflict ImportConflict) (*ImportReport, error) {
+	switch conflict {
+	case "":
+		conflict = ImportConflictSkip
+	case ImportConflictSkip, ImportConflictMerge:
+	default:
+		return nil, errors.Errorf("unsupported conflict policy %q", conflict)
+	}
+
+	entries, err := readDatasetManifest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{}
+
+	// blobs share same content share same path
+	infosOfPath := map[string][]blob.Info{}
+
+	for _, entry := range entries {
+		// content not match digest of blob
+		if entry.Transform != "" {
+			report.fail(entry.Info, errors.Errorf("content transformed by %s could not be imported", entry.Transform))
+			continue
+		}
+		infosOfPath[entry.Path] = append(infosOfPath[entry.Path], entry.Info)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	err = walkDataset(r, func(hdr *tar.Header, tr io.Reader) error {
+		infos, ok := infosOfPath[hdr.Name]
+		if !ok {
+			return nil
+		}
+		delete(infosOfPath, hdr.Name)
+
+		var content io.ReadSeeker
+
+		if len(infos) == 1 {
+			content = &onceReadSeeker{Reader: tr}
+		} else {
+			f, err := store.TempFile(ctx)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = f.Close()
+				_ = os.Remove(f.Name())
+			}()
+			if _, err := io.Copy(f, tr); err != nil {
+				return err
+			}
+			content = f
+		}
+
+		for _, info := range infos {
+			if _, err := content.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			importBlob(ctx, store, report, info, content, hdr.Size, conflict)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, infos := range infosOfPath {
+		for _, info := range infos {
+			report.fail(info, errors.New("content missing in archive"))
+		}
+	}
+
+	_ = store.Audit(ctx, AuditEntry{
+		Action: AuditActionImport,
+		Params: map[string]string{
+			"conflict": string(conflict),
+			"imported": strconv.Itoa(len(report.Imported)),
+			"skipped":  strconv.Itoa(len(report.Skipped)),
+			"merged":   strconv.Itoa(len(report.Merged)),
+			"failed":   strconv.Itoa(len(report.Failed)),
+		},
+	})
+
+	return report, nil
+}
+
+func importBlob(ctx context.Context, store Store, report *ImportReport, info blob.Info, r io.Reader, size int64, conflict ImportConflict) {
+	admin := ActorFromContext(ctx).Admin
+
+	labels := blob.Labels{}
+	for name, values := range info.Labels {
+		switch name {
+		case blob.LabelTier, blob.LabelDeleted:
+			// content always imported into hot tier
+			continue
+		case blob.LabelDerivedFrom, blob.LabelRendition:
+			// could not be trusted as rendition of source, derived again when requested
+			continue
+		case blob.LabelHold:
+			// legal hold by admin only
+			if !admin {
+				continue
+			}
+		}
+		labels[name] = values
+	}
+
+	existed, err := store.Info(ctx, info.Ref)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		report.fail(info, err)
+		return
+	}
+
+	// deleted blob kept deleted, which not shown even imported again.
+	if errors.Is(err, ErrBlobDeleted) {
+		report.fail(info, err)
+		return
+	}
+
+	if err == nil {
+		if conflict != ImportConflictMerge {
+			report.Skipped = append(report.Skipped, blob.RefString(info.Ref))
+			return
+		}
+
+		change := LabelChange{Put: blob.Labels{}}
+		for name, values := range labels {
+			if strings.HasPrefix(name, "_") {
+				continue
+			}
+			for _, v := range values {
+				if !contains(existed.Labels[name], v) {
+					change.Put[name] = append(change.Put[name], v)
+				}
+			}
+		}
+
+		if len(change.Put) > 0 {
+			results, err := store.BulkLabel(ctx, []blob.Ref{info.Ref}, change)
+			if err != nil {
+				report.fail(info, err)
+				return
+			}
+			if results[0].Error != "" {
+				report.fail(info, errors.New(results[0].Error))
+				return
+			}
+		}
+
+		report.Merged = append(report.Merged, blob.RefString(info.Ref))
+		return
+	}
+
+	w, err := store.Writer(ctx, blob.WithUserId(info.UserID), blob.WithFromThough(info.From, info.Through))
+	if err != nil {
+		report.fail(info, err)
+		return
+	}
+	defer w.Close()
+
+	if _, err := io.Copy(w, r); err != nil {
+		report.fail(info, err)
+		return
+	}
+
+	if err := w.Commit(ctx, size, info.Digest(), blob.WithLabels(labels)); err != nil {
+		report.fail(info, err)
+		return
+	}
+
+	report.Imported = append(report.Imported, blob.RefString(info.Ref))
+}
+
+// readDatasetManifest reads entries from manifest.jsonl,
+// or from legacy manifest `labels`, which lines formatted by blob.Info.String.
+func readDatasetManifest(r io.Reader) ([]DatasetEntry, error) {
+	var entries []DatasetEntry
+	found := false
+
+	err := walkDataset(r, func(hdr *tar.Header, tr io.Reader) error {
+		if hdr.Name != DatasetManifest && hdr.Name != "labels" {
+			return nil
+		}
+		if found {
+			return nil
+		}
+		found = true
+
+		scanner := bufio.NewScanner(tr)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			if hdr.Name == "labels" {
+				info, err := blob.ParseInfo(line)
+				if err != nil {
+					return err
+				}
+				entries = append(entries, DatasetEntry{Info: info, Path: info.BlobPath(""), Digest: info.Digest()})
+				continue
+			}
+
+			entry := DatasetEntry{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				return err
+			}
+
+			// time in json is in seconds, ref key keeps the full ref.
+			parsed, err := blob.ParseExternalKey(entry.RefKey, "")
+			if err != nil {
+				return err
+			}
+			if parsed.Digest() != entry.Digest {
+				return errors.Errorf("digest of %s not match ref %s", entry.Path, entry.RefKey)
+			}
+			entry.Ref = parsed.Ref
+			entries = append(entries, entry)
+		}
+
+		return scanner.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, errors.New("invalid dataset, manifest missing")
+	}
+
+	return entries, nil
+}
+
+func walkDataset(r io.Reader, each func(hdr *tar.Header, tr io.Reader) error) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := each(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// onceReadSeeker only seeks to start before read
+type onceReadSeeker struct {
+	io.Reader
+	read bool
+}
+
+func (r *onceReadSeeker) Read(p []byte) (int, error) {
+	r.read = true
+	return r.Reader.Read(p)
+}
+
+func (r *onceReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if r.read || offset != 0 || whence != io.SeekStart {
+		return 0, errors.New("seek unsupported")
+	}
+	return 0, nil
+}
+
+func contains(values []string, v string) bool {
+	for i := range values {
+		if values[i] == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	. "github.com/octohelm/x/testing"
+	"github.com/pkg/errors"
+)
+
+func TestStoreImportDataset(t *testing.T) {
+	src, _ := newTestStore(t)
+
+	for i, data := range []string{"a", "b", "a"} {
+		commitBlob(t, src, data, blob.WithLabels(blob.Labels{
+			"_media_type": {"text/plain"},
+			"class":       {fmt.Sprintf("c%d", i)},
+		}))
+	}
+
+	blobs, err := src.Query(context.Background(), blob.Last(time.Hour), blob.DefaultUser)
+	Expect(t, err, Be[error](nil))
+	Expect(t, len(blobs), Be(3))
+
+	archive := bytes.NewBuffer(nil)
+	_, err = ExportDataset(context.Background(), src, archive, blobs)
+	Expect(t, err, Be[error](nil))
+
+	dst, _ := newTestStore(t)
+
+	t.Run("Import", func(t *testing.T) {
+		report, err := ImportDataset(context.Background(), dst, bytes.NewReader(archive.Bytes()), "")
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.Imported), Be(3))
+		Expect(t, len(report.Failed), Be(0))
+
+		imported, err := dst.Query(context.Background(), blob.Last(time.Hour), blob.DefaultUser)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(imported), Be(3))
+
+		for _, b := range blobs {
+			info, err := dst.Info(context.Background(), b.Ref)
+			Expect(t, err, Be[error](nil))
+			Expect(t, info.Labels["class"], Equal(b.Labels["class"]))
+		}
+	})
+
+	t.Run("Import again should skip existed", func(t *testing.T) {
+		report, err := ImportDataset(context.Background(), dst, bytes.NewReader(archive.Bytes()), ImportConflictSkip)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.Imported), Be(0))
+		Expect(t, len(report.Skipped), Be(3))
+	})
+
+	t.Run("Import with merge should add missing labels", func(t *testing.T) {
+		Expect(t, dst.DeleteLabel(context.Background(), blobs[0].Ref, "class", blobs[0].Labels["class"][0]), Be[error](nil))
+
+		report, err := ImportDataset(context.Background(), dst, bytes.NewReader(archive.Bytes()), ImportConflictMerge)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.Merged), Be(3))
+
+		info, err := dst.Info(context.Background(), blobs[0].Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, info.Labels["class"], Equal(blobs[0].Labels["class"]))
+	})
+
+	t.Run("Deleted blob should not be imported again", func(t *testing.T) {
+		Expect(t, dst.Delete(context.Background(), blobs[1].Ref), Be[error](nil))
+
+		report, err := ImportDataset(context.Background(), dst, bytes.NewReader(archive.Bytes()), ImportConflictSkip)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.Imported), Be(0))
+		Expect(t, len(report.Skipped), Be(2))
+		Expect(t, len(report.Failed), Be(1))
+		Expect(t, report.Failed[0].Ref.Ref(), Equal(blobs[1].Ref))
+
+		_, err = dst.Info(context.Background(), blobs[1].Ref)
+		Expect(t, errors.Is(err, ErrBlobDeleted), Be(true))
+		Expect(t, errors.Is(err, ErrNotFound), Be(true))
+	})
+
+	t.Run("Tampered content should fail", func(t *testing.T) {
+		tampered := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(tampered)
+		tw := tar.NewWriter(gw)
+
+		b := blob.FromString("x", blob.WithLabels(blob.Labels{"class": {"x"}}))
+		manifest := b.String() + "\n"
+
+		_ = copyToTar(tw, bytes.NewBufferString("y"), tar.Header{Name: b.BlobPath(""), Size: 1})
+		_ = copyToTar(tw, bytes.NewBufferString(manifest), tar.Header{Name: "labels", Size: int64(len(manifest))})
+		_ = tw.Close()
+		_ = gw.Close()
+
+		other, _ := newTestStore(t)
+
+		report, err := ImportDataset(context.Background(), other, bytes.NewReader(tampered.Bytes()), "")
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.Imported), Be(0))
+		Expect(t, len(report.Failed), Be(1))
+	})
+
+	t.Run("Reserved labels dropped", func(t *testing.T) {
+		archive := bytes.NewBuffer(nil)
+		_, err := ExportDataset(context.Background(), src, archive, []blob.Info{
+			blob.FromString("a", blob.WithLabels(blob.Labels{
+				blob.LabelHold:        {"case-1"},
+				blob.LabelDerivedFrom: {blobs[1].Ref.ExternalKey("")},
+				blob.LabelRendition:   {"thumbnail?h=0&w=320"},
+				"class":               {"c0"},
+			})),
+		})
+		Expect(t, err, Be[error](nil))
+
+		for _, admin := range []bool{false, true} {
+			dst, _ := newTestStore(t)
+			ctx := NewContextWithActor(context.Background(), Actor{Name: "bob", Admin: admin})
+
+			report, err := ImportDataset(ctx, dst, bytes.NewReader(archive.Bytes()), "")
+			Expect(t, err, Be[error](nil))
+			Expect(t, len(report.Imported), Be(1))
+
+			info, err := dst.Info(context.Background(), report.Imported[0].Ref())
+			Expect(t, err, Be[error](nil))
+			Expect(t, info.Labels[blob.LabelHold] != nil, Be(admin))
+			Expect(t, info.Labels[blob.LabelDerivedFrom] == nil, Be(true))
+			Expect(t, info.Labels[blob.LabelRendition] == nil, Be(true))
+			Expect(t, info.Labels["class"], Equal([]string{"c0"}))
+		}
+	})
+}
@@ -3,15 +3,16 @@ package storage
 import (
 	context "context"
 	"net/http"
+	"sync"
 
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
 	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
-	contentlocal "github.com/innoai-tech/media-toolkit/pkg/storage/content/local"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/label/index"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/label/local"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
 	"github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/labels"
@@ -19,6 +20,7 @@ import (
 
 var (
 	ErrNotFound       = errors.New("blob not found")
+	ErrBlobDeleted    = errors.Wrap(ErrNotFound, "deleted")
 	ErrLabelImmutable = errors.New("label with `_` prefix is immutable")
 )
 
@@ -29,25 +31,101 @@ func New(c config.Config) (Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	contentStore, err := contentlocal.NewStore(c.Storage.Root)
+	contentStore, err := newContentStore(c)
 	if err != nil {
+		_ = indexClient.Shutdown(context.Background())
 		return nil, err
 	}
 
-	return &store{
+	contentStore, err = newTieredContentStore(c, contentStore)
+	if err != nil {
+		_ = indexClient.Shutdown(context.Background())
+		return nil, err
+	}
+
+	auditLog, err := newAuditLog(indexClient)
+	if err != nil {
+		_ = indexClient.Shutdown(context.Background())
+		return nil, err
+	}
+
+	intents, err := newIntentLog(c.Storage.Root)
+	if err != nil {
+		_ = indexClient.Shutdown(context.Background())
+		return nil, err
+	}
+
+	uploads, err := newUploads(c.Storage.Root)
+	if err != nil {
+		_ = indexClient.Shutdown(context.Background())
+		return nil, err
+	}
+
+	s := &store{
 		c:           c,
 		indexClient: indexClient,
+		auditLog:    auditLog,
+		intents:     intents,
+		uploads:     uploads,
 		Store:       contentStore,
-	}, nil
+		replay:      make(chan struct{}, 1),
+	}
+
+	if err := s.replayIntents(context.Background()); err != nil {
+		_ = indexClient.Shutdown(context.Background())
+		return nil, err
+	}
+
+	if err := s.migrateContentOnce(context.Background()); err != nil {
+		_ = indexClient.Shutdown(context.Background())
+		return nil, err
+	}
+
+	if err := s.backfillLabelNamesOnce(context.Background()); err != nil {
+		_ = indexClient.Shutdown(context.Background())
+		return nil, err
+	}
+
+	q, err := newQuota(context.Background(), s)
+	if err != nil {
+		_ = indexClient.Shutdown(context.Background())
+		return nil, err
+	}
+	s.quota = q
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	if _, ok := contentStore.(*tieredContentStore); ok && c.Storage.Tiering.Interval > 0 {
+		go s.runTiering(ctx)
+	}
+
+	go s.runUploadExpiry(ctx)
+	go s.runIntentReplay(ctx)
+
+	return s, nil
 }
 
 type store struct {
 	c config.Config
 	content.Store
 	indexClient index.Client
+	auditLog    *auditLog
+	intents     *intentLog
+	uploads     *uploads
+	quota       *quota
+	cancel      context.CancelFunc
+
+	// commitMu shared by commits, held exclusively by Check,
+	// which never sees content in place but labels not indexed yet.
+	commitMu sync.RWMutex
+	// replay notified when commit failed with intent kept
+	replay chan struct{}
 }
 
 func (s *store) Shutdown(ctx context.Context) error {
+	s.cancel()
+	s.uploads.Close()
 	return s.indexClient.Shutdown(ctx)
 }
 
@@ -82,7 +160,30 @@ func (s *store) Query(ctx context.Context, timeRange blob.TimeRange, userID stri
 	if err != nil {
 		return nil, err
 	}
-	return indexStore.GetBlobs(ctx, timeRange, userID, label.MetricLabel, matchers...)
+	blobs, err := indexStore.GetBlobs(ctx, timeRange, userID, label.MetricLabel, matchers...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range blobs {
+		withTier(&blobs[i])
+	}
+	return blobs, nil
+}
+
+func (s *store) LabelNames(ctx context.Context, timeRange blob.TimeRange, userID string) ([]string, error) {
+	indexStore, err := s.labelIndexStoreFor(ctx, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	return indexStore.LabelNames(ctx, timeRange, userID, label.MetricLabel)
+}
+
+func (s *store) LabelValues(ctx context.Context, timeRange blob.TimeRange, userID string, labelName string, matchers ...*labels.Matcher) ([]string, error) {
+	indexStore, err := s.labelIndexStoreFor(ctx, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	return indexStore.LabelValues(ctx, timeRange, userID, label.MetricLabel, labelName, matchers...)
 }
 
 func (s *store) Info(ctx context.Context, ref blob.Ref) (*blob.Info, error) {
@@ -95,17 +196,76 @@ func (s *store) Info(ctx context.Context, ref blob.Ref) (*blob.Info, error) {
 		return nil, err
 	}
 	if len(blobs) == 0 {
+		deleted, err := indexStore.DeletedRefs(ctx, []blob.Ref{ref}, label.MetricLabel)
+		if err != nil {
+			return nil, err
+		}
+		if len(deleted) > 0 {
+			return nil, statuserr.Wrap(http.StatusNotFound, ErrBlobDeleted, "")
+		}
 		return nil, statuserr.Wrap(http.StatusNotFound, ErrNotFound, "")
 	}
+	withTier(&blobs[0])
 	return &blobs[0], nil
 }
 
 func (s *store) Delete(ctx context.Context, ref blob.Ref) error {
+	info, err := s.Info(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	if err := errIfHeld(info); err != nil {
+		return err
+	}
+
 	labelWriter, err := s.labelWriterFor(ctx, ref.TimeRange)
 	if err != nil {
 		return err
 	}
-	return labelWriter.DelOne(ctx, ref.TimeRange, label.MetricLabel, ref)
+
+	if err := labelWriter.DelOne(ctx, ref.TimeRange, label.MetricLabel, ref); err != nil {
+		return err
+	}
+
+	// content kept, still counted until reclaimed
+	if s.quota != nil && s.quota.counted(info) {
+		s.quota.markDeleted(info)
+	}
+
+	return s.deleteDerived(ctx, info)
+}
+
+// deleteDerived deletes blobs derived from source, which are useless without source.
+// derived blobs under legal hold are kept.
+func (s *store) deleteDerived(ctx context.Context, source *blob.Info) error {
+	derived, err := s.Query(ctx, source.TimeRange, source.UserID, labels.MustNewMatcher(labels.MatchEqual, blob.LabelDerivedFrom, source.Ref.ExternalKey("")))
+	if err != nil {
+		return err
+	}
+
+	for i := range derived {
+		info := &derived[i]
+
+		if errIfHeld(info) != nil {
+			continue
+		}
+
+		labelWriter, err := s.labelWriterFor(ctx, info.TimeRange)
+		if err != nil {
+			return err
+		}
+
+		if err := labelWriter.DelOne(ctx, info.TimeRange, label.MetricLabel, info.Ref); err != nil {
+			return err
+		}
+
+		if s.quota != nil && s.quota.counted(info) {
+			s.quota.markDeleted(info)
+		}
+	}
+
+	return nil
 }
 
 func (s *store) PutLabel(ctx context.Context, ref blob.Ref, labelName string, labelValue string) error {
@@ -131,32 +291,206 @@ func (s *store) DeleteLabel(ctx context.Context, ref blob.Ref, labelName string,
 		return err
 	}
 
-	return labelWriter.DelLabels(ctx, ref.TimeRange, label.MetricLabel, ref, blob.Labels{labelName: {labelValue}})
+	if err := labelWriter.DelLabels(ctx, ref.TimeRange, label.MetricLabel, ref, blob.Labels{labelName: {labelValue}}); err != nil {
+		return err
+	}
+
+	if labelName == s.retainLabel() {
+		s.rewindQuota(ref.From)
+	}
+
+	return nil
+}
+
+func (s *store) BulkLabel(ctx context.Context, refs []blob.Ref, change LabelChange) ([]LabelChangeResult, error) {
+	for _, lbs := range []blob.Labels{change.Put, change.Delete} {
+		for labelName := range lbs {
+			if len(labelName) != 0 && labelName[0] == '_' {
+				return nil, statuserr.Wrap(http.StatusForbidden, ErrLabelImmutable, "")
+			}
+		}
+	}
+
+	results := make([]LabelChangeResult, len(refs))
+
+	// refs in same period share same schema and label writer
+	periods := map[types.Time][]int{}
+
+	for i, ref := range refs {
+		results[i].Ref = blob.RefString(ref)
+
+		pc, err := s.c.Schema.SchemaForTime(ref.From)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		periods[pc.From] = append(periods[pc.From], i)
+	}
+
+	for _, idxes := range periods {
+		timeRange := refs[idxes[0]].TimeRange
+
+		indexStore, err := s.labelIndexStoreFor(ctx, timeRange)
+		if err != nil {
+			return nil, err
+		}
+
+		labelWriter, err := s.labelWriterFor(ctx, timeRange)
+		if err != nil {
+			return nil, err
+		}
+
+		periodRefs := make([]blob.Ref, len(idxes))
+		for i, idx := range idxes {
+			periodRefs[i] = refs[idx]
+		}
+
+		existed, err := indexStore.RefsToBlobs(ctx, periodRefs, label.MetricLabel)
+		if err != nil {
+			return nil, err
+		}
+
+		existedRefKeys := make(map[string]struct{}, len(existed))
+		for _, b := range existed {
+			existedRefKeys[b.RefKey] = struct{}{}
+		}
+
+		updates := make([]label.LabelsUpdate, 0, len(idxes))
+		updated := make([]int, 0, len(idxes))
+
+		for _, idx := range idxes {
+			if _, ok := existedRefKeys[s.c.Schema.ExternalKey(refs[idx])]; !ok {
+				results[idx].Error = ErrNotFound.Error()
+				continue
+			}
+
+			updates = append(updates, label.LabelsUpdate{
+				Ref:    refs[idx],
+				Put:    change.Put,
+				Delete: change.Delete,
+			})
+			updated = append(updated, idx)
+		}
+
+		if len(updates) == 0 {
+			continue
+		}
+
+		if err := labelWriter.UpdateLabels(ctx, label.MetricLabel, updates); err != nil {
+			for _, idx := range updated {
+				results[idx].Error = err.Error()
+			}
+			continue
+		}
+
+		if _, ok := change.Delete[s.retainLabel()]; ok {
+			for _, idx := range updated {
+				s.rewindQuota(refs[idx].From)
+			}
+		}
+	}
+
+	return results, nil
 }
 
 func (s *store) Writer(ctx context.Context, opts ...blob.Opt) (Writer, error) {
+	if s.quota != nil {
+		info := blob.Info{}
+		content.CompleteInfo(&info, opts...)
+
+		if err := s.quota.Admit(ctx, &info, 0); err != nil {
+			return nil, err
+		}
+	}
+
 	w, err := s.Store.Writer(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
+	return s.wrapWriter(ctx, w)
+}
+
+func (s *store) ResumeWriter(ctx context.Context, ref string) (Writer, error) {
+	w, err := s.Store.ResumeWriter(ctx, ref)
+	if err != nil {
+		if errors.Is(err, content.ErrNotFound) {
+			return nil, statuserr.Wrap(http.StatusNotFound, err, "")
+		}
+		return nil, err
+	}
+	return s.wrapWriter(ctx, w)
+}
 
+func (s *store) wrapWriter(ctx context.Context, w Writer) (Writer, error) {
 	labelWriter, err := s.labelWriterFor(ctx, w.Info().TimeRange)
 	if err != nil {
+		_ = w.Close()
 		return nil, err
 	}
 
-	return &writer{Writer: w, labelWriter: labelWriter}, nil
+	return &writer{Writer: w, labelWriter: labelWriter, s: s}, nil
 }
 
 type writer struct {
 	Writer
 	labelWriter label.Writer
+	s           *store
 }
 
 func (w *writer) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...blob.Opt) error {
-	err := w.Writer.Commit(ctx, size, expected, opts...)
+	info := w.Writer.Info()
+	content.CompleteInfo(&info, opts...)
+
+	q := w.s.quota
+	counted := q != nil && q.counted(&info)
+
+	if counted {
+		if size <= 0 {
+			if st, err := w.Writer.Status(); err == nil {
+				size = st.Offset
+			}
+		}
+		if err := q.Admit(ctx, &info, size); err != nil {
+			return err
+		}
+	}
+
+	placed, err := w.commit(ctx, info, size, expected, opts...)
+
+	if counted {
+		// content in place counted, even labels not indexed yet.
+		if placed {
+			q.commit(&info, size)
+		} else {
+			q.unreserve(&info, size)
+		}
+	}
+
+	return err
+}
+
+// commit returns whether content placed, labels will be indexed by replaying intent when failed after placed.
+func (w *writer) commit(ctx context.Context, info blob.Info, size int64, expected digest.Digest, opts ...blob.Opt) (bool, error) {
+	w.s.commitMu.RLock()
+	defer w.s.commitMu.RUnlock()
+
+	id, err := w.s.intents.Begin(info)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	if err := w.Writer.Commit(ctx, size, expected, opts...); err != nil {
+		// content not in place, nothing to recover.
+		_ = w.s.intents.Done(id)
+		return false, err
+	}
+
+	// when failed, intent kept to replay.
+	// labels synced before intent done, otherwise lost by crash.
+	if err := w.labelWriter.Put(index.NewContextWithSync(ctx), label.MetricLabel, []blob.Info{info}); err != nil {
+		w.s.replayIntentsLater()
+		return true, err
 	}
-	return w.labelWriter.Put(ctx, label.MetricLabel, []blob.Info{w.Writer.Info()})
+
+	return true, w.s.intents.Done(id)
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
 	"github.com/innoai-tech/media-toolkit/pkg/types"
 	. "github.com/octohelm/x/testing"
 	"github.com/pkg/errors"
@@ -92,6 +93,31 @@ func TestStore(t *testing.T) {
 				Expect(t, existsTag, Be(false))
 			})
 
+			t.Run("BulkLabel", func(t *testing.T) {
+				missing := blob.FromString("missing")
+
+				results, err := s.BulkLabel(context.Background(), []blob.Ref{info.Ref, missing.Ref}, LabelChange{
+					Put: blob.Labels{"class": {"person"}},
+				})
+				Expect(t, err, Be[error](nil))
+				Expect(t, results[0].Error, Be(""))
+				Expect(t, results[1].Error, Be(ErrNotFound.Error()))
+
+				updated, err := s.Info(context.Background(), info.Ref)
+				Expect(t, err, Be[error](nil))
+				Expect(t, updated.Labels["class"], Equal([]string{"person"}))
+
+				_, err = s.BulkLabel(context.Background(), []blob.Ref{info.Ref}, LabelChange{
+					Delete: blob.Labels{"class": {"person"}},
+				})
+				Expect(t, err, Be[error](nil))
+
+				updated2, err := s.Info(context.Background(), info.Ref)
+				Expect(t, err, Be[error](nil))
+				_, existsClass := updated2.Labels["class"]
+				Expect(t, existsClass, Be(false))
+			})
+
 			t.Run("DeleteRecord", func(t *testing.T) {
 				err = s.Delete(context.Background(), info.Ref)
 				Expect(t, err, Be[error](nil))
@@ -102,3 +128,56 @@ func TestStore(t *testing.T) {
 
 	})
 }
+
+// newTestConfig returns default config with storage root in temp dir of t
+func newTestConfig(t *testing.T, fns ...func(c *config.Config)) config.Config {
+	c := config.DefaultConfig
+	c.Storage.Root = t.TempDir()
+	for _, fn := range fns {
+		fn(&c)
+	}
+	return c
+}
+
+// newTestStore opens store of newTestConfig, which shutdown when t done
+func newTestStore(t *testing.T, fns ...func(c *config.Config)) (Store, config.Config) {
+	c := newTestConfig(t, fns...)
+
+	s, err := New(c)
+	Expect(t, err, Be[error](nil))
+	t.Cleanup(func() {
+		_ = s.Shutdown(context.Background())
+	})
+
+	return s, c
+}
+
+// writeBlob writes data as blob into s
+func writeBlob(s Store, data string, opts ...blob.Opt) (blob.Info, error) {
+	w, err := s.Writer(context.Background())
+	if err != nil {
+		return blob.Info{}, err
+	}
+	_, _ = io.WriteString(w, data)
+	err = w.Commit(context.Background(), int64(len(data)), blob.FromString(data).Digest(), opts...)
+	return w.Info(), err
+}
+
+// commitBlob writes data as blob into s, which must be committed
+func commitBlob(t *testing.T, s Store, data string, opts ...blob.Opt) blob.Info {
+	info, err := writeBlob(s, data, opts...)
+	Expect(t, err, Be[error](nil))
+	return info
+}
+
+// commitContent commits content only, like crashed before labels indexed
+func commitContent(t *testing.T, s Store, data string) blob.Info {
+	w, err := s.(*store).Store.Writer(context.Background())
+	Expect(t, err, Be[error](nil))
+	_, _ = io.WriteString(w, data)
+	info := w.Info()
+	content.CompleteInfo(&info, blob.WithLabels(blob.Labels{"_tag": {"test"}}))
+	err = w.Commit(context.Background(), int64(len(data)), info.Digest(), blob.WithLabels(blob.Labels{"_tag": {"test"}}))
+	Expect(t, err, Be[error](nil))
+	return info
+}
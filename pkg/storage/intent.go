@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label/index"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// intentLog is a write-ahead log of blob commits.
+//
+// An intent is written before the content moved into place,
+// and removed after the labels indexed.
+// Intents left by a crash will be replayed when the store opened,
+// and intents of failed commits replayed in process until done.
+type intentLog struct {
+	root string
+}
+
+type intent struct {
+	Ref    blob.RefString `json:"ref"`
+	Labels blob.Labels    `json:"labels"`
+}
+
+func newIntentLog(root string) (*intentLog, error) {
+	root = filepath.Join(root, "intents")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &intentLog{root: root}, nil
+}
+
+func (l *intentLog) idOf(info blob.Info) string {
+	return digest.FromString(info.ExternalKey("")).Hex()
+}
+
+// Begin writes the intent durably, and returns the id for Done
+func (l *intentLog) Begin(info blob.Info) (string, error) {
+	id := l.idOf(info)
+
+	data, err := json.Marshal(intent{
+		Ref:    blob.RefString(info.Ref),
+		Labels: info.Labels,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(l.root, id+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	if err := os.Rename(f.Name(), filepath.Join(l.root, id)); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	return id, syncDir(l.root)
+}
+
+func (l *intentLog) Done(id string) error {
+	if err := os.Remove(filepath.Join(l.root, id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Pending returns all intents not done, partial written intents will be dropped.
+func (l *intentLog) Pending() (map[string]blob.Info, error) {
+	list, err := os.ReadDir(l.root)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := map[string]blob.Info{}
+
+	for _, f := range list {
+		if f.IsDir() {
+			continue
+		}
+
+		// crashed before rename, the content never moved into place.
+		if strings.HasSuffix(f.Name(), ".tmp") {
+			_ = os.Remove(filepath.Join(l.root, f.Name()))
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(l.root, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		i := intent{}
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, err
+		}
+
+		pending[f.Name()] = blob.Info{
+			Ref:    i.Ref.Ref(),
+			Labels: i.Labels,
+		}
+	}
+
+	return pending, nil
+}
+
+// restorer of local content store
+type restorer interface {
+	Restore(ctx context.Context, ref blob.Ref) error
+}
+
+// replayIntentsLater notifies runIntentReplay to replay intents
+func (s *store) replayIntentsLater() {
+	select {
+	case s.replay <- struct{}{}:
+	default:
+	}
+}
+
+// runIntentReplay replays intents when notified, and retries every minute until ctx done
+func (s *store) runIntentReplay(ctx context.Context) {
+	l := logr.FromContextOrDiscard(ctx)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.replay:
+		case <-ticker.C:
+		}
+
+		// no commit in progress, pending intents are of failed commits only.
+		s.commitMu.Lock()
+		err := s.replayIntents(ctx)
+		s.commitMu.Unlock()
+
+		if err != nil {
+			l.Error(err, "replay intents failed")
+		}
+	}
+}
+
+// replayIntents indexes labels of blobs which content committed but labels not,
+// and drops intents which content never committed.
+func (s *store) replayIntents(ctx context.Context) error {
+	l := logr.FromContextOrDiscard(ctx)
+
+	pending, err := s.intents.Pending()
+	if err != nil {
+		return err
+	}
+
+	for id := range pending {
+		info := pending[id]
+
+		committed, err := s.committed(ctx, info.Ref)
+		if err != nil {
+			return err
+		}
+
+		if committed {
+			labelWriter, err := s.labelWriterFor(ctx, info.TimeRange)
+			if err != nil {
+				return err
+			}
+
+			if err := labelWriter.Put(index.NewContextWithSync(ctx), label.MetricLabel, []blob.Info{info}); err != nil {
+				return err
+			}
+
+			l.Info("intent replayed", "ref", info.Ref.ExternalKey(""))
+		}
+
+		if err := s.intents.Done(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// committed returns whether content of ref in place,
+// and finishes placing content which object moved into place but not linked by a crash.
+func (s *store) committed(ctx context.Context, ref blob.Ref) (bool, error) {
+	r, err := s.Store.ReaderAt(ctx, ref)
+	if err == nil {
+		_ = r.Close()
+		return true, nil
+	}
+	if !errors.Is(err, content.ErrNotFound) {
+		return false, err
+	}
+
+	rs, ok := s.localContentStore().(restorer)
+	if !ok || !s.isLocalContent(ref) {
+		return false, nil
+	}
+
+	if err := rs.Restore(ctx, ref); err != nil {
+		if errors.Is(err, content.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/octohelm/x/testing"
+)
+
+func TestStoreRecovery(t *testing.T) {
+	c := newTestConfig(t)
+
+	s, err := New(c)
+	Expect(t, err, Be[error](nil))
+
+	t.Run("Replay intents when open", func(t *testing.T) {
+		info := commitContent(t, s, "committed")
+
+		_, err := s.(*store).intents.Begin(info)
+		Expect(t, err, Be[error](nil))
+
+		_ = s.Shutdown(context.Background())
+		s, err = New(c)
+		Expect(t, err, Be[error](nil))
+
+		found, err := s.Info(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, found.Labels["_tag"], Equal([]string{"test"}))
+
+		pending, err := s.(*store).intents.Pending()
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(pending), Be(0))
+	})
+
+	t.Run("Replay intents of object not linked", func(t *testing.T) {
+		info := commitContent(t, s, "not linked")
+
+		// like crashed after object moved into place
+		Expect(t, os.RemoveAll(filepath.Join(c.Storage.Root, "refs", info.Alg, info.Hex)), Be[error](nil))
+		Expect(t, os.Remove(info.BlobPath(c.Storage.Root)), Be[error](nil))
+
+		_, err := s.(*store).intents.Begin(info)
+		Expect(t, err, Be[error](nil))
+
+		_ = s.Shutdown(context.Background())
+		s, err = New(c)
+		Expect(t, err, Be[error](nil))
+
+		_, err = s.Info(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+
+		_, err = os.Stat(info.BlobPath(c.Storage.Root))
+		Expect(t, err, Be[error](nil))
+	})
+
+	t.Run("Replay intents in process", func(t *testing.T) {
+		// like labels indexing failed after content placed
+		info := commitContent(t, s, "in process")
+
+		_, err := s.(*store).intents.Begin(info)
+		Expect(t, err, Be[error](nil))
+
+		s.(*store).replayIntentsLater()
+
+		for i := 0; i < 100; i++ {
+			if _, err = s.Info(context.Background(), info.Ref); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		Expect(t, err, Be[error](nil))
+	})
+
+	_ = s.Shutdown(context.Background())
+}
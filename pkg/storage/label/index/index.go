@@ -31,6 +31,19 @@ type Client interface {
 	QueryPages(ctx context.Context, queries []Query, callback QueryPagesCallback) error
 }
 
+type syncContextKey struct {
+}
+
+// NewContextWithSync makes BatchWrite durable before returned
+func NewContextWithSync(ctx context.Context) context.Context {
+	return context.WithValue(ctx, syncContextKey{}, true)
+}
+
+func SyncFromContext(ctx context.Context) bool {
+	sync, _ := ctx.Value(syncContextKey{}).(bool)
+	return sync
+}
+
 type WriteBatch interface {
 	Add(entry Entry)
 	Delete(entry Entry)
@@ -22,6 +22,7 @@ var rangeValues = []RangeValue{
 	RangeValueMetricName{},
 	RangeValueLabelValue{},
 	RangeValueLabelValueBlob{},
+	RangeValueLabelName{},
 }
 
 func DecodeRangeValue(rv []byte) (RangeValue, error) {
@@ -113,6 +114,24 @@ func (r RangeValueLabelValueBlob) BlobID() string {
 	return string(readPart(r, 1))
 }
 
+type RangeValueLabelName []byte
+
+func (RangeValueLabelName) RangeKey() byte {
+	return '4'
+}
+
+func (RangeValueLabelName) New(rv []byte) RangeValue {
+	return RangeValueLabelName(rv)
+}
+
+func (r RangeValueLabelName) EncodeRangeValue(labelName []byte) []byte {
+	return EncodeRangeValue(r, labelName, nil)
+}
+
+func (r RangeValueLabelName) LabelName() string {
+	return string(readPart(r, 0))
+}
+
 func sha256bytes(v []byte) []byte {
 	h := sha256.Sum256(v)
 	return encodeBase64Bytes(h[:])
@@ -14,6 +14,7 @@ type BlobStoreSchema interface {
 	GetMetricLabelValues(timeRange blob.TimeRange, userID string, metricName string, blobID string) ([]Query, error)
 
 	GetReadQueriesForMetric(timeRange blob.TimeRange, userID string, metricName string) ([]Query, error)
+	GetReadQueriesForMetricLabelNames(timeRange blob.TimeRange, userID string, metricName string) ([]Query, error)
 	GetReadQueriesForMetricLabel(timeRange blob.TimeRange, userID string, metricName string, labelName string) ([]Query, error)
 	GetReadQueriesForMetricLabelValue(timeRange blob.TimeRange, userID string, metricName string, labelName string, labelValue string) ([]Query, error)
 
@@ -24,6 +25,7 @@ type BlobStoreEntries interface {
 	GetMetricLabelValues(bucket *Bucket, metricName string, blobID string) ([]Query, error)
 
 	GetReadQueriesForMetric(bucket *Bucket, metricName string) ([]Query, error)
+	GetReadQueriesForMetricLabelNames(bucket *Bucket, metricName string) ([]Query, error)
 	GetReadQueriesForMetricLabel(bucket *Bucket, metricName string, labelName string) ([]Query, error)
 	GetReadQueriesForMetricLabelValue(bucket *Bucket, metricName string, labelName string, labelValue string) ([]Query, error)
 
@@ -100,6 +102,12 @@ func (s *blobStoreSchema) GetReadQueriesForMetric(timeRange blob.TimeRange, user
 	})
 }
 
+func (s *blobStoreSchema) GetReadQueriesForMetricLabelNames(timeRange blob.TimeRange, userID string, metricName string) ([]Query, error) {
+	return s.makeQuerySliceBuckets(timeRange, userID, func(bucket *Bucket) ([]Query, error) {
+		return s.entries.GetReadQueriesForMetricLabelNames(bucket, metricName)
+	})
+}
+
 func (s *blobStoreSchema) GetReadQueriesForMetricLabel(timeRange blob.TimeRange, userID string, metricName string, labelName string) ([]Query, error) {
 	return s.makeQuerySliceBuckets(timeRange, userID, func(bucket *Bucket) ([]Query, error) {
 		return s.entries.GetReadQueriesForMetricLabel(bucket, metricName, labelName)
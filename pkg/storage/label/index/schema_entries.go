@@ -7,6 +7,11 @@ import (
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
 )
 
+// labelNamesKey is the hash key part of the row which collects all label names of a metric in one bucket.
+// `_` prefixed label names are immutable for users, so it never conflicts with a real label name.
+// The row is append-only, names stay after all labels or blobs with them deleted.
+const labelNamesKey = "__names__"
+
 type v1Entries struct {
 	rowShards uint32
 }
@@ -28,6 +33,15 @@ func (s *v1Entries) GetReadQueriesForMetric(bucket *Bucket, metricName string) (
 	}), nil
 }
 
+func (s *v1Entries) GetReadQueriesForMetricLabelNames(bucket *Bucket, metricName string) ([]Query, error) {
+	return makeQuerySlice(s.rowShards, func(shard uint32) Query {
+		return Query{
+			TableName: bucket.TableName,
+			HashValue: bucket.HashValuePrefixFor(shard, metricName, labelNamesKey),
+		}
+	}), nil
+}
+
 func (s *v1Entries) GetReadQueriesForMetricLabel(bucket *Bucket, metricName string, labelName string) ([]Query, error) {
 	return makeQuerySlice(s.rowShards, func(shard uint32) Query {
 		return Query{
@@ -66,6 +80,15 @@ func (s *v1Entries) GetLabelWriteEntries(bucket *Bucket, metricName string, labe
 	}}
 
 	for labelName, vv := range labels {
+		// HashValuePrefixFor(__names__) | label_name _ rk = 0
+		// shared by all blobs in the bucket shard, value 0 to keep it when label deleted.
+		entries = append(entries, Entry{
+			TableName:  bucket.TableName,
+			HashValue:  bucket.HashValuePrefixFor(shard, metricName, labelNamesKey),
+			RangeValue: RangeValueLabelName(nil).EncodeRangeValue([]byte(labelName)),
+			Value:      []byte{0},
+		})
+
 		for _, labelValue := range vv {
 			labelValueBytes := []byte(labelValue)
 
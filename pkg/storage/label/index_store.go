@@ -9,6 +9,7 @@ import (
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
 	"github.com/innoai-tech/media-toolkit/pkg/storage/label/index"
+	"github.com/innoai-tech/media-toolkit/pkg/util/stringutil"
 	"github.com/prometheus/prometheus/model/labels"
 )
 
@@ -16,13 +17,40 @@ type IndexStore interface {
 	GetBlobRefs(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string, matchers ...*labels.Matcher) ([]blob.Ref, error)
 	GetBlobs(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string, matchers ...*labels.Matcher) ([]blob.Info, error)
 	RefsToBlobs(ctx context.Context, refs []blob.Ref, metricName string) ([]blob.Info, error)
+	// DeletedRefs returns refs marked as deleted, which excluded by RefsToBlobs
+	DeletedRefs(ctx context.Context, refs []blob.Ref, metricName string) ([]blob.Ref, error)
+	LabelNames(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string) ([]string, error)
+	LabelValues(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string, labelName string, matchers ...*labels.Matcher) ([]string, error)
 }
 
-func NewIndexStore(schemaCfg config.SchemaConfig, index index.Client, schema index.BlobStoreSchema) IndexStore {
+type IndexStoreOptions struct {
+	// MaxBlobsToFilterByLabels is the max count of candidate blobs,
+	// which will be filtered by their own labels instead of looking up the label index again.
+	MaxBlobsToFilterByLabels int
+}
+
+type IndexStoreOptFunc func(o *IndexStoreOptions)
+
+func WithMaxBlobsToFilterByLabels(max int) IndexStoreOptFunc {
+	return func(o *IndexStoreOptions) {
+		o.MaxBlobsToFilterByLabels = max
+	}
+}
+
+func NewIndexStore(schemaCfg config.SchemaConfig, index index.Client, schema index.BlobStoreSchema, opts ...IndexStoreOptFunc) IndexStore {
+	options := IndexStoreOptions{
+		MaxBlobsToFilterByLabels: 1000,
+	}
+
+	for i := range opts {
+		opts[i](&options)
+	}
+
 	return &indexStore{
 		schema:    schema,
 		index:     index,
 		schemaCfg: schemaCfg,
+		options:   options,
 	}
 }
 
@@ -30,6 +58,7 @@ type indexStore struct {
 	schema    index.BlobStoreSchema
 	index     index.Client
 	schemaCfg config.SchemaConfig
+	options   IndexStoreOptions
 }
 
 func (c *indexStore) GetBlobs(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string, matchers ...*labels.Matcher) ([]blob.Info, error) {
@@ -41,18 +70,56 @@ func (c *indexStore) GetBlobs(ctx context.Context, timeRange blob.TimeRange, use
 }
 
 func (c *indexStore) RefsToBlobs(ctx context.Context, refs []blob.Ref, metricName string) ([]blob.Info, error) {
+	blobSet, deletedBlobs, err := c.lookupBlobs(ctx, refs, metricName)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make(blob.Infos, 0, len(blobSet))
+
+	for i := range blobSet {
+		b := blobSet[i]
+		if _, ok := deletedBlobs[b.RefKey]; !ok {
+			blobs = append(blobs, *b)
+		}
+	}
+
+	sort.Sort(blobs)
+
+	return blobs, nil
+}
+
+func (c *indexStore) DeletedRefs(ctx context.Context, refs []blob.Ref, metricName string) ([]blob.Ref, error) {
+	_, deletedBlobs, err := c.lookupBlobs(ctx, refs, metricName)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]blob.Ref, 0, len(deletedBlobs))
+
+	for _, ref := range refs {
+		if _, ok := deletedBlobs[c.schemaCfg.ExternalKey(ref)]; ok {
+			deleted = append(deleted, ref)
+		}
+	}
+
+	return deleted, nil
+}
+
+// lookupBlobs returns blobs of refs with labels, and ids of blobs marked as deleted
+func (c *indexStore) lookupBlobs(ctx context.Context, refs []blob.Ref, metricName string) (map[string]*blob.Info, map[string]struct{}, error) {
 	queries := make([]index.Query, 0)
 	for _, ref := range refs {
 		q, err := c.schema.GetMetricLabelValues(ref.TimeRange, ref.UserID, metricName, c.schemaCfg.ExternalKey(ref))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		queries = append(queries, q...)
 	}
 
 	entries, err := c.lookupEntriesByQueries(ctx, queries)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	blobSet := make(map[string]*blob.Info)
@@ -63,7 +130,7 @@ func (c *indexStore) RefsToBlobs(ctx context.Context, refs []blob.Ref, metricNam
 
 		rk, err := index.DecodeRangeValue(e.RangeValue)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		labelName := rk.(index.RangeValueLabelValue).LabelName()
 		blobID := e.HashValue
@@ -76,7 +143,7 @@ func (c *indexStore) RefsToBlobs(ctx context.Context, refs []blob.Ref, metricNam
 		if !ok {
 			bb, err := blob.ParseExternalKey(blobID, "")
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			bb.RefKey = c.schemaCfg.ExternalKey(bb.Ref)
 			bb.Labels = map[string][]string{}
@@ -86,18 +153,94 @@ func (c *indexStore) RefsToBlobs(ctx context.Context, refs []blob.Ref, metricNam
 		b.Labels[labelName] = append(b.Labels[labelName], string(e.Value))
 	}
 
-	blobs := make(blob.Infos, 0, len(blobSet))
+	return blobSet, deletedBlobs, nil
+}
 
-	for i := range blobSet {
-		b := blobSet[i]
-		if _, ok := deletedBlobs[b.RefKey]; !ok {
-			blobs = append(blobs, *b)
+func (c *indexStore) LabelNames(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string) ([]string, error) {
+	queries, err := c.schema.GetReadQueriesForMetricLabelNames(timeRange, userID, metricName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := c.lookupEntriesByQueries(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	names := stringutil.NewUniqueStrings(0)
+
+	for i := range entries {
+		rk, err := index.DecodeRangeValue(entries[i].RangeValue)
+		if err != nil {
+			return nil, err
+		}
+		labelName := rk.(index.RangeValueLabelName).LabelName()
+		if labelName == blob.LabelDeleted {
+			continue
 		}
+		names.Add(labelName)
 	}
 
-	sort.Sort(blobs)
+	return names.Strings(), nil
+}
 
-	return blobs, nil
+func (c *indexStore) LabelValues(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string, labelName string, matchers ...*labels.Matcher) ([]string, error) {
+	queries, err := c.schema.GetReadQueriesForMetricLabel(timeRange, userID, metricName, labelName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := c.lookupEntriesByQueries(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return []string{}, nil
+	}
+
+	deletedIDs, err := c.lookupIdsByMatcher(ctx, timeRange, userID, metricName, labels.MustNewMatcher(labels.MatchNotEqual, blob.LabelDeleted, ""), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var matchedIDs map[string]struct{}
+
+	if len(matchers) > 0 {
+		ids, err := c.lookupBlobMatchers(ctx, timeRange, userID, metricName, matchers)
+		if err != nil {
+			return nil, err
+		}
+		matchedIDs = toStringSet(ids)
+	}
+
+	deletedSet := toStringSet(deletedIDs)
+	values := stringutil.NewUniqueStrings(0)
+
+	for i := range entries {
+		e := entries[i]
+
+		rk, err := index.DecodeRangeValue(e.RangeValue)
+		if err != nil {
+			return nil, err
+		}
+
+		blobID := rk.(index.RangeValueLabelValueBlob).BlobID()
+
+		if _, ok := deletedSet[blobID]; ok {
+			continue
+		}
+
+		if matchedIDs != nil {
+			if _, ok := matchedIDs[blobID]; !ok {
+				continue
+			}
+		}
+
+		values.Add(string(e.Value))
+	}
+
+	return values.Strings(), nil
 }
 
 func (c *indexStore) GetBlobRefs(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string, matchers ...*labels.Matcher) ([]blob.Ref, error) {
@@ -111,47 +254,122 @@ func (c *indexStore) GetBlobRefs(ctx context.Context, timeRange blob.TimeRange,
 }
 
 func (c *indexStore) lookupBlobMatchers(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string, matchers []*labels.Matcher) ([]string, error) {
-	incomingIDs := make(chan []string)
-	incomingErrors := make(chan error)
+	first, rest := planMatchers(matchers)
+
+	// when first is nil, all blobs of the metric are candidates,
+	// which are required by matchers matching blobs without the label.
+	ids, err := c.lookupIdsByMatcher(ctx, timeRange, userID, metricName, first, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	if len(matchers) == 0 {
-		matchers = []*labels.Matcher{nil}
+	if len(rest) == 0 || len(ids) == 0 {
+		return ids, nil
 	}
 
+	// The idea is that if we have 2 matchers, and if one returns a lot of
+	// blobs and the other returns only 10 (a few), we don't lookup the first one at all.
+	// We just manually filter through the 10 blobs again by their labels,
+	// saving us from looking up and intersecting a lot of blobs.
+	if len(ids) <= c.options.MaxBlobsToFilterByLabels {
+		return c.filterIdsByLabels(ctx, metricName, ids, rest)
+	}
+
+	return c.filterIdsByIndex(ctx, timeRange, userID, metricName, ids, rest)
+}
+
+func (c *indexStore) filterIdsByLabels(ctx context.Context, metricName string, ids []string, matchers []*labels.Matcher) ([]string, error) {
+	queries := make([]index.Query, 0, len(ids))
+	for _, blobID := range ids {
+		b, err := blob.ParseExternalKey(blobID, "")
+		if err != nil {
+			return nil, err
+		}
+		q, err := c.schema.GetMetricLabelValues(b.TimeRange, b.UserID, metricName, blobID)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q...)
+	}
+
+	entries, err := c.lookupEntriesByQueries(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	labelsOfBlobs := make(map[string]blob.Labels, len(ids))
+
+	for i := range entries {
+		e := entries[i]
+
+		rk, err := index.DecodeRangeValue(e.RangeValue)
+		if err != nil {
+			return nil, err
+		}
+
+		lbs, ok := labelsOfBlobs[e.HashValue]
+		if !ok {
+			lbs = blob.Labels{}
+			labelsOfBlobs[e.HashValue] = lbs
+		}
+
+		labelName := rk.(index.RangeValueLabelValue).LabelName()
+		lbs[labelName] = append(lbs[labelName], string(e.Value))
+	}
+
+	filtered := make([]string, 0, len(ids))
+
+	for _, blobID := range ids {
+		if matchLabels(labelsOfBlobs[blobID], matchers) {
+			filtered = append(filtered, blobID)
+		}
+	}
+
+	return filtered, nil
+}
+
+func (c *indexStore) filterIdsByIndex(ctx context.Context, timeRange blob.TimeRange, userID string, metricName string, ids []string, matchers []*labels.Matcher) ([]string, error) {
 	for _, matcher := range matchers {
-		go func(matcher *labels.Matcher) {
-			ids, err := c.lookupIdsByMatcher(ctx, timeRange, userID, metricName, matcher, nil)
+		if len(ids) == 0 {
+			break
+		}
+
+		// blobs without the label never match.
+		if !matcher.Matches("") {
+			matched, err := c.lookupIdsByMatcher(ctx, timeRange, userID, metricName, matcher, nil)
 			if err != nil {
-				incomingErrors <- err
-				return
+				return nil, err
 			}
-			incomingIDs <- ids
-		}(matcher)
-	}
+			ids = intersectStrings(ids, matched)
+			continue
+		}
 
-	var ids []string
-	var initialized bool
+		// blobs without the label match,
+		// so exclude the blobs which have the label but not matched.
+		var excluded []string
 
-	var preIntersectionCount int
+		switch matcher.Type {
+		case labels.MatchNotEqual, labels.MatchNotRegexp:
+			inverse := labels.MustNewMatcher(inverseMatchType(matcher.Type), matcher.Name, matcher.Value)
 
-	for i := 0; i < len(matchers); i++ {
-		select {
-		case incoming := <-incomingIDs:
-			preIntersectionCount += len(incoming)
-			if !initialized {
-				ids = incoming
-				initialized = true
-			} else {
-				ids = intersectStrings(ids, incoming)
+			matched, err := c.lookupIdsByMatcher(ctx, timeRange, userID, metricName, inverse, nil)
+			if err != nil {
+				return nil, err
 			}
-		case err := <-incomingErrors:
-			// The idea is that if we have 2 matchers, and if one returns a lot of
-			// series and the other returns only 10 (a few), we don't lookup the first one at all.
-			// We just manually filter through the 10 series again using "filterChunksByMatchers",
-			// saving us from looking up and intersecting a lot of series.
-			// TODO
-			return nil, err
+			excluded = matched
+		default:
+			withLabel, err := c.lookupIdsByMatcher(ctx, timeRange, userID, metricName, labels.MustNewMatcher(labels.MatchRegexp, matcher.Name, ".*"), nil)
+			if err != nil {
+				return nil, err
+			}
+			matched, err := c.lookupIdsByMatcher(ctx, timeRange, userID, metricName, matcher, nil)
+			if err != nil {
+				return nil, err
+			}
+			excluded = subtractStrings(withLabel, matched)
 		}
+
+		ids = subtractStrings(ids, excluded)
 	}
 
 	return ids, nil
@@ -165,6 +383,16 @@ func (c *indexStore) lookupIdsByMatcher(ctx context.Context, timeRange TimeRange
 		queries, err = c.schema.GetReadQueriesForMetric(timeRange, userID, metricName)
 	} else if matcher.Type == labels.MatchEqual {
 		queries, err = c.schema.GetReadQueriesForMetricLabelValue(timeRange, userID, metricName, matcher.Name, matcher.Value)
+	} else if set := setMatches(matcher); len(set) > 0 {
+		// lookup the value index for each value of set like regexp,
+		// instead of scanning all values of the label.
+		for _, v := range set {
+			q, e := c.schema.GetReadQueriesForMetricLabelValue(timeRange, userID, metricName, matcher.Name, v)
+			if e != nil {
+				return nil, e
+			}
+			queries = append(queries, q...)
+		}
 	} else {
 		queries, err = c.schema.GetReadQueriesForMetricLabel(timeRange, userID, metricName, matcher.Name)
 	}
@@ -248,8 +476,8 @@ func (c *indexStore) lookupEntriesByQueries(ctx context.Context, queries []index
 	chEntry := make(chan *index.Entry)
 	entries := make([]index.Entry, 0)
 
+	wg.Add(1)
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
 
 		for entry := range chEntry {
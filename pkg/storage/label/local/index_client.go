@@ -220,6 +220,10 @@ func (b *indexClient) WriteToDB(ctx context.Context, db *pebble.DB, writes *Tabl
 		}
 	}
 
+	if index.SyncFromContext(ctx) {
+		return batch.Commit(pebble.Sync)
+	}
+
 	return batch.Commit(pebble.NoSync)
 }
 
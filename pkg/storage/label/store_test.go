@@ -2,6 +2,7 @@ package label_test
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"testing"
 	"time"
@@ -64,6 +65,94 @@ func TestStore(t *testing.T) {
 			})
 		})
 
+		t.Run("GetBlobRefs With Matchers", func(t *testing.T) {
+			cases := []struct {
+				name     string
+				matchers []*labels.Matcher
+				count    int
+			}{
+				{
+					name:     "negative",
+					matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchNotEqual, "tag", "wine")},
+					count:    5000,
+				},
+				{
+					name:     "negative regexp",
+					matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchNotRegexp, "tag", "face|wine")},
+					count:    0,
+				},
+				{
+					name:     "negative on missing label",
+					matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchNotEqual, "missing", "x")},
+					count:    10000,
+				},
+				{
+					name:     "empty on missing label",
+					matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "missing", "")},
+					count:    10000,
+				},
+				{
+					name: "positive with negative",
+					matchers: []*labels.Matcher{
+						labels.MustNewMatcher(labels.MatchNotEqual, "tag", "face"),
+						labels.MustNewMatcher(labels.MatchEqual, "mediaType", "text/plain"),
+					},
+					count: 0,
+				},
+				{
+					name: "set regexp with positive",
+					matchers: []*labels.Matcher{
+						labels.MustNewMatcher(labels.MatchRegexp, "mediaType", "text/plain|text/html"),
+						labels.MustNewMatcher(labels.MatchEqual, "instanceID", "1"),
+					},
+					count: 3333,
+				},
+				{
+					name: "regexp with negative",
+					matchers: []*labels.Matcher{
+						labels.MustNewMatcher(labels.MatchRegexp, "mediaType", "text/.+"),
+						labels.MustNewMatcher(labels.MatchNotEqual, "instanceID", "1"),
+					},
+					count: 6667,
+				},
+			}
+
+			for _, max := range []int{len(sampleBlobs), 0} {
+				r := label.NewIndexStore(c.Schema, indexClient, schema, label.WithMaxBlobsToFilterByLabels(max))
+
+				for i := range cases {
+					c := cases[i]
+
+					t.Run(fmt.Sprintf("%s when filter under %d", c.name, max), func(t *testing.T) {
+						blobRefs, err := r.GetBlobRefs(context.Background(), blob.SinceFrom(dayFrom, 24*time.Hour), blob.DefaultUser, label.MetricLabel, c.matchers...)
+						Expect(t, err, Be[error](nil))
+						Expect(t, len(blobRefs), Be(c.count))
+					})
+				}
+			}
+		})
+
+		t.Run("LabelNames", func(t *testing.T) {
+			names, err := r.LabelNames(context.Background(), blob.SinceFrom(dayFrom, 24*time.Hour), blob.DefaultUser, label.MetricLabel)
+			Expect(t, err, Be[error](nil))
+			Expect(t, names, Equal([]string{"instanceID", "mediaType", "tag"}))
+		})
+
+		t.Run("LabelValues", func(t *testing.T) {
+			t.Run("Without Filter", func(t *testing.T) {
+				values, err := r.LabelValues(context.Background(), blob.SinceFrom(dayFrom, 24*time.Hour), blob.DefaultUser, label.MetricLabel, "mediaType")
+				Expect(t, err, Be[error](nil))
+				Expect(t, values, Equal([]string{"text/html", "text/plain"}))
+			})
+
+			t.Run("With Filter", func(t *testing.T) {
+				filter := labels.MustNewMatcher(labels.MatchEqual, "tag", "wine")
+				values, err := r.LabelValues(context.Background(), blob.SinceFrom(dayFrom, 24*time.Hour), blob.DefaultUser, label.MetricLabel, "mediaType", filter)
+				Expect(t, err, Be[error](nil))
+				Expect(t, values, Equal([]string{"text/html"}))
+			})
+		})
+
 		t.Run("GetBlob", func(t *testing.T) {
 			filters := labels.MustNewMatcher(labels.MatchEqual, "mediaType", "text/plain")
 			blobs, err := r.GetBlobs(context.Background(), blob.SinceFrom(dayFrom, 24*time.Hour), blob.DefaultUser, label.MetricLabel, filters)
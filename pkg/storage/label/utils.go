@@ -1,9 +1,13 @@
 package label
 
 import (
+	"math"
+	"sort"
 	"strings"
 	"unicode/utf8"
 
+	"github.com/prometheus/prometheus/model/labels"
+
 	"github.com/innoai-tech/media-toolkit/pkg/types"
 
 	"github.com/innoai-tech/media-toolkit/pkg/blob"
@@ -51,6 +55,33 @@ func uniqueStrings(cs []string) []string {
 	return result
 }
 
+func subtractStrings(left, right []string) []string {
+	var (
+		i, j   = 0, 0
+		result = []string{}
+	)
+	for i < len(left) {
+		if j >= len(right) || left[i] < right[j] {
+			result = append(result, left[i])
+			i++
+			continue
+		}
+		if left[i] == right[j] {
+			i++
+		}
+		j++
+	}
+	return result
+}
+
+func toStringSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
 func intersectStrings(left, right []string) []string {
 	var (
 		i, j   = 0, 0
@@ -70,6 +101,103 @@ func intersectStrings(left, right []string) []string {
 	return result
 }
 
+// planMatchers picks the most selective matcher to lookup candidate blobs,
+// and returns the rest to filter the candidates.
+// first will be nil when all matchers could match blobs without the label.
+func planMatchers(matchers []*labels.Matcher) (first *labels.Matcher, rest []*labels.Matcher) {
+	if len(matchers) == 0 {
+		return nil, nil
+	}
+
+	sorted := make([]*labels.Matcher, len(matchers))
+	copy(sorted, matchers)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return matcherCost(sorted[i]) < matcherCost(sorted[j])
+	})
+
+	if sorted[0].Matches("") {
+		return nil, sorted
+	}
+
+	return sorted[0], sorted[1:]
+}
+
+// matcherCost estimates the cost to lookup blobs by the matcher in label index.
+func matcherCost(m *labels.Matcher) int {
+	if m.Matches("") {
+		// could match blobs without the label, never used to lookup candidates.
+		return math.MaxInt
+	}
+	switch m.Type {
+	case labels.MatchEqual:
+		return 0
+	case labels.MatchRegexp:
+		if set := setMatches(m); len(set) > 0 {
+			return len(set)
+		}
+		return 2 << 10
+	}
+	return 1 << 10
+}
+
+// setMatches returns values of the set like regexp matcher (=~"a|b|c|d|...")
+func setMatches(m *labels.Matcher) []string {
+	if m.Type != labels.MatchRegexp || m.Matches("") {
+		return nil
+	}
+	return FindSetMatches(m.Value)
+}
+
+func inverseMatchType(t labels.MatchType) labels.MatchType {
+	switch t {
+	case labels.MatchEqual:
+		return labels.MatchNotEqual
+	case labels.MatchNotEqual:
+		return labels.MatchEqual
+	case labels.MatchRegexp:
+		return labels.MatchNotRegexp
+	default:
+		return labels.MatchRegexp
+	}
+}
+
+// matchLabels checks labels of blob with all matchers.
+// As Prometheus does, blob without the label is treated as the label with empty value.
+// For label with multi values, positive matcher requires any value matched,
+// and negative matcher requires all values matched.
+func matchLabels(lbs blob.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !matchLabel(lbs[m.Name], m) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchLabel(values []string, m *labels.Matcher) bool {
+	if len(values) == 0 {
+		return m.Matches("")
+	}
+
+	switch m.Type {
+	case labels.MatchNotEqual, labels.MatchNotRegexp:
+		for _, v := range values {
+			if !m.Matches(v) {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, v := range values {
+			if m.Matches(v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 // Bitmap used by func isRegexMetaCharacter to check whether a character needs to be escaped.
 var regexMetaCharacterBytes [16]byte
 
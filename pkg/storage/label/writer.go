@@ -24,6 +24,15 @@ type Writer interface {
 	DelLabels(ctx context.Context, timeRange TimeRange, metricName string, ref blob.Ref, labels blob.Labels) error
 
 	DelOne(ctx context.Context, timeRange TimeRange, metricName string, ref blob.Ref) error
+
+	UpdateLabels(ctx context.Context, metricName string, updates []LabelsUpdate) error
+}
+
+// LabelsUpdate labels to put and to delete of one blob
+type LabelsUpdate struct {
+	Ref    blob.Ref
+	Put    blob.Labels
+	Delete blob.Labels
 }
 
 func NewWriter(schemaCfg config.SchemaConfig, indexWriter IndexWriter, schema index.BlobStoreSchema) Writer {
@@ -88,45 +97,78 @@ func (c *writer) DelLabels(ctx context.Context, timeRange TimeRange, metricName
 	return nil
 }
 
+// UpdateLabels writes all updates in one write batch.
+// Deletes are applied after puts when the same label is in both.
+func (c *writer) UpdateLabels(ctx context.Context, metricName string, updates []LabelsUpdate) error {
+	batch := c.indexWriter.NewWriteBatch()
+
+	for _, u := range updates {
+		if len(u.Put) > 0 {
+			if err := c.addIndexEntries(batch, u.Ref.TimeRange, metricName, u.Ref, u.Put); err != nil {
+				return err
+			}
+		}
+		if len(u.Delete) > 0 {
+			if err := c.addIndexEntriesForLabelDelete(batch, u.Ref.TimeRange, metricName, u.Ref, u.Delete); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.indexWriter.BatchWrite(ctx, batch)
+}
+
 func (c *writer) calculateIndexEntries(ctx context.Context, timeRange TimeRange, metricName string, ref blob.Ref, labels blob.Labels) (index.WriteBatch, error) {
+	result := c.indexWriter.NewWriteBatch()
+	if err := c.addIndexEntries(result, timeRange, metricName, ref, labels); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *writer) calculateIndexEntriesForLabelDelete(ctx context.Context, timeRange TimeRange, metricName string, ref blob.Ref, labels blob.Labels) (index.WriteBatch, error) {
+	result := c.indexWriter.NewWriteBatch()
+	if err := c.addIndexEntriesForLabelDelete(result, timeRange, metricName, ref, labels); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *writer) addIndexEntries(result index.WriteBatch, timeRange TimeRange, metricName string, ref blob.Ref, labels blob.Labels) error {
 	entries := make([]index.Entry, 0)
 
 	keys, labelEntries, err := c.schema.GetCacheKeysAndLabelWriteEntries(timeRange, ref.UserID, metricName, c.schemaCfg.ExternalKey(ref), labels)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	for i, _ := range keys {
 		entries = append(entries, labelEntries[i]...)
 	}
 
-	result := c.indexWriter.NewWriteBatch()
-
 	for i := range entries {
 		result.Add(entries[i])
 	}
 
-	return result, nil
+	return nil
 }
 
-func (c *writer) calculateIndexEntriesForLabelDelete(ctx context.Context, timeRange TimeRange, metricName string, ref blob.Ref, labels blob.Labels) (index.WriteBatch, error) {
+func (c *writer) addIndexEntriesForLabelDelete(result index.WriteBatch, timeRange TimeRange, metricName string, ref blob.Ref, labels blob.Labels) error {
 	entries := make([]index.Entry, 0)
 
 	keys, labelEntries, err := c.schema.GetCacheKeysAndLabelWriteEntries(timeRange, ref.UserID, metricName, c.schemaCfg.ExternalKey(ref), labels)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	for i, _ := range keys {
 		entries = append(entries, labelEntries[i]...)
 	}
 
-	result := c.indexWriter.NewWriteBatch()
-
 	for i := range entries {
 		e := entries[i]
 
-		// skip range value metric
+		// skip range value metric and label name, which are shared with other labels or blobs
 		if bytes.Equal(e.Value, []byte{0}) {
 			continue
 		}
@@ -134,5 +176,5 @@ func (c *writer) calculateIndexEntriesForLabelDelete(ctx context.Context, timeRa
 		result.Delete(e)
 	}
 
-	return result, nil
+	return nil
 }
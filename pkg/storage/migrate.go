@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+)
+
+type Migrator interface {
+	// MigrateContent converts content committed before refcounted, identical content will be shared.
+	MigrateContent(ctx context.Context) (*MigrationReport, error)
+}
+
+type MigrationReport struct {
+	Retained int `json:"retained"`
+}
+
+// retainer of local content store
+type retainer interface {
+	Retain(ctx context.Context, ref blob.Ref) error
+}
+
+func (s *store) migrated() string {
+	return filepath.Join(s.c.Storage.Root, "objects", ".migrated")
+}
+
+// migrateContentOnce migrates content when store opened first time after refcounted
+func (s *store) migrateContentOnce(ctx context.Context) error {
+	if _, err := os.Stat(s.migrated()); err == nil {
+		return nil
+	}
+
+	report, err := s.MigrateContent(ctx)
+	if err != nil {
+		return err
+	}
+
+	if report.Retained > 0 {
+		logr.FromContextOrDiscard(ctx).Info("content migrated", "retained", report.Retained)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.migrated()), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.migrated(), []byte(time.Now().Format(time.RFC3339)), 0644)
+}
+
+func (s *store) MigrateContent(ctx context.Context) (*MigrationReport, error) {
+	report := &MigrationReport{}
+
+	r, ok := s.localContentStore().(retainer)
+	if !ok {
+		return report, nil
+	}
+
+	days, err := os.ReadDir(filepath.Join(s.c.Storage.Root, "blobs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, nil
+		}
+		return nil, err
+	}
+
+	for _, d := range days {
+		day, err := strconv.ParseInt(d.Name(), 10, 64)
+		if err != nil || !d.IsDir() {
+			continue
+		}
+
+		if err := s.migrateDay(ctx, r, day, report); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func (s *store) migrateDay(ctx context.Context, r retainer, day int64, report *MigrationReport) error {
+	dayRange := blob.SinceFrom(types.TimeFromUnix(day*int64(24*time.Hour/time.Second)), 24*time.Hour-time.Millisecond)
+
+	contents, err := s.listContentsOfDay(day)
+	if err != nil {
+		return err
+	}
+
+	if len(contents) == 0 {
+		return nil
+	}
+
+	existed := map[string]bool{}
+	for _, p := range contents {
+		existed[p] = true
+	}
+
+	indexStore, err := s.labelIndexStoreFor(ctx, dayRange)
+	if err != nil {
+		return err
+	}
+
+	// deleted blobs included, which still hold content
+	refs, err := indexStore.GetBlobRefs(ctx, dayRange, blob.DefaultUser, label.MetricLabel)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		if blob.UnixDay(ref.From) != day || !existed[ref.BlobPath("")] {
+			continue
+		}
+		if err := r.Retain(ctx, ref); err != nil {
+			return err
+		}
+		report.Retained++
+	}
+
+	return nil
+}
+
+func (s *store) labelNamesBackfilled() string {
+	return filepath.Join(s.c.Storage.Root, "labels", ".names-backfilled")
+}
+
+// backfillLabelNamesOnce indexes label names of blobs committed before label names indexed.
+func (s *store) backfillLabelNamesOnce(ctx context.Context) error {
+	if _, err := os.Stat(s.labelNamesBackfilled()); err == nil {
+		return nil
+	}
+
+	if len(s.c.Schema.Configs) > 0 {
+		backfilled := 0
+
+		for day, today := blob.UnixDay(s.c.Schema.Configs[0].From), blob.UnixDay(types.Now()); day <= today; day++ {
+			n, err := s.backfillLabelNamesOfDay(ctx, day)
+			if err != nil {
+				return err
+			}
+			backfilled += n
+		}
+
+		if backfilled > 0 {
+			logr.FromContextOrDiscard(ctx).Info("label names backfilled", "blobs", backfilled)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.labelNamesBackfilled()), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.labelNamesBackfilled(), []byte(time.Now().Format(time.RFC3339)), 0644)
+}
+
+func (s *store) backfillLabelNamesOfDay(ctx context.Context, day int64) (int, error) {
+	dayRange := blob.SinceFrom(types.TimeFromUnix(day*int64(24*time.Hour/time.Second)), 24*time.Hour-time.Millisecond)
+
+	blobs, err := s.Query(ctx, dayRange, blob.DefaultUser)
+	if err != nil {
+		return 0, err
+	}
+
+	updates := make([]label.LabelsUpdate, 0, len(blobs))
+
+	for _, b := range blobs {
+		if blob.UnixDay(b.From) != day {
+			continue
+		}
+		// label names indexed with labels, rewriting existed labels is idempotent.
+		updates = append(updates, label.LabelsUpdate{Ref: b.Ref, Put: b.Labels})
+	}
+
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	labelWriter, err := s.labelWriterFor(ctx, dayRange)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := labelWriter.UpdateLabels(ctx, label.MetricLabel, updates); err != nil {
+		return 0, err
+	}
+
+	return len(updates), nil
+}
@@ -1,6 +1,22 @@
 package mime
 
+import "mime"
+
 const (
 	MediaTypeImageJPEG = "image/jpeg"
 	MediaTypeVideoMP4  = "video/mp4"
 )
+
+// ExtensionOf returns file extension of media type, empty when unknown
+func ExtensionOf(mediaType string) string {
+	switch mediaType {
+	case MediaTypeImageJPEG:
+		return ".jpg"
+	case MediaTypeVideoMP4:
+		return ".mp4"
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
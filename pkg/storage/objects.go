@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	contentlocal "github.com/innoai-tech/media-toolkit/pkg/storage/content/local"
+	contents3 "github.com/innoai-tech/media-toolkit/pkg/storage/content/s3"
+)
+
+// newContentStore creates content store, which routes content of blob
+// to the store of the ObjectType configured by the period of blob.
+// All content ingested in local store, and moved when committed.
+func newContentStore(c config.Config) (content.Store, error) {
+	local, err := contentlocal.NewStore(c.Storage.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := &periodContentStore{
+		schema: c.Schema,
+		local:  local,
+		stores: map[string]content.Store{},
+	}
+
+	for _, pc := range c.Schema.Configs {
+		objectType := objectTypeOf(pc)
+		if _, ok := ps.stores[objectType]; ok {
+			continue
+		}
+
+		switch objectType {
+		case config.ObjectTypeLocal:
+			ps.stores[objectType] = local
+		case config.ObjectTypeS3:
+			s, err := contents3.NewStore(c.Storage.S3, local)
+			if err != nil {
+				return nil, err
+			}
+			ps.stores[objectType] = s
+		default:
+			return nil, fmt.Errorf("unsupported object type %s", pc.ObjectType)
+		}
+	}
+
+	return ps, nil
+}
+
+func objectTypeOf(pc config.PeriodConfig) string {
+	if pc.ObjectType == "" {
+		return config.ObjectTypeLocal
+	}
+	return pc.ObjectType
+}
+
+type periodContentStore struct {
+	schema config.SchemaConfig
+	local  content.Store
+	stores map[string]content.Store
+}
+
+// storeFor returns store of the period of blob, local store used when no period matched.
+func (s *periodContentStore) storeFor(ref blob.Ref) content.Store {
+	pc, err := s.schema.SchemaForTime(ref.From)
+	if err != nil {
+		return s.local
+	}
+	return s.stores[objectTypeOf(pc)]
+}
+
+func (s *periodContentStore) Delete(ctx context.Context, ref blob.Ref) error {
+	return s.storeFor(ref).Delete(ctx, ref)
+}
+
+func (s *periodContentStore) ReaderAt(ctx context.Context, ref blob.Ref) (content.ReaderAt, error) {
+	return s.storeFor(ref).ReaderAt(ctx, ref)
+}
+
+func (s *periodContentStore) PresignedURL(ctx context.Context, ref blob.Ref) (string, error) {
+	if p, ok := s.storeFor(ref).(content.Presigner); ok {
+		return p.PresignedURL(ctx, ref)
+	}
+	return "", nil
+}
+
+func (s *periodContentStore) Writer(ctx context.Context, opts ...blob.Opt) (content.Writer, error) {
+	w, err := s.local.Writer(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return s.importingWriter(w), nil
+}
+
+func (s *periodContentStore) ResumeWriter(ctx context.Context, ref string) (content.Writer, error) {
+	w, err := s.local.ResumeWriter(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.importingWriter(w), nil
+}
+
+// importingWriter picks target store when committed, since time range of blob could be changed by commit opts.
+func (s *periodContentStore) importingWriter(w content.Writer) content.Writer {
+	return content.NewImportingWriter(w, s.local, func(info blob.Info) content.Importer {
+		cs := s.storeFor(info.Ref)
+		if cs == s.local {
+			return nil
+		}
+		if importer, ok := cs.(content.Importer); ok {
+			return importer
+		}
+		return nil
+	})
+}
+
+func (s *periodContentStore) Abort(ctx context.Context, ref string) error {
+	return s.local.Abort(ctx, ref)
+}
+
+func (s *periodContentStore) TempFile(ctx context.Context) (*os.File, error) {
+	return s.local.TempFile(ctx)
+}
+
+// isLocal returns whether content of blob stored in local
+func (s *periodContentStore) isLocal(ref blob.Ref) bool {
+	return s.storeFor(ref) == s.local
+}
+
+// PresignedURL returns url to download content from object storage directly,
+// empty when content stored in local.
+func (s *store) PresignedURL(ctx context.Context, ref blob.Ref) (string, error) {
+	if p, ok := s.Store.(content.Presigner); ok {
+		return p.PresignedURL(ctx, ref)
+	}
+	return "", nil
+}
+
+// localContentStore returns the local store under tiers and periods
+func (s *store) localContentStore() content.Store {
+	return localStoreOf(s.Store)
+}
+
+func localStoreOf(cs content.Store) content.Store {
+	if ts, ok := cs.(*tieredContentStore); ok {
+		cs = ts.Store
+	}
+	if ps, ok := cs.(*periodContentStore); ok {
+		return ps.local
+	}
+	return cs
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content/s3/s3test"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	. "github.com/octohelm/x/testing"
+	"github.com/pkg/errors"
+)
+
+func TestStoreObjectType(t *testing.T) {
+	srv, s3c := s3test.NewServer()
+	defer srv.Close()
+
+	s, c := newTestStore(t, func(c *config.Config) {
+		c.Storage.S3 = s3c
+
+		s3Period := c.Schema.Configs[0]
+		s3Period.From = types.TimeFromUnixNano(time.Now().Add(-24 * time.Hour).UnixNano())
+		s3Period.ObjectType = config.ObjectTypeS3
+		c.Schema.Configs = append(c.Schema.Configs[0:1:1], s3Period)
+	})
+
+	tagged := blob.WithLabels(blob.Labels{"_tag": {"test"}})
+
+	readAll := func(t *testing.T, ref blob.Ref) string {
+		r, err := s.ReaderAt(context.Background(), ref)
+		Expect(t, err, Be[error](nil))
+		defer r.Close()
+		data, _ := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+		return string(data)
+	}
+
+	t.Run("Content of s3 period", func(t *testing.T) {
+		info := commitBlob(t, s, "in s3", tagged)
+		Expect(t, readAll(t, info.Ref), Be("in s3"))
+
+		_, err := os.Stat(info.BlobPath(c.Storage.Root))
+		Expect(t, os.IsNotExist(err), Be(true))
+
+		u, err := s.PresignedURL(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, u != "", Be(true))
+
+		report, err := s.Check(context.Background(), blob.Last(time.Hour), false)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.DanglingRefs), Be(0))
+
+		Expect(t, s.Delete(context.Background(), info.Ref), Be[error](nil))
+	})
+
+	t.Run("Content of local period", func(t *testing.T) {
+		from := types.TimeFromUnixNano(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+		info := commitBlob(t, s, "in local", blob.WithFromThough(from), tagged)
+		Expect(t, readAll(t, info.Ref), Be("in local"))
+
+		_, err := os.Stat(info.BlobPath(c.Storage.Root))
+		Expect(t, err, Be[error](nil))
+
+		u, err := s.PresignedURL(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, u, Be(""))
+	})
+}
+
+func TestStoreDedup(t *testing.T) {
+	s, c := newTestStore(t)
+
+	day := func(d int, hour int) blob.Opt {
+		return blob.WithFromThough(types.TimeFromUnixNano(time.Date(2022, 6, d, hour, 0, 0, 0, time.UTC).UnixNano()))
+	}
+
+	tagged := blob.WithLabels(blob.Labels{"_tag": {"test"}})
+
+	sameFile := func(t *testing.T, a blob.Info, b blob.Info) bool {
+		fa, err := os.Stat(a.BlobPath(c.Storage.Root))
+		Expect(t, err, Be[error](nil))
+		fb, err := os.Stat(b.BlobPath(c.Storage.Root))
+		Expect(t, err, Be[error](nil))
+		return os.SameFile(fa, fb)
+	}
+
+	contentStore := s.(*store).Store
+
+	t.Run("Shared content deleted by last ref", func(t *testing.T) {
+		a1 := commitBlob(t, s, "same", day(1, 1), tagged)
+		a2 := commitBlob(t, s, "same", day(1, 2), tagged)
+		b := commitBlob(t, s, "same", day(2, 1), tagged)
+
+		Expect(t, sameFile(t, a1, b), Be(true))
+
+		Expect(t, contentStore.Delete(context.Background(), a1.Ref), Be[error](nil))
+		_, err := os.Stat(a2.BlobPath(c.Storage.Root))
+		Expect(t, err, Be[error](nil))
+
+		Expect(t, contentStore.Delete(context.Background(), a2.Ref), Be[error](nil))
+		_, err = os.Stat(a2.BlobPath(c.Storage.Root))
+		Expect(t, os.IsNotExist(err), Be(true))
+
+		r, err := s.ReaderAt(context.Background(), b.Ref)
+		Expect(t, err, Be[error](nil))
+		_ = r.Close()
+
+		Expect(t, errors.Is(contentStore.Delete(context.Background(), a2.Ref), content.ErrNotFound), Be(true))
+
+		Expect(t, contentStore.Delete(context.Background(), b.Ref), Be[error](nil))
+		_, err = os.Stat(filepath.Join(c.Storage.Root, "objects", b.Alg, b.Hex))
+		Expect(t, os.IsNotExist(err), Be(true))
+	})
+
+	t.Run("Migrate", func(t *testing.T) {
+		legacy := func(t *testing.T, data string, from blob.Opt) blob.Info {
+			info := blob.FromString(data, from, tagged)
+			bp := info.BlobPath(c.Storage.Root)
+			Expect(t, os.MkdirAll(filepath.Dir(bp), 0755), Be[error](nil))
+			Expect(t, os.WriteFile(bp, []byte(data), 0644), Be[error](nil))
+
+			w, _ := s.(*store).labelWriterFor(context.Background(), info.TimeRange)
+			Expect(t, w.Put(context.Background(), label.MetricLabel, []blob.Info{info}), Be[error](nil))
+			return info
+		}
+
+		a := legacy(t, "legacy", day(3, 1))
+		b := legacy(t, "legacy", day(4, 1))
+		Expect(t, sameFile(t, a, b), Be(false))
+
+		report, err := s.MigrateContent(context.Background())
+		Expect(t, err, Be[error](nil))
+		Expect(t, report.Retained, Be(2))
+		Expect(t, sameFile(t, a, b), Be(true))
+
+		Expect(t, contentStore.Delete(context.Background(), a.Ref), Be[error](nil))
+		r, err := s.ReaderAt(context.Background(), b.Ref)
+		Expect(t, err, Be[error](nil))
+		_ = r.Close()
+	})
+}
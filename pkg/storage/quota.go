@@ -0,0 +1,600 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+type QuotaManager interface {
+	Quota(ctx context.Context) (*QuotaState, error)
+}
+
+type QuotaState struct {
+	Enabled   bool                   `json:"enabled"`
+	Evict     bool                   `json:"evict"`
+	MaxBytes  int64                  `json:"maxBytes"`
+	UsedBytes int64                  `json:"usedBytes"`
+	Devices   map[string]DeviceQuota `json:"devices"`
+}
+
+type DeviceQuota struct {
+	MaxBytes  int64 `json:"maxBytes"`
+	UsedBytes int64 `json:"usedBytes"`
+}
+
+func (s *store) Quota(ctx context.Context) (*QuotaState, error) {
+	if s.quota == nil {
+		return &QuotaState{Devices: map[string]DeviceQuota{}}, nil
+	}
+	return s.quota.state(), nil
+}
+
+// newQuota creates quota seeded by scanning alive blobs in local disk, nil when disabled
+func newQuota(ctx context.Context, s *store) (*quota, error) {
+	c := s.c.Storage.Quota
+
+	if !c.Enabled() {
+		return nil, nil
+	}
+
+	if c.RetainLabel == "" {
+		c.RetainLabel = "retain"
+	}
+
+	q := &quota{
+		s:             s,
+		c:             c,
+		devices:       map[string]int64{},
+		contents:      map[digest.Digest]*contentUsage{},
+		devicesOldest: map[string]types.Time{},
+	}
+
+	if err := q.seed(ctx); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// quota tracks used bytes of blobs in local disk, and evicts oldest blobs when exceeded.
+// content shared by blobs of same digest counted once, and once for each device.
+type quota struct {
+	s        *store
+	c        config.QuotaConfig
+	mu       sync.Mutex
+	used     int64
+	devices  map[string]int64
+	contents map[digest.Digest]*contentUsage
+	// deleted blobs, which content still in local disk until reclaimed.
+	// content of blobs deleted before started not counted.
+	deleted []blob.Info
+	// oldest time of evictable blobs, and of each device when evicted by device.
+	// evictions start from it, rewound when blobs older added or became evictable.
+	oldest        types.Time
+	devicesOldest map[string]types.Time
+	// rewinds counts rewinding, which discards advancing of eviction scanned before
+	rewinds  int
+	evicting sync.Mutex
+}
+
+// contentUsage of content in local disk
+type contentUsage struct {
+	size int64
+	// blobs of the content by device
+	blobs map[string]int
+}
+
+func (q *quota) seed(ctx context.Context) error {
+	if len(q.s.c.Schema.Configs) == 0 {
+		return nil
+	}
+
+	since := q.s.c.Schema.Configs[0].From
+	now := types.Now()
+
+	blobs, err := q.s.Query(ctx, blob.TimeRange{From: since, Through: now}, blob.DefaultUser)
+	if err != nil {
+		return err
+	}
+
+	q.oldest = now
+
+	for i := range blobs {
+		if !q.counted(&blobs[i]) {
+			continue
+		}
+		size, err := q.s.sizeOf(ctx, &blobs[i])
+		if err != nil {
+			if errors.Is(err, content.ErrNotFound) {
+				continue
+			}
+			return err
+		}
+		q.add(&blobs[i], size)
+	}
+
+	return nil
+}
+
+// counted returns whether content of blob in local disk
+func (q *quota) counted(info *blob.Info) bool {
+	return info.Labels[blob.LabelTier] == nil && q.s.isLocalContent(info.Ref)
+}
+
+func (q *quota) add(info *blob.Info, size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.addLocked(info, size)
+}
+
+func (q *quota) addLocked(info *blob.Info, size int64) {
+	dgst := info.Ref.Digest()
+	deviceID := deviceIDOf(info)
+
+	u, ok := q.contents[dgst]
+	if !ok {
+		u = &contentUsage{size: size, blobs: map[string]int{}}
+		q.contents[dgst] = u
+		q.used += u.size
+	}
+
+	if u.blobs[deviceID] == 0 && deviceID != "" {
+		q.devices[deviceID] += u.size
+	}
+	u.blobs[deviceID]++
+
+	q.rewindLocked(info.From)
+}
+
+// rewind makes blobs since from evictable again, like retain label removed
+func (q *quota) rewind(from types.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rewindLocked(from)
+}
+
+func (q *quota) rewindLocked(from types.Time) {
+	rewound := false
+
+	if from < q.oldest {
+		q.oldest = from
+		rewound = true
+	}
+
+	for deviceID, oldest := range q.devicesOldest {
+		if from < oldest {
+			q.devicesOldest[deviceID] = from
+			rewound = true
+		}
+	}
+
+	if rewound {
+		q.rewinds++
+	}
+}
+
+// oldestOf returns where eviction of device (all when empty) starts, and rewinds for advance
+func (q *quota) oldestOf(deviceID string) (types.Time, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	oldest := q.oldest
+	if o, ok := q.devicesOldest[deviceID]; ok && deviceID != "" && o > oldest {
+		oldest = o
+	}
+	return oldest, q.rewinds
+}
+
+// advance skips blobs before to for later evictions of device (all when empty),
+// unless rewound since rewinds got by oldestOf.
+func (q *quota) advance(deviceID string, to types.Time, rewinds int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.rewinds != rewinds {
+		return
+	}
+
+	if deviceID == "" {
+		if to > q.oldest {
+			q.oldest = to
+		}
+		return
+	}
+
+	if to > q.devicesOldest[deviceID] {
+		q.devicesOldest[deviceID] = to
+	}
+}
+
+// release releases blob, which content removed from local disk.
+// bytes of content released when no more blobs of it.
+func (q *quota) release(info *blob.Info) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	dgst := info.Ref.Digest()
+	deviceID := deviceIDOf(info)
+
+	u, ok := q.contents[dgst]
+	if !ok || u.blobs[deviceID] == 0 {
+		return
+	}
+
+	if u.blobs[deviceID]--; u.blobs[deviceID] == 0 {
+		delete(u.blobs, deviceID)
+		if deviceID != "" {
+			q.subDevice(deviceID, u.size)
+		}
+	}
+
+	if len(u.blobs) == 0 {
+		delete(q.contents, dgst)
+		q.used -= u.size
+	}
+}
+
+func (q *quota) subDevice(deviceID string, size int64) {
+	q.devices[deviceID] -= size
+	if q.devices[deviceID] <= 0 {
+		delete(q.devices, deviceID)
+	}
+}
+
+// markDeleted keeps deleted blob counted until its content reclaimed
+func (q *quota) markDeleted(info *blob.Info) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.contents[info.Ref.Digest()]; ok {
+		q.deleted = append(q.deleted, *info)
+	}
+}
+
+func (q *quota) state() *QuotaState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	st := &QuotaState{
+		Enabled:   true,
+		Evict:     q.c.Evict,
+		MaxBytes:  q.c.MaxBytes,
+		UsedBytes: q.used,
+		Devices:   map[string]DeviceQuota{},
+	}
+
+	for deviceID, used := range q.devices {
+		st.Devices[deviceID] = DeviceQuota{
+			MaxBytes:  q.c.MaxBytesOfDevice(deviceID),
+			UsedBytes: used,
+		}
+	}
+
+	return st
+}
+
+// reserve reserves size bytes of the blob, when quota not exceeded.
+// otherwise, returns bytes need to free.
+func (q *quota) reserve(info *blob.Info, size int64) (total int64, device int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	deviceID := deviceIDOf(info)
+	totalSize, deviceSize := size, size
+
+	// content stored already
+	if u, ok := q.contents[info.Ref.Digest()]; ok {
+		totalSize = 0
+		if u.blobs[deviceID] > 0 {
+			deviceSize = 0
+		}
+	}
+
+	if q.c.MaxBytes > 0 && q.used+totalSize > q.c.MaxBytes {
+		total = q.used + totalSize - q.c.MaxBytes
+	}
+
+	if max := q.c.MaxBytesOfDevice(deviceID); deviceID != "" && max > 0 && q.devices[deviceID]+deviceSize > max {
+		device = q.devices[deviceID] + deviceSize - max
+	}
+
+	if total > 0 || device > 0 {
+		return
+	}
+
+	q.used += size
+	if deviceID != "" {
+		q.devices[deviceID] += size
+	}
+
+	return
+}
+
+// unreserve cancels bytes reserved by Admit
+func (q *quota) unreserve(info *blob.Info, size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.unreserveLocked(info, size)
+}
+
+func (q *quota) unreserveLocked(info *blob.Info, size int64) {
+	q.used -= size
+	if deviceID := deviceIDOf(info); deviceID != "" {
+		q.subDevice(deviceID, size)
+	}
+}
+
+// commit counts blob, which bytes reserved by Admit
+func (q *quota) commit(info *blob.Info, size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.unreserveLocked(info, size)
+	q.addLocked(info, size)
+}
+
+// Admit reserves size bytes of the blob, by reclaiming content of deleted blobs,
+// and by evicting oldest blobs when enabled.
+// Derived blobs are caches of source, which never evict others, and refused when quota exceeded.
+// Reserved bytes should be commit or unreserve after written.
+// When size is 0, only checks the quota not full.
+func (q *quota) Admit(ctx context.Context, info *blob.Info, size int64) error {
+	total, device := q.reserve(info, size)
+	if total == 0 && device == 0 {
+		return nil
+	}
+
+	q.evicting.Lock()
+	defer q.evicting.Unlock()
+
+	if err := q.reclaim(ctx); err != nil {
+		return err
+	}
+
+	if total, device = q.reserve(info, size); total == 0 && device == 0 {
+		return nil
+	}
+
+	if _, derived := info.Labels[blob.LabelDerivedFrom]; derived || !q.c.Evict {
+		return q.errExceeded(deviceIDOf(info), total, device)
+	}
+
+	deviceID := deviceIDOf(info)
+
+	if device > 0 {
+		if err := q.evict(ctx, deviceID, device); err != nil {
+			return err
+		}
+	}
+
+	if total, device = q.reserve(info, size); total == 0 && device == 0 {
+		return nil
+	}
+
+	if total > 0 {
+		if err := q.evict(ctx, "", total); err != nil {
+			return err
+		}
+	}
+
+	if total, device = q.reserve(info, size); total > 0 || device > 0 {
+		return q.errExceeded(deviceID, total, device)
+	}
+
+	return nil
+}
+
+// reclaim removes content of deleted blobs
+func (q *quota) reclaim(ctx context.Context) error {
+	q.mu.Lock()
+	deleted := q.deleted
+	q.deleted = nil
+	q.mu.Unlock()
+
+	for i := range deleted {
+		if err := q.s.removeContent(ctx, &deleted[i]); err != nil {
+			q.mu.Lock()
+			q.deleted = append(q.deleted, deleted[i:]...)
+			q.mu.Unlock()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// usedOf returns used bytes of device, of all when empty
+func (q *quota) usedOf(deviceID string) int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if deviceID == "" {
+		return q.used
+	}
+	return q.devices[deviceID]
+}
+
+func (q *quota) errExceeded(deviceID string, total int64, device int64) error {
+	if device > 0 {
+		return statuserr.Wrap(http.StatusInsufficientStorage, ErrQuotaExceeded, fmt.Sprintf("quota of device %s exceeded by %d bytes", deviceID, device))
+	}
+	return statuserr.Wrap(http.StatusInsufficientStorage, ErrQuotaExceeded, fmt.Sprintf("quota exceeded by %d bytes", total))
+}
+
+// evict deletes oldest blobs of device (all when empty) day by day, until need bytes freed.
+// blobs with retain label or under legal hold are skipped.
+func (q *quota) evict(ctx context.Context, deviceID string, need int64) error {
+	l := logr.FromContextOrDiscard(ctx)
+
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, q.c.RetainLabel, ""),
+		labels.MustNewMatcher(labels.MatchEqual, blob.LabelHold, ""),
+		labels.MustNewMatcher(labels.MatchNotEqual, blob.LabelTier, blob.TierCold),
+	}
+	if deviceID != "" {
+		matchers = append(matchers, labels.MustNewMatcher(labels.MatchEqual, "_device_id", deviceID))
+	}
+
+	oldest, rewinds := q.oldestOf(deviceID)
+
+	today := blob.UnixDay(types.Now())
+
+	// content shared by other blobs not freed
+	before := q.usedOf(deviceID)
+	freed := int64(0)
+
+	for day := blob.UnixDay(oldest); day <= today && freed < need; day++ {
+		dayRange := blob.SinceFrom(types.TimeFromUnix(day*int64(24*time.Hour/time.Second)), 24*time.Hour-time.Millisecond)
+
+		blobs, err := q.s.Query(ctx, dayRange, blob.DefaultUser, matchers...)
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(blobs, func(i, j int) bool {
+			return blobs[i].From < blobs[j].From
+		})
+
+		exhausted := true
+
+		for i := range blobs {
+			if freed >= need {
+				exhausted = false
+				break
+			}
+
+			info := &blobs[i]
+
+			if !q.counted(info) || blob.UnixDay(info.From) != day {
+				continue
+			}
+
+			size, err := q.s.evict(ctx, info)
+			if err != nil {
+				return err
+			}
+
+			freed = before - q.usedOf(deviceID)
+
+			l.Info("blob evicted", "ref", info.Ref.ExternalKey(""), "size", size)
+		}
+
+		// nothing evictable left in the day, never scanned again
+		if exhausted {
+			q.advance(deviceID, types.TimeFromUnix((day+1)*int64(24*time.Hour/time.Second)), rewinds)
+		}
+	}
+
+	return nil
+}
+
+// retainLabel returns label of blobs never evicted, empty when quota disabled
+func (s *store) retainLabel() string {
+	if s.quota == nil {
+		return ""
+	}
+	return s.quota.c.RetainLabel
+}
+
+// rewindQuota makes blobs since from evictable again, when retain label or legal hold removed
+func (s *store) rewindQuota(from types.Time) {
+	if s.quota != nil {
+		s.quota.rewind(from)
+	}
+}
+
+// evict marks blob deleted and removes its content, returns size freed
+func (s *store) evict(ctx context.Context, info *blob.Info) (int64, error) {
+	if err := errIfHeld(info); err != nil {
+		return 0, err
+	}
+
+	size, err := s.sizeOf(ctx, info)
+	if err != nil && !errors.Is(err, content.ErrNotFound) {
+		return 0, err
+	}
+
+	labelWriter, err := s.labelWriterFor(ctx, info.TimeRange)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := labelWriter.DelOne(ctx, info.TimeRange, label.MetricLabel, info.Ref); err != nil {
+		return 0, err
+	}
+
+	if err := s.removeContent(ctx, info); err != nil {
+		return 0, err
+	}
+
+	if err := s.deleteDerived(ctx, info); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// removeContent removes ref of content of blob from the tier which holds it, content removed when no more refs.
+func (s *store) removeContent(ctx context.Context, info *blob.Info) error {
+	remove := s.Store.Delete
+	if ts, ok := s.Store.(*tieredContentStore); ok && info.Labels[blob.LabelTier] != nil {
+		remove = ts.deleteCold
+	}
+
+	if err := remove(ctx, info.Ref); err != nil && !errors.Is(err, content.ErrNotFound) {
+		return err
+	}
+
+	if s.quota != nil {
+		s.quota.release(info)
+	}
+
+	return nil
+}
+
+// sizeOf returns size of blob by _size label, or by size of content
+func (s *store) sizeOf(ctx context.Context, info *blob.Info) (int64, error) {
+	if sizes, ok := info.Labels["_size"]; ok && len(sizes) > 0 {
+		if size, err := strconv.ParseInt(sizes[0], 10, 64); err == nil {
+			return size, nil
+		}
+	}
+
+	r, err := s.Store.ReaderAt(ctx, info.Ref)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	return r.Size(), nil
+}
+
+func deviceIDOf(info *blob.Info) string {
+	if ids, ok := info.Labels["_device_id"]; ok && len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
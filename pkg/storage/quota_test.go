@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	. "github.com/octohelm/x/testing"
+	"github.com/pkg/errors"
+)
+
+func TestStoreQuota(t *testing.T) {
+	c := newTestConfig(t, func(c *config.Config) {
+		c.Storage.Quota = config.QuotaConfig{
+			MaxBytes:       12,
+			DeviceMaxBytes: 8,
+			Evict:          true,
+		}
+	})
+
+	s, err := New(c)
+	Expect(t, err, Be[error](nil))
+
+	hour := func(h int) blob.Opt {
+		return blob.WithFromThough(types.TimeFromUnixNano(time.Now().Add(time.Duration(h-10) * time.Hour).UnixNano()))
+	}
+
+	device := func(id string) blob.Opt {
+		return blob.WithLabels(blob.Labels{"_device_id": {id}})
+	}
+
+	retained := commitBlob(t, s, "0000", hour(0), blob.WithLabels(blob.Labels{"_device_id": {"a"}, "retain": {"true"}}))
+	oldest := commitBlob(t, s, "1111", hour(1), device("b"))
+	commitBlob(t, s, "2222", hour(2), device("a"))
+
+	t.Run("Evict oldest but retained", func(t *testing.T) {
+		commitBlob(t, s, "3333", hour(3), device("c"))
+
+		_, err := s.Info(context.Background(), oldest.Ref)
+		Expect(t, errors.Is(err, ErrNotFound), Be(true))
+		_, err = s.ReaderAt(context.Background(), oldest.Ref)
+		Expect(t, errors.Is(err, content.ErrNotFound), Be(true))
+
+		_, err = s.Info(context.Background(), retained.Ref)
+		Expect(t, err, Be[error](nil))
+
+		st, err := s.Quota(context.Background())
+		Expect(t, err, Be[error](nil))
+		Expect(t, st.UsedBytes, Be(int64(12)))
+		Expect(t, st.Devices["a"].UsedBytes, Be(int64(8)))
+	})
+
+	t.Run("Evict oldest of device", func(t *testing.T) {
+		commitBlob(t, s, "4444", hour(4), device("a"))
+
+		st, _ := s.Quota(context.Background())
+		Expect(t, st.UsedBytes, Be(int64(12)))
+		Expect(t, st.Devices["a"].UsedBytes, Be(int64(8)))
+		Expect(t, st.Devices["c"].UsedBytes, Be(int64(4)))
+	})
+
+	t.Run("Seeded when reopen", func(t *testing.T) {
+		_ = s.Shutdown(context.Background())
+
+		c.Storage.Quota.Evict = false
+		s, err = New(c)
+		Expect(t, err, Be[error](nil))
+
+		st, _ := s.Quota(context.Background())
+		Expect(t, st.UsedBytes, Be(int64(12)))
+
+		_, err = writeBlob(s, "5555", hour(5), device("c"))
+		Expect(t, errors.Is(err, ErrQuotaExceeded), Be(true))
+	})
+
+	_ = s.Shutdown(context.Background())
+}
+
+func TestStoreQuotaOfContent(t *testing.T) {
+	s, _ := newTestStore(t, func(c *config.Config) {
+		c.Storage.Quota = config.QuotaConfig{
+			MaxBytes:       12,
+			DeviceMaxBytes: 8,
+		}
+	})
+
+	device := func(id string) blob.Opt {
+		return blob.WithLabels(blob.Labels{"_device_id": {id}})
+	}
+
+	t.Run("Same content counted once", func(t *testing.T) {
+		commitBlob(t, s, "0000", device("a"))
+		commitBlob(t, s, "0000", device("a"))
+		commitBlob(t, s, "0000", device("b"))
+
+		st, _ := s.Quota(context.Background())
+		Expect(t, st.UsedBytes, Be(int64(4)))
+		Expect(t, st.Devices["a"].UsedBytes, Be(int64(4)))
+		Expect(t, st.Devices["b"].UsedBytes, Be(int64(4)))
+	})
+
+	t.Run("Deleted counted until reclaimed", func(t *testing.T) {
+		deleted := commitBlob(t, s, "1111", device("b"))
+		Expect(t, s.Delete(context.Background(), deleted.Ref), Be[error](nil))
+
+		st, _ := s.Quota(context.Background())
+		Expect(t, st.UsedBytes, Be(int64(8)))
+		Expect(t, st.Devices["b"].UsedBytes, Be(int64(8)))
+
+		commitBlob(t, s, "2222", device("c"))
+		commitBlob(t, s, "3333", device("c"))
+
+		st, _ = s.Quota(context.Background())
+		Expect(t, st.UsedBytes, Be(int64(12)))
+		Expect(t, st.Devices["b"].UsedBytes, Be(int64(4)))
+
+		_, err := s.ReaderAt(context.Background(), deleted.Ref)
+		Expect(t, errors.Is(err, content.ErrNotFound), Be(true))
+	})
+
+	t.Run("Concurrent writes reserved", func(t *testing.T) {
+		s, _ := newTestStore(t, func(c *config.Config) {
+			c.Storage.Quota = config.QuotaConfig{MaxBytes: 8}
+		})
+
+		errs := make(chan error, 4)
+		for i := 0; i < 4; i++ {
+			go func(i int) {
+				_, err := writeBlob(s, strings.Repeat(strconv.Itoa(i), 4))
+				errs <- err
+			}(i)
+		}
+
+		exceeded := 0
+		for i := 0; i < 4; i++ {
+			if err := <-errs; errors.Is(err, ErrQuotaExceeded) {
+				exceeded++
+			}
+		}
+		Expect(t, exceeded, Be(2))
+
+		st, _ := s.Quota(context.Background())
+		Expect(t, st.UsedBytes, Be(int64(8)))
+	})
+}
+
+func TestStoreQuotaEvictionScan(t *testing.T) {
+	s, _ := newTestStore(t, func(c *config.Config) {
+		c.Storage.Quota = config.QuotaConfig{
+			MaxBytes: 8,
+			Evict:    true,
+		}
+	})
+
+	daysAgo := func(d int) blob.Opt {
+		return blob.WithFromThough(types.TimeFromUnixNano(time.Now().Add(-time.Duration(d) * 24 * time.Hour).UnixNano()))
+	}
+
+	tagged := blob.WithLabels(blob.Labels{"_tag": {"test"}})
+
+	q := s.(*store).quota
+
+	retained := commitBlob(t, s, "0000", daysAgo(5), tagged, blob.WithLabels(blob.Labels{"retain": {"true"}}))
+	commitBlob(t, s, "1111", daysAgo(3), tagged)
+
+	oldest, _ := q.oldestOf("")
+	Expect(t, oldest, Be(retained.From))
+
+	t.Run("Days without evictable blobs skipped", func(t *testing.T) {
+		commitBlob(t, s, "2222", daysAgo(1), tagged)
+
+		oldest, _ := q.oldestOf("")
+		Expect(t, blob.UnixDay(oldest), Be(blob.UnixDay(types.Now())-2))
+	})
+
+	t.Run("Rewound when retain label removed", func(t *testing.T) {
+		Expect(t, s.DeleteLabel(context.Background(), retained.Ref, "retain", "true"), Be[error](nil))
+
+		oldest, _ := q.oldestOf("")
+		Expect(t, oldest, Be(retained.From))
+
+		commitBlob(t, s, "3333", daysAgo(0), tagged)
+
+		_, err := s.Info(context.Background(), retained.Ref)
+		Expect(t, errors.Is(err, ErrNotFound), Be(true))
+	})
+}
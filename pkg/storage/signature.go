@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DatasetSignedManifest lists digest of every file in archive, written after all files.
+	DatasetSignedManifest = "dataset.json"
+	// DatasetSignature is the base64 encoded ed25519 signature of DatasetSignedManifest.
+	DatasetSignature = DatasetSignedManifest + ".sig"
+)
+
+var ErrDatasetInvalid = errors.New("dataset invalid")
+
+type SignedManifest struct {
+	// Version of mtk which exported the dataset
+	Version   string       `json:"version"`
+	CreatedAt types.Time   `json:"createdAt"`
+	Query     DatasetQuery `json:"query"`
+	Layout    ExportLayout `json:"layout"`
+	Files     []FileDigest `json:"files"`
+}
+
+// DatasetQuery which selected blobs of dataset
+type DatasetQuery struct {
+	TimeRange types.DateTimeRange `json:"time"`
+	Filter    string              `json:"filter,omitempty"`
+}
+
+type FileDigest struct {
+	Path   string        `json:"path"`
+	Size   int64         `json:"size"`
+	Digest digest.Digest `json:"digest"`
+}
+
+type DatasetVerifyReport struct {
+	Manifest *SignedManifest `json:"manifest,omitempty"`
+	Valid    bool            `json:"valid"`
+	Errors   []string        `json:"errors,omitempty"`
+}
+
+// VerifyDataset validates dataset archive offline by the public key of signer,
+// the signature of manifest, and digest of each file listed in manifest.
+func VerifyDataset(r io.Reader, publicKey ed25519.PublicKey) (*DatasetVerifyReport, error) {
+	var manifestData, signature []byte
+
+	files := map[string]FileDigest{}
+
+	err := walkDataset(r, func(hdr *tar.Header, tr io.Reader) error {
+		switch hdr.Name {
+		case DatasetSignedManifest:
+			data, err := io.ReadAll(tr)
+			manifestData = data
+			return err
+		case DatasetSignature:
+			data, err := io.ReadAll(io.LimitReader(tr, 1024))
+			if err != nil {
+				return err
+			}
+			signature, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+			return err
+		}
+
+		digester := digest.SHA256.Digester()
+		n, err := io.Copy(digester.Hash(), tr)
+		if err != nil {
+			return err
+		}
+		files[hdr.Name] = FileDigest{Path: hdr.Name, Size: n, Digest: digester.Digest()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DatasetVerifyReport{}
+
+	invalid := func(msg string) {
+		report.Errors = append(report.Errors, msg)
+	}
+
+	if manifestData == nil {
+		return nil, errors.Wrap(ErrDatasetInvalid, "signed manifest missing")
+	}
+
+	if signature == nil {
+		invalid("signature missing")
+	} else if !ed25519.Verify(publicKey, manifestData, signature) {
+		invalid("signature not match")
+	}
+
+	m := &SignedManifest{}
+	if err := json.Unmarshal(manifestData, m); err != nil {
+		return nil, errors.Wrap(ErrDatasetInvalid, err.Error())
+	}
+	report.Manifest = m
+
+	listed := map[string]bool{}
+
+	for _, f := range m.Files {
+		listed[f.Path] = true
+
+		actual, ok := files[f.Path]
+		if !ok {
+			invalid("missing " + f.Path)
+			continue
+		}
+		if actual.Digest != f.Digest || actual.Size != f.Size {
+			invalid("digest not match " + f.Path)
+		}
+	}
+
+	unexpected := make([]string, 0)
+	for p := range files {
+		if !listed[p] {
+			unexpected = append(unexpected, p)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, p := range unexpected {
+		invalid("unexpected " + p)
+	}
+
+	report.Valid = len(report.Errors) == 0
+
+	return report, nil
+}
+
+// digestingTarWriter records digest of each file written
+type digestingTarWriter struct {
+	*tar.Writer
+	files []FileDigest
+}
+
+func (w *digestingTarWriter) copy(r io.Reader, header tar.Header) (digest.Digest, error) {
+	digester := digest.SHA256.Digester()
+	if err := copyToTar(w.Writer, io.TeeReader(r, digester.Hash()), header); err != nil {
+		return "", err
+	}
+	w.files = append(w.files, FileDigest{Path: header.Name, Size: header.Size, Digest: digester.Digest()})
+	return digester.Digest(), nil
+}
+
+func (w *digestingTarWriter) sign(m *SignedManifest, privateKey ed25519.PrivateKey) error {
+	m.Files = w.files
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := copyToTar(w.Writer, bytes.NewReader(data), tar.Header{
+		Name: DatasetSignedManifest,
+		Size: int64(len(data)),
+	}); err != nil {
+		return err
+	}
+
+	if privateKey == nil {
+		return nil
+	}
+
+	signature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data)))
+
+	return copyToTar(w.Writer, bytes.NewReader(signature), tar.Header{
+		Name: DatasetSignature,
+		Size: int64(len(signature)),
+	})
+}
+
+// GenerateSigningKey generates ed25519 key pair in PEM
+func GenerateSigningKey() (privateKeyPEM []byte, publicKeyPEM []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}),
+		nil
+}
+
+// LoadSigningKey loads ed25519 private key from PEM file
+func LoadSigningKey(filename string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(filename, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	if k, ok := key.(ed25519.PrivateKey); ok {
+		return k, nil
+	}
+	return nil, errors.Errorf("%s: not an ed25519 private key", filename)
+}
+
+// LoadVerifyKey loads ed25519 public key from PEM file
+func LoadVerifyKey(filename string) (ed25519.PublicKey, error) {
+	der, err := readPEM(filename, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	if k, ok := key.(ed25519.PublicKey); ok {
+		return k, nil
+	}
+	return nil, errors.Errorf("%s: not an ed25519 public key", filename)
+}
+
+func readPEM(filename string, typ string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != typ {
+		return nil, errors.Errorf("%s: %s in PEM required", filename, typ)
+	}
+	return block.Bytes, nil
+}
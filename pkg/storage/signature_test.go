@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	. "github.com/octohelm/x/testing"
+)
+
+func TestStoreVerifyDataset(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	commitBlob(t, s, "signed", blob.WithLabels(blob.Labels{"_tag": {"test"}}))
+
+	dir := t.TempDir()
+	privateKeyPEM, publicKeyPEM, err := GenerateSigningKey()
+	Expect(t, err, Be[error](nil))
+	_ = os.WriteFile(filepath.Join(dir, "key.pem"), privateKeyPEM, 0600)
+	_ = os.WriteFile(filepath.Join(dir, "key.pub.pem"), publicKeyPEM, 0644)
+
+	privateKey, err := LoadSigningKey(filepath.Join(dir, "key.pem"))
+	Expect(t, err, Be[error](nil))
+	publicKey, err := LoadVerifyKey(filepath.Join(dir, "key.pub.pem"))
+	Expect(t, err, Be[error](nil))
+
+	timeRange := blob.Last(time.Hour)
+	blobs, err := s.Query(context.Background(), timeRange, blob.DefaultUser)
+	Expect(t, err, Be[error](nil))
+
+	archive := bytes.NewBuffer(nil)
+	_, err = ExportDataset(context.Background(), s, archive, blobs, WithExportQuery(timeRange, types.Filter{}), WithSigningKey(privateKey))
+	Expect(t, err, Be[error](nil))
+
+	t.Run("Valid", func(t *testing.T) {
+		report, err := VerifyDataset(bytes.NewReader(archive.Bytes()), publicKey)
+		Expect(t, err, Be[error](nil))
+		Expect(t, len(report.Errors), Be(0))
+		Expect(t, report.Valid, Be(true))
+		Expect(t, len(report.Manifest.Files), Be(2))
+		Expect(t, report.Manifest.Query.TimeRange.From.Unix(), Be(timeRange.From.Unix()))
+	})
+
+	t.Run("Wrong key", func(t *testing.T) {
+		otherPublicKey, _, _ := ed25519.GenerateKey(nil)
+
+		report, err := VerifyDataset(bytes.NewReader(archive.Bytes()), otherPublicKey)
+		Expect(t, err, Be[error](nil))
+		Expect(t, report.Valid, Be(false))
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(tampered)
+		tw := tar.NewWriter(gw)
+
+		err := walkDataset(bytes.NewReader(archive.Bytes()), func(hdr *tar.Header, r io.Reader) error {
+			if strings.HasPrefix(hdr.Name, "blobs/") {
+				return copyToTar(tw, bytes.NewBufferString("s1gned"), *hdr)
+			}
+			return copyToTar(tw, r, *hdr)
+		})
+		Expect(t, err, Be[error](nil))
+		_ = tw.Close()
+		_ = gw.Close()
+
+		report, err := VerifyDataset(bytes.NewReader(tampered.Bytes()), publicKey)
+		Expect(t, err, Be[error](nil))
+		Expect(t, report.Valid, Be(false))
+		Expect(t, len(report.Errors), Be(1))
+	})
+}
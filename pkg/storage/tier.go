@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	contentlocal "github.com/innoai-tech/media-toolkit/pkg/storage/content/local"
+	contents3 "github.com/innoai-tech/media-toolkit/pkg/storage/content/s3"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/label"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+type Tiering interface {
+	// MoveToCold moves blobs matched tiering policy from local disk to cold tier
+	MoveToCold(ctx context.Context) (*TieringReport, error)
+}
+
+type TieringReport struct {
+	Moved  []blob.RefString `json:"moved"`
+	Failed []blob.RefString `json:"failed"`
+}
+
+// newTieredContentStore wraps hot content store with cold tier,
+// returns hot store when tiering disabled.
+func newTieredContentStore(c config.Config, hot content.Store) (content.Store, error) {
+	var cold content.Store
+
+	switch c.Storage.Tiering.ObjectType {
+	case "":
+		return hot, nil
+	case config.ObjectTypeLocal:
+		if c.Storage.Tiering.Root == "" {
+			return nil, errors.New("root of cold tier is required")
+		}
+		if filepath.Clean(c.Storage.Tiering.Root) == filepath.Clean(c.Storage.Root) {
+			return nil, errors.New("root of cold tier should not be the storage root")
+		}
+		s, err := contentlocal.NewStore(c.Storage.Tiering.Root)
+		if err != nil {
+			return nil, err
+		}
+		cold = s
+	case config.ObjectTypeS3:
+		// staged in the local store of hot tier, which guards refs of shared objects under root.
+		s, err := contents3.NewStore(c.Storage.S3, localStoreOf(hot))
+		if err != nil {
+			return nil, err
+		}
+		cold = s
+	default:
+		return nil, fmt.Errorf("unsupported object type %s of cold tier", c.Storage.Tiering.ObjectType)
+	}
+
+	return &tieredContentStore{Store: hot, cold: cold}, nil
+}
+
+// tieredContentStore writes into hot tier, and reads fall through tiers
+type tieredContentStore struct {
+	content.Store
+	cold content.Store
+}
+
+func (s *tieredContentStore) ReaderAt(ctx context.Context, ref blob.Ref) (content.ReaderAt, error) {
+	r, err := s.Store.ReaderAt(ctx, ref)
+	if err == nil || !errors.Is(err, content.ErrNotFound) {
+		return r, err
+	}
+	return s.cold.ReaderAt(ctx, ref)
+}
+
+// Delete removes content of ref in hot tier only,
+// content of refs in cold tier removed by deleteCold.
+func (s *tieredContentStore) Delete(ctx context.Context, ref blob.Ref) error {
+	return s.Store.Delete(ctx, ref)
+}
+
+func (s *tieredContentStore) deleteCold(ctx context.Context, ref blob.Ref) error {
+	return s.cold.Delete(ctx, ref)
+}
+
+func (s *tieredContentStore) PresignedURL(ctx context.Context, ref blob.Ref) (string, error) {
+	if p, ok := s.Store.(content.Presigner); ok {
+		u, err := p.PresignedURL(ctx, ref)
+		if err != nil || u != "" {
+			return u, err
+		}
+	}
+
+	// content in hot tier served directly
+	if r, err := s.Store.ReaderAt(ctx, ref); err == nil {
+		_ = r.Close()
+		return "", nil
+	}
+
+	if p, ok := s.cold.(content.Presigner); ok {
+		return p.PresignedURL(ctx, ref)
+	}
+	return "", nil
+}
+
+// moveToCold copies content to cold tier, verified by digest, then removes it from hot tier
+func (s *tieredContentStore) moveToCold(ctx context.Context, info blob.Info, mark func() error) error {
+	r, err := s.Store.ReaderAt(ctx, info.Ref)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if importer, ok := s.cold.(content.Importer); ok {
+		if err := importer.Import(ctx, info, r); err != nil {
+			return err
+		}
+	} else {
+		w, err := s.cold.Writer(ctx, blob.WithUserId(info.UserID), blob.WithFromThough(info.From, info.Through))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, io.NewSectionReader(r, 0, r.Size())); err != nil {
+			_ = w.Close()
+			return err
+		}
+		if err := w.Commit(ctx, r.Size(), info.Digest()); err != nil {
+			_ = w.Close()
+			return err
+		}
+	}
+
+	if err := s.verifyCold(ctx, info.Ref); err != nil {
+		return err
+	}
+
+	if err := mark(); err != nil {
+		return err
+	}
+
+	return s.Store.Delete(ctx, info.Ref)
+}
+
+func (s *tieredContentStore) verifyCold(ctx context.Context, ref blob.Ref) error {
+	r, err := s.cold.ReaderAt(ctx, ref)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	dgst, err := ref.Digest().Algorithm().FromReader(io.NewSectionReader(r, 0, r.Size()))
+	if err != nil {
+		return err
+	}
+
+	if dgst != ref.Digest() {
+		return fmt.Errorf("content in cold tier is %s, but expected %s", dgst, ref.Digest())
+	}
+
+	return nil
+}
+
+func (s *store) MoveToCold(ctx context.Context) (*TieringReport, error) {
+	ts, ok := s.Store.(*tieredContentStore)
+	if !ok {
+		return nil, errors.New("tiering is disabled")
+	}
+
+	l := logr.FromContextOrDiscard(ctx)
+
+	report := &TieringReport{
+		Moved:  []blob.RefString{},
+		Failed: []blob.RefString{},
+	}
+
+	candidates, err := s.tieringCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		info := candidates[i]
+
+		// content in object storage already
+		if !s.isLocalContent(info.Ref) {
+			continue
+		}
+
+		err := ts.moveToCold(ctx, info, func() error {
+			labelWriter, err := s.labelWriterFor(ctx, info.TimeRange)
+			if err != nil {
+				return err
+			}
+			return labelWriter.PutLabels(ctx, info.TimeRange, label.MetricLabel, info.Ref, blob.Labels{
+				blob.LabelTier: {blob.TierCold},
+			})
+		})
+
+		if err != nil {
+			l.Error(err, "move to cold tier failed", "ref", info.Ref.ExternalKey(""))
+			report.Failed = append(report.Failed, blob.RefString(info.Ref))
+			continue
+		}
+
+		if s.quota != nil {
+			s.quota.release(&info)
+		}
+
+		report.Moved = append(report.Moved, blob.RefString(info.Ref))
+	}
+
+	return report, nil
+}
+
+// tieringCandidates returns blobs older than Tiering.After days, or matched Tiering.Filter, which still in hot tier.
+func (s *store) tieringCandidates(ctx context.Context) ([]blob.Info, error) {
+	tc := s.c.Storage.Tiering
+
+	if len(s.c.Schema.Configs) == 0 {
+		return nil, nil
+	}
+
+	inHot := labels.MustNewMatcher(labels.MatchNotEqual, blob.LabelTier, blob.TierCold)
+
+	since := s.c.Schema.Configs[0].From
+	now := types.Now()
+
+	candidates := make([]blob.Info, 0)
+	picked := map[string]bool{}
+
+	collect := func(timeRange blob.TimeRange, matchers ...*labels.Matcher) error {
+		blobs, err := s.Query(ctx, timeRange, blob.DefaultUser, append(matchers, inHot)...)
+		if err != nil {
+			return err
+		}
+		for _, b := range blobs {
+			if picked[b.RefKey] {
+				continue
+			}
+			picked[b.RefKey] = true
+			candidates = append(candidates, b)
+		}
+		return nil
+	}
+
+	if tc.After > 0 {
+		through := types.TimeFromUnixNano(now.Time().Add(-time.Duration(tc.After) * 24 * time.Hour).UnixNano())
+		if through > since {
+			if err := collect(blob.TimeRange{From: since, Through: through}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if tc.Filter != "" {
+		filter := types.Filter{}
+		if err := filter.UnmarshalText([]byte(tc.Filter)); err != nil {
+			return nil, errors.Wrap(err, "invalid tiering filter")
+		}
+		if len(filter.Matchers) > 0 {
+			if err := collect(blob.TimeRange{From: since, Through: now}, filter.Matchers...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+// runTiering moves blobs to cold tier every Tiering.Interval until ctx done
+func (s *store) runTiering(ctx context.Context) {
+	l := logr.FromContextOrDiscard(ctx)
+
+	ticker := time.NewTicker(s.c.Storage.Tiering.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.MoveToCold(ctx)
+			if err != nil {
+				l.Error(err, "tiering failed")
+				continue
+			}
+			if len(report.Moved) > 0 || len(report.Failed) > 0 {
+				l.Info("tiering done", "moved", len(report.Moved), "failed", len(report.Failed))
+			}
+		}
+	}
+}
+
+// withTier fills Tier of blob by LabelTier
+func withTier(info *blob.Info) {
+	info.Tier = blob.TierHot
+	if tiers, ok := info.Labels[blob.LabelTier]; ok && len(tiers) > 0 {
+		info.Tier = tiers[0]
+	}
+}
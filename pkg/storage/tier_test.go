@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/config"
+	"github.com/innoai-tech/media-toolkit/pkg/types"
+	. "github.com/octohelm/x/testing"
+)
+
+func TestStoreTiering(t *testing.T) {
+	s, c := newTestStore(t, func(c *config.Config) {
+		c.Storage.Tiering = config.TieringConfig{
+			ObjectType: config.ObjectTypeLocal,
+			Root:       t.TempDir(),
+			After:      30,
+			Filter:     `{_tag="archive"}`,
+		}
+	})
+
+	old := commitBlob(t, s, "old",
+		blob.WithFromThough(types.TimeFromUnixNano(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC).UnixNano())),
+		blob.WithLabels(blob.Labels{"_tag": {"test"}}),
+	)
+	archived := commitBlob(t, s, "archived", blob.WithLabels(blob.Labels{"_tag": {"archive"}}))
+	recent := commitBlob(t, s, "recent", blob.WithLabels(blob.Labels{"_tag": {"test"}}))
+
+	report, err := s.MoveToCold(context.Background())
+	Expect(t, err, Be[error](nil))
+	Expect(t, len(report.Moved), Be(2))
+	Expect(t, len(report.Failed), Be(0))
+
+	for _, moved := range []blob.Info{old, archived} {
+		info, err := s.Info(context.Background(), moved.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, info.Tier, Be(blob.TierCold))
+
+		_, err = os.Stat(moved.BlobPath(c.Storage.Root))
+		Expect(t, os.IsNotExist(err), Be(true))
+
+		_, err = os.Stat(moved.BlobPath(c.Storage.Tiering.Root))
+		Expect(t, err, Be[error](nil))
+
+		r, err := s.ReaderAt(context.Background(), moved.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, r.Size() > 0, Be(true))
+		_ = r.Close()
+	}
+
+	info, err := s.Info(context.Background(), recent.Ref)
+	Expect(t, err, Be[error](nil))
+	Expect(t, info.Tier, Be(blob.TierHot))
+
+	report, err = s.MoveToCold(context.Background())
+	Expect(t, err, Be[error](nil))
+	Expect(t, len(report.Moved), Be(0))
+
+	checked, err := s.Check(context.Background(), blob.Last(time.Hour), false)
+	Expect(t, err, Be[error](nil))
+	Expect(t, len(checked.DanglingRefs), Be(0))
+
+	t.Run("Content in cold tier kept when same content in hot tier removed", func(t *testing.T) {
+		from := blob.WithFromThough(old.From + 1)
+		hot := commitBlob(t, s, "old", from, blob.WithLabels(blob.Labels{"_tag": {"test"}}))
+		Expect(t, hot.BlobPath(""), Be(old.BlobPath("")))
+
+		Expect(t, s.(*store).removeContent(context.Background(), &hot), Be[error](nil))
+
+		r, err := s.ReaderAt(context.Background(), old.Ref)
+		Expect(t, err, Be[error](nil))
+		Expect(t, r.Size(), Be(int64(3)))
+		_ = r.Close()
+	})
+}
@@ -0,0 +1,374 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/go-logr/logr"
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	"github.com/innoai-tech/media-toolkit/pkg/statuserr"
+	"github.com/innoai-tech/media-toolkit/pkg/storage/content"
+	"github.com/innoai-tech/media-toolkit/pkg/util/syncutil"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadIncomplete     = errors.New("upload incomplete")
+	ErrUploadExceeded       = errors.New("upload exceeded declared total")
+	ErrDigestRequired       = errors.New("expected digest is required")
+)
+
+type Uploader interface {
+	CreateUpload(ctx context.Context, total int64) (*Upload, error)
+	GetUpload(ctx context.Context, id string) (*Upload, error)
+	// WriteUpload appends data from offset, which must be equal to Upload.Offset.
+	// Data beyond the declared total rejected.
+	WriteUpload(ctx context.Context, id string, offset int64, r io.Reader) (*Upload, error)
+	CommitUpload(ctx context.Context, id string, expected digest.Digest, opts ...blob.Opt) (*blob.Info, error)
+	AbortUpload(ctx context.Context, id string) error
+}
+
+// Upload status of upload session, which backed by ingest writer
+type Upload struct {
+	ID        string    `json:"id"`
+	Offset    int64     `json:"offset"`
+	Total     int64     `json:"total,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type uploadMeta struct {
+	Total int64 `json:"total,omitempty"`
+}
+
+type upload struct {
+	mu    sync.Mutex
+	id    string
+	total int64
+	w     Writer
+	// done when committed, aborted or failed to resume
+	done bool
+}
+
+func (u *upload) status() (*Upload, error) {
+	st, err := u.w.Status()
+	if err != nil {
+		return nil, err
+	}
+	return &Upload{
+		ID:        u.id,
+		Offset:    st.Offset,
+		Total:     u.total,
+		StartedAt: st.StartedAt,
+		UpdatedAt: st.UpdatedAt,
+	}, nil
+}
+
+type uploads struct {
+	root   string
+	opened syncutil.Map[string, *upload]
+}
+
+func newUploads(root string) (*uploads, error) {
+	root = filepath.Join(root, "uploads")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &uploads{root: root}, nil
+}
+
+func (us *uploads) metaFile(id string) string {
+	return filepath.Join(us.root, id+".json")
+}
+
+// Close closes all opened writers, written data kept for resuming
+func (us *uploads) Close() {
+	us.opened.Range(func(key, value any) bool {
+		u := value.(*upload)
+		u.mu.Lock()
+		defer u.mu.Unlock()
+
+		if u.w != nil && !u.done {
+			_ = u.w.Close()
+		}
+		u.done = true
+		us.opened.Delete(key)
+		return true
+	})
+}
+
+func (s *store) CreateUpload(ctx context.Context, total int64) (*Upload, error) {
+	w, err := s.Writer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := w.Status()
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	data, err := json.Marshal(uploadMeta{Total: total})
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	if err := os.WriteFile(s.uploads.metaFile(st.Ref), data, 0644); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	u := &upload{id: st.Ref, total: total, w: w}
+	s.uploads.opened.Store(u.id, u)
+
+	return u.status()
+}
+
+func (s *store) GetUpload(ctx context.Context, id string) (*Upload, error) {
+	u, err := s.openUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer u.mu.Unlock()
+
+	return u.status()
+}
+
+func (s *store) WriteUpload(ctx context.Context, id string, offset int64, r io.Reader) (*Upload, error) {
+	u, err := s.openUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer u.mu.Unlock()
+
+	current, err := u.status()
+	if err != nil {
+		return nil, err
+	}
+
+	if offset != current.Offset {
+		return nil, statuserr.Wrap(http.StatusConflict, ErrUploadOffsetMismatch, fmt.Sprintf("upload offset should be %d, but got %d", current.Offset, offset))
+	}
+
+	if u.total <= 0 {
+		if _, err := io.Copy(u.w, r); err != nil {
+			// written part kept, client could resume from offset in status
+			return nil, err
+		}
+		return u.status()
+	}
+
+	if _, err := io.Copy(u.w, io.LimitReader(r, u.total-current.Offset)); err != nil {
+		return nil, err
+	}
+
+	// part in total kept
+	if n, _ := io.ReadFull(r, make([]byte, 1)); n > 0 {
+		return nil, statuserr.Wrap(http.StatusRequestEntityTooLarge, ErrUploadExceeded, fmt.Sprintf("upload total is %d bytes", u.total))
+	}
+
+	return u.status()
+}
+
+func (s *store) CommitUpload(ctx context.Context, id string, expected digest.Digest, opts ...blob.Opt) (*blob.Info, error) {
+	if expected == "" {
+		return nil, statuserr.Wrap(http.StatusBadRequest, ErrDigestRequired, "")
+	}
+
+	u, err := s.openUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer u.mu.Unlock()
+
+	current, err := u.status()
+	if err != nil {
+		return nil, err
+	}
+
+	if u.total > 0 && current.Offset != u.total {
+		return nil, statuserr.Wrap(http.StatusBadRequest, ErrUploadIncomplete, fmt.Sprintf("%d of %d bytes uploaded", current.Offset, u.total))
+	}
+
+	// writer closed after commit, even failed.
+	u.done = true
+	s.uploads.opened.Delete(id)
+
+	if err := u.w.Commit(ctx, current.Offset, expected, opts...); err != nil {
+		if errors.Is(err, errdefs.ErrFailedPrecondition) {
+			return nil, statuserr.Wrap(http.StatusPreconditionFailed, err, "")
+		}
+		return nil, err
+	}
+
+	_ = os.Remove(s.uploads.metaFile(id))
+
+	return s.Info(ctx, u.w.Info().Ref)
+}
+
+func (s *store) AbortUpload(ctx context.Context, id string) error {
+	u, err := s.openUpload(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer u.mu.Unlock()
+
+	return s.abortUpload(ctx, u)
+}
+
+// abortUpload aborts the locked upload
+func (s *store) abortUpload(ctx context.Context, u *upload) error {
+	u.done = true
+	s.uploads.opened.Delete(u.id)
+
+	_ = u.w.Close()
+	_ = os.Remove(s.uploads.metaFile(u.id))
+
+	return s.Store.Abort(ctx, u.id)
+}
+
+func (s *store) uploadExpires() time.Duration {
+	if s.c.Storage.UploadExpires > 0 {
+		return s.c.Storage.UploadExpires
+	}
+	return 24 * time.Hour
+}
+
+func (s *store) runUploadExpiry(ctx context.Context) {
+	l := logr.FromContextOrDiscard(ctx)
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.expireUploads(ctx, s.uploadExpires()); err != nil {
+				l.Error(err, "expire uploads failed")
+			}
+		}
+	}
+}
+
+// expireUploads aborts upload sessions not written in expires
+func (s *store) expireUploads(ctx context.Context, expires time.Duration) error {
+	l := logr.FromContextOrDiscard(ctx)
+
+	list, err := os.ReadDir(s.uploads.root)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range list {
+		id := strings.TrimSuffix(f.Name(), ".json")
+		if f.IsDir() || id == f.Name() {
+			continue
+		}
+
+		u, err := s.openUpload(ctx, id)
+		if err != nil {
+			// written data lost, nothing to resume
+			if errors.Is(err, content.ErrNotFound) {
+				_ = os.Remove(s.uploads.metaFile(id))
+				continue
+			}
+			return err
+		}
+
+		st, err := u.status()
+		if err != nil {
+			u.mu.Unlock()
+			continue
+		}
+
+		// updated time of resumed writer is the time of data last written
+		updatedAt := st.UpdatedAt
+		if st.Offset == 0 {
+			updatedAt = st.StartedAt
+		}
+
+		if time.Since(updatedAt) < expires {
+			u.mu.Unlock()
+			continue
+		}
+
+		err = s.abortUpload(ctx, u)
+		u.mu.Unlock()
+		if err != nil && !errors.Is(err, content.ErrNotFound) {
+			return err
+		}
+
+		l.Info("upload expired", "id", id, "updatedAt", updatedAt)
+	}
+
+	return nil
+}
+
+// openUpload returns the locked upload, writer will be resumed when not opened
+func (s *store) openUpload(ctx context.Context, id string) (*upload, error) {
+	created := &upload{id: id}
+
+	u, loaded := s.uploads.opened.LoadOrStore(id, created)
+	if !loaded {
+		u = created
+	}
+
+	u.mu.Lock()
+
+	if u.done {
+		u.mu.Unlock()
+		return nil, statuserr.Wrap(http.StatusNotFound, ErrNotFound, fmt.Sprintf("upload `%s` is not found", id))
+	}
+
+	if u.w != nil {
+		return u, nil
+	}
+
+	err := func() error {
+		data, err := os.ReadFile(s.uploads.metaFile(id))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return statuserr.Wrap(http.StatusNotFound, ErrNotFound, fmt.Sprintf("upload `%s` is not found", id))
+			}
+			return err
+		}
+
+		meta := uploadMeta{}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return err
+		}
+
+		w, err := s.ResumeWriter(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		u.w = w
+		u.total = meta.Total
+		return nil
+	}()
+
+	if err != nil {
+		u.done = true
+		s.uploads.opened.Delete(id)
+		u.mu.Unlock()
+		return nil, err
+	}
+
+	return u, nil
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/innoai-tech/media-toolkit/pkg/blob"
+	. "github.com/octohelm/x/testing"
+	"github.com/pkg/errors"
+)
+
+func TestStoreUpload(t *testing.T) {
+	c := newTestConfig(t)
+
+	s, err := New(c)
+	Expect(t, err, Be[error](nil))
+
+	data := "0123456789"
+
+	u, err := s.CreateUpload(context.Background(), int64(len(data)))
+	Expect(t, err, Be[error](nil))
+	Expect(t, u.Offset, Be(int64(0)))
+
+	u, err = s.WriteUpload(context.Background(), u.ID, 0, bytes.NewBufferString(data[:4]))
+	Expect(t, err, Be[error](nil))
+	Expect(t, u.Offset, Be(int64(4)))
+
+	t.Run("Offset mismatch", func(t *testing.T) {
+		_, err := s.WriteUpload(context.Background(), u.ID, 0, bytes.NewBufferString(data[4:]))
+		Expect(t, errors.Is(err, ErrUploadOffsetMismatch), Be(true))
+	})
+
+	t.Run("Commit incomplete", func(t *testing.T) {
+		_, err := s.CommitUpload(context.Background(), u.ID, blob.FromString(data).Digest())
+		Expect(t, errors.Is(err, ErrUploadIncomplete), Be(true))
+	})
+
+	t.Run("Resume after reopen", func(t *testing.T) {
+		_ = s.Shutdown(context.Background())
+		s, err = New(c)
+		Expect(t, err, Be[error](nil))
+
+		resumed, err := s.GetUpload(context.Background(), u.ID)
+		Expect(t, err, Be[error](nil))
+		Expect(t, resumed.Offset, Be(int64(4)))
+		Expect(t, resumed.Total, Be(int64(len(data))))
+
+		resumed, err = s.WriteUpload(context.Background(), u.ID, resumed.Offset, bytes.NewBufferString(data[4:]))
+		Expect(t, err, Be[error](nil))
+		Expect(t, resumed.Offset, Be(int64(len(data))))
+
+		info, err := s.CommitUpload(
+			context.Background(),
+			u.ID,
+			blob.FromString(data).Digest(),
+			blob.WithLabels(blob.Labels{"_media_type": {"text/plain"}}),
+		)
+		Expect(t, err, Be[error](nil))
+		Expect(t, info.Labels["_media_type"], Equal([]string{"text/plain"}))
+
+		r, err := s.ReaderAt(context.Background(), info.Ref)
+		Expect(t, err, Be[error](nil))
+		buf := make([]byte, len(data))
+		_, _ = r.ReadAt(buf, 0)
+		_ = r.Close()
+		Expect(t, string(buf), Be(data))
+
+		_, err = s.GetUpload(context.Background(), u.ID)
+		Expect(t, errors.Is(err, ErrNotFound), Be(true))
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		u, err := s.CreateUpload(context.Background(), 0)
+		Expect(t, err, Be[error](nil))
+		Expect(t, s.AbortUpload(context.Background(), u.ID), Be[error](nil))
+
+		_, err = s.GetUpload(context.Background(), u.ID)
+		Expect(t, errors.Is(err, ErrNotFound), Be(true))
+	})
+
+	t.Run("Uploads created in same time", func(t *testing.T) {
+		u1, err := s.CreateUpload(context.Background(), 0)
+		Expect(t, err, Be[error](nil))
+		u2, err := s.CreateUpload(context.Background(), 0)
+		Expect(t, err, Be[error](nil))
+		Expect(t, u1.ID != u2.ID, Be(true))
+
+		_, err = s.WriteUpload(context.Background(), u1.ID, 0, bytes.NewBufferString("1"))
+		Expect(t, err, Be[error](nil))
+
+		st, err := s.GetUpload(context.Background(), u2.ID)
+		Expect(t, err, Be[error](nil))
+		Expect(t, st.Offset, Be(int64(0)))
+	})
+
+	t.Run("Exceeded total", func(t *testing.T) {
+		u, err := s.CreateUpload(context.Background(), 4)
+		Expect(t, err, Be[error](nil))
+
+		_, err = s.WriteUpload(context.Background(), u.ID, 0, bytes.NewBufferString("01234"))
+		Expect(t, errors.Is(err, ErrUploadExceeded), Be(true))
+
+		st, err := s.GetUpload(context.Background(), u.ID)
+		Expect(t, err, Be[error](nil))
+		Expect(t, st.Offset, Be(int64(4)))
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		u, err := s.CreateUpload(context.Background(), 0)
+		Expect(t, err, Be[error](nil))
+
+		Expect(t, s.(*store).expireUploads(context.Background(), time.Hour), Be[error](nil))
+		_, err = s.GetUpload(context.Background(), u.ID)
+		Expect(t, err, Be[error](nil))
+
+		Expect(t, s.(*store).expireUploads(context.Background(), 0), Be[error](nil))
+		_, err = s.GetUpload(context.Background(), u.ID)
+		Expect(t, errors.Is(err, ErrNotFound), Be(true))
+	})
+
+	_ = s.Shutdown(context.Background())
+}
@@ -12,9 +12,23 @@ export const listStreams = createRequest<void, Stream[]>(
 	}),
 );
 
+export interface SubscriberStatus {
+	name: string;
+	preset: string;
+	policy: "drop" | "disconnect";
+	queued: number;
+	// ms
+	lag: number;
+	dropped: number;
+}
+
 export const liveStreamStatus = createRequest<
 	{ id: string },
-	{ active: boolean; observers: { [k: string]: number } }
+	{
+		active: boolean;
+		observers: { [k: string]: number };
+		subscribers: SubscriberStatus[];
+	}
 >(
 	({ id }) => ({
 		method: "GET",
@@ -22,29 +36,74 @@ export const liveStreamStatus = createRequest<
 	}),
 );
 
-export const wsMp4fStreamFor = createRequest<{ id: string }, void>(
-	({ id }) => ({
-		method: "GET",
-		url: `/api/live-streams/${id}/wsmp4f`,
-	}),
-);
+export const wsMp4fStreamFor = createRequest<
+	{ id: string; policy?: "drop" | "disconnect" },
+	void
+>(({ id, policy }) => ({
+	method: "GET",
+	url: `/api/live-streams/${id}/wsmp4f`,
+	params: {
+		policy,
+	},
+}));
 
-export const takePic = createRequest<{ id: string }, void>(
-	({ id }) => ({
-		method: "PUT",
-		url: `/api/live-streams/${id}/takepic`,
-	}),
-);
+export const mjpegStreamFor = createRequest<
+	{ id: string; fps?: number; w?: number; h?: number; quality?: number },
+	void
+>(({ id, fps, w, h, quality }) => ({
+	method: "GET",
+	url: `/api/live-streams/${id}/mjpeg`,
+	params: {
+		fps,
+		w,
+		h,
+		quality,
+	},
+}));
 
-export const takeVideo = createRequest<{ id: string; stop?: boolean }, void>(
-	({ id, stop }) => ({
-		method: "PUT",
-		url: `/api/live-streams/${id}/takevideo`,
-		params: {
-			stop,
-		},
-	}),
-);
+export const getSnapshot = createRequest<{ id: string }, Blob>(({ id }) => ({
+	method: "GET",
+	url: `/api/live-streams/${id}/snapshot.jpg`,
+}));
+
+// status 207 when failed after some taken
+export interface TakePicResult {
+	blobs: BlobInfo[];
+	error?: string;
+}
+
+export const takePic = createRequest<
+	{
+		id: string;
+		quality?: number;
+		scale?: number;
+		burst?: number;
+		// ms
+		interval?: number;
+	},
+	TakePicResult
+>(({ id, quality, scale, burst, interval }) => ({
+	method: "PUT",
+	url: `/api/live-streams/${id}/takepic`,
+	params: {
+		quality,
+		scale,
+		burst,
+		interval,
+	},
+}));
+
+export const takeVideo = createRequest<
+	{ id: string; stop?: boolean; label?: string[] },
+	void
+>(({ id, stop, label }) => ({
+	method: "PUT",
+	url: `/api/live-streams/${id}/takevideo`,
+	params: {
+		stop,
+		label,
+	},
+}));
 
 export interface BlobInfo {
 	// BlobRef
@@ -53,18 +112,29 @@ export interface BlobInfo {
 	from: string;
 	through: string;
 	labels: { [k: string]: string[] };
+	// hot or cold
+	tier?: string;
 }
 
 export const exportDataset = createRequest<
-	{ time: string; filter?: string },
+	{
+		time: string;
+		filter?: string;
+		layout?: "blobs" | "imagefolder" | "coco";
+		groupBy?: string;
+		transform?: string;
+	},
 	Blob
 >(
-	({ time, filter }) => ({
+	({ time, filter, layout, groupBy, transform }) => ({
 		method: "GET",
 		url: "/api/datasets",
 		params: {
 			time,
 			filter,
+			layout,
+			groupBy,
+			transform,
 		},
 	}),
 );
@@ -90,6 +160,71 @@ export const getBlob = createRequest<{ ref: string }, any>(
 	}),
 );
 
+export const headBlob = createRequest<{ ref: string }, void>(({ ref }) => ({
+	method: "HEAD",
+	url: `/api/blobs/${ref}`,
+}));
+
+export const getBlobThumbnail = createRequest<
+	{ ref: string; w?: number; h?: number },
+	Blob
+>(({ ref, w, h }) => ({
+	method: "GET",
+	url: `/api/blobs/${ref}/thumbnail`,
+	params: {
+		w,
+		h,
+	},
+}));
+
+export const getBlobTransformed = createRequest<
+	{
+		ref: string;
+		crop?: string;
+		rotate?: 0 | 90 | 180 | 270;
+		w?: number;
+		h?: number;
+		format?: "jpeg" | "png";
+		quality?: number;
+	},
+	Blob
+>(({ ref, crop, rotate, w, h, format, quality }) => ({
+	method: "GET",
+	url: `/api/blobs/${ref}/transform`,
+	params: {
+		crop,
+		rotate,
+		w,
+		h,
+		format,
+		quality,
+	},
+}));
+
+export const getBlobClip = createRequest<
+	{ ref: string; start: string; end: string },
+	Blob
+>(({ ref, start, end }) => ({
+	method: "GET",
+	url: `/api/blobs/${ref}/clip`,
+	params: {
+		start,
+		end,
+	},
+}));
+
+export const saveBlobClip = createRequest<
+	{ ref: string; start: string; end: string },
+	BlobInfo
+>(({ ref, start, end }) => ({
+	method: "POST",
+	url: `/api/blobs/${ref}/clip`,
+	params: {
+		start,
+		end,
+	},
+}));
+
 export const deleteBlob = createRequest<{ ref: string }, any>(
 	({ ref }) => ({
 		method: "DELETE",
@@ -116,3 +251,165 @@ export const unLabelBlob = createRequest<
 		url: `/api/blobs/${ref}/labels/${label}/${value}`,
 	}),
 );
+
+export const listLabel = createRequest<{ time: string }, string[]>(
+	({ time }) => ({
+		method: "GET",
+		url: "/api/labels",
+		params: {
+			time,
+		},
+	}),
+);
+
+export const listLabelValue = createRequest<
+	{ name: string; time: string; filter?: string },
+	string[]
+>(
+	({ name, time, filter }) => ({
+		method: "GET",
+		url: `/api/labels/${name}/values`,
+		params: {
+			time,
+			filter,
+		},
+	}),
+);
+
+export const bulkLabelBlob = createRequest<
+	{
+		refs?: string[];
+		time?: string;
+		filter?: string;
+		put?: { [k: string]: string[] };
+		delete?: { [k: string]: string[] };
+	},
+	{ ref: string; error?: string }[]
+>(
+	(body) => ({
+		method: "PATCH",
+		url: "/api/blobs",
+		body,
+	}),
+);
+
+export interface Upload {
+	id: string;
+	offset: number;
+	total?: number;
+	startedAt: string;
+	updatedAt: string;
+}
+
+export const createUpload = createRequest<{ total?: number }, Upload>(
+	(body) => ({
+		method: "POST",
+		url: "/api/uploads",
+		body,
+	}),
+);
+
+export const getUpload = createRequest<{ id: string }, Upload>(({ id }) => ({
+	method: "GET",
+	url: `/api/uploads/${id}`,
+}));
+
+export const writeUpload = createRequest<
+	{ id: string; offset: number; chunk: Blob },
+	Upload
+>(({ id, offset, chunk }) => ({
+	method: "PATCH",
+	url: `/api/uploads/${id}`,
+	headers: {
+		"Upload-Offset": offset,
+		"Content-Type": "application/octet-stream",
+	},
+	body: chunk,
+}));
+
+export const commitUpload = createRequest<
+	{
+		id: string;
+		digest: string;
+		mediaType?: string;
+		deviceID?: string;
+		from?: string;
+		through?: string;
+		labels?: { [k: string]: string[] };
+	},
+	BlobInfo
+>(({ id, ...body }) => ({
+	method: "PUT",
+	url: `/api/uploads/${id}/commit`,
+	body,
+}));
+
+export const abortUpload = createRequest<{ id: string }, void>(({ id }) => ({
+	method: "DELETE",
+	url: `/api/uploads/${id}`,
+}));
+
+export interface Quota {
+	enabled: boolean;
+	evict: boolean;
+	maxBytes: number;
+	usedBytes: number;
+	devices: { [deviceID: string]: { maxBytes: number; usedBytes: number } };
+}
+
+export const getQuota = createRequest<void, Quota>(() => ({
+	method: "GET",
+	url: "/api/quota",
+}));
+
+export const holdBlob = createRequest<{ ref: string; reason?: string }, void>(
+	({ ref, reason }) => ({
+		method: "PUT",
+		url: `/api/blobs/${ref}/hold`,
+		body: { reason },
+	}),
+);
+
+export const releaseBlob = createRequest<{ ref: string }, void>(({ ref }) => ({
+	method: "DELETE",
+	url: `/api/blobs/${ref}/hold`,
+}));
+
+export interface CheckReport {
+	orphanContents: string[];
+	danglingRefs: string[];
+	orphanObjects: string[];
+	danglingObjectRefs: string[];
+	unlinkedObjectRefs: string[];
+	repaired: boolean;
+}
+
+export const runCheck = createRequest<
+	{ time?: string; repair?: boolean },
+	CheckReport
+>(({ time, repair }) => ({
+	method: "POST",
+	url: "/api/check",
+	params: {
+		time,
+		repair,
+	},
+}));
+
+export interface AuditEntry {
+	time: string;
+	actor: { name: string; admin?: boolean; addr?: string; claimed?: string };
+	action: string;
+	ref?: string;
+	params?: { [k: string]: string };
+}
+
+export const listAudit = createRequest<{ time: string }, AuditEntry[]>(
+	({ time }) => ({
+		method: "GET",
+		url: "/api/audit",
+		params: {
+			time,
+		},
+	}),
+);
@@ -15,7 +15,7 @@ export interface RequestConfig<TInputs> {
 	url: string;
 	params?: { [k: string]: any };
 	headers?: { [k: string]: any };
-	body?: { [k: string]: any };
+	body?: { [k: string]: any } | Blob;
 	inputs: TInputs;
 }
 
@@ -103,6 +103,10 @@ export const transformRequestBody = (data: any, headers: any) => {
 		return paramsSerializer(data);
 	}
 
+	if (data instanceof Blob) {
+		return data;
+	}
+
 	if (isArray(data) || isObject(data)) {
 		return JSON.stringify(data);
 	}